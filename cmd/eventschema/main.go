@@ -0,0 +1,110 @@
+// Command eventschema writes the JSON Schema of every event contract and the
+// protobuf definitions (events.proto), and checks that contract changes stay
+// compatible with existing consumers.
+//
+//	eventschema [-dir internal/common/events/schemas] [-check]
+//
+// A schema is written as <subject>.v<version>.json using the subject's
+// current events.SchemaVersions entry. If a file for that version exists,
+// the new schema must be compatible with it (see events.Compatible);
+// otherwise the command fails and the subject's schema version has to be
+// bumped, which writes a new file next to the old one. With -check nothing
+// is written and stale files fail the command too, for CI.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
+)
+
+func main() {
+	log.SetFlags(0)
+	dir := flag.String("dir", "internal/common/events/schemas", "schema directory")
+	check := flag.Bool("check", false, "fail on stale or incompatible schemas without writing")
+	flag.Parse()
+
+	subjects := make([]string, 0, len(events.Contracts))
+	for s := range events.Contracts {
+		subjects = append(subjects, string(s))
+	}
+	sort.Strings(subjects)
+
+	failed := false
+	for _, name := range subjects {
+		subject := events.Subject(name)
+		file := filepath.Join(*dir, events.SchemaFile(subject, events.SchemaVersions[subject]))
+		next, err := events.GenerateSchema(subject)
+		if err != nil {
+			log.Fatal(err)
+		}
+		b, err := json.MarshalIndent(next, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		b = append(b, '\n')
+
+		prevBytes, err := os.ReadFile(file)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			prevBytes = nil
+		case err != nil:
+			log.Fatal(err)
+		}
+		if prevBytes != nil {
+			var prev events.Schema
+			if err := json.Unmarshal(prevBytes, &prev); err != nil {
+				log.Fatalf("%s: %v", file, err)
+			}
+			if breaks := events.Compatible(&prev, next); len(breaks) > 0 {
+				failed = true
+				fmt.Fprintf(os.Stderr, "%s: incompatible with %s; bump events.SchemaVersions[%q]:\n", subject, file, subject)
+				for _, b := range breaks {
+					fmt.Fprintf(os.Stderr, "  %s\n", b)
+				}
+				continue
+			}
+			if bytes.Equal(prevBytes, b) {
+				continue
+			}
+		}
+
+		if *check {
+			failed = true
+			fmt.Fprintf(os.Stderr, "%s: out of date; run go generate ./internal/common/events\n", file)
+			continue
+		}
+		if err := os.WriteFile(file, b, 0o644); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("wrote %s\n", file)
+	}
+	proto, err := events.ProtoFile()
+	if err != nil {
+		log.Fatal(err)
+	}
+	file := filepath.Join(*dir, "events.proto")
+	if prev, err := os.ReadFile(file); err != nil || string(prev) != proto {
+		if *check {
+			failed = true
+			fmt.Fprintf(os.Stderr, "%s: out of date; run go generate ./internal/common/events\n", file)
+		} else if err := os.WriteFile(file, []byte(proto), 0o644); err != nil {
+			log.Fatal(err)
+		} else {
+			fmt.Printf("wrote %s\n", file)
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
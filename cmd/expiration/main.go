@@ -7,26 +7,43 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/deadletter"
 	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/expiration"
 	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/pubsub"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/store"
 )
 
 func main() {
+	events.SetProducer("expiration")
+	if err := events.SetEncoding(os.Getenv("EVENT_ENCODING")); err != nil {
+		log.Fatal(err)
+	}
+
 	// Configuration from environment
 	redisAddr := os.Getenv("REDIS_URL")
 	if redisAddr == "" {
 		redisAddr = "localhost:6379"
 	}
 
-	natsURL := os.Getenv("NATS_URL")
-	if natsURL == "" {
-		log.Fatal("NATS_URL is required")
+	driver := os.Getenv("PUBSUB_DRIVER")
+	brokerURL := os.Getenv(pubsub.URLEnv(driver))
+	if brokerURL == "" && driver == pubsub.DriverRedis {
+		brokerURL = redisAddr
+	}
+	if brokerURL == "" {
+		log.Fatalf("%s is required", pubsub.URLEnv(driver))
 	}
 
-	// Connect to NATS
-	natsClient, err := pubsub.NewNATS(natsURL)
+	// Connect to the event bus
+	natsClient, err := pubsub.Open(pubsub.Config{
+		Driver:   driver,
+		URL:      brokerURL,
+		Service:  "expiration",
+		Subjects: events.AllSubjects(),
+	})
 	if err != nil {
-		log.Fatalf("Failed to connect to NATS: %v", err)
+		log.Fatalf("Failed to connect to event bus: %v", err)
 	}
 	defer natsClient.Close()
 
@@ -38,8 +55,23 @@ func main() {
 
 	worker := expiration.NewExpirationWorker(redisAddr, natsClient, 10)
 
+	// Dead letters go to Postgres when configured; otherwise they are only logged
+	var dl events.DeadLetterSink
+	if dbURL := os.Getenv("DATABASE_URL"); dbURL != "" {
+		db, err := store.NewPostgres(dbURL)
+		if err != nil {
+			log.Fatalf("store.NewPostgres: %v", err)
+		}
+		defer db.Close()
+		dls := deadletter.NewStore(db)
+		if err := dls.EnsureSchema(context.Background()); err != nil {
+			log.Printf("dead letters EnsureSchema: %v", err)
+		}
+		dl = dls
+	}
+
 	// Register NATS listeners to schedule expirations
-	if err := expiration.RegisterNATSListeners(context.Background(), natsClient, queue); err != nil {
+	if err := expiration.RegisterNATSListeners(context.Background(), natsClient, queue, dl); err != nil {
 		log.Fatalf("Failed to register NATS listeners: %v", err)
 	}
 
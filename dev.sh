@@ -48,6 +48,7 @@ check_env() {
         echo "export JWT_SECRET='your-secret-key'"
         echo "export NATS_URL='nats://localhost:4222'"
         echo "export REDIS_HOST='localhost:6379'"
+        echo "export INTERNAL_API_OPEN=true"
         return 1
     fi
     
@@ -1,14 +1,36 @@
 import { NextResponse } from 'next/server';
+import { cookies } from 'next/headers';
 
 const API_URL = process.env.API_URL || 'http://localhost:8080';
 
+// The tickets service takes the ticket ID as a query parameter.
+function ticketURL(path: string, id: string) {
+  return `${API_URL}${path}?id=${encodeURIComponent(id)}`;
+}
+
+// authHeaders forwards the caller's session cookie and Authorization header.
+async function authHeaders(request: Request): Promise<Record<string, string>> {
+  const headers: Record<string, string> = {};
+  const jwt = (await cookies()).get('jwt');
+  if (jwt) {
+    headers.Cookie = `jwt=${jwt.value}`;
+  }
+  const authorization = request.headers.get('Authorization');
+  if (authorization) {
+    headers.Authorization = authorization;
+  }
+  return headers;
+}
+
 export async function GET(
   request: Request,
   { params }: { params: Promise<{ id: string }> }
 ) {
   try {
     const { id } = await params;
-    const response = await fetch(`${API_URL}/api/tickets/${id}`);
+    const response = await fetch(ticketURL('/api/tickets/show', id), {
+      headers: await authHeaders(request),
+    });
     const data = await response.json();
     return NextResponse.json(data, { status: response.status });
   } catch (error) {
@@ -27,10 +49,11 @@ export async function PUT(
   try {
     const { id } = await params;
     const body = await request.json();
-    const response = await fetch(`${API_URL}/api/tickets/${id}`, {
+    const response = await fetch(ticketURL('/api/tickets', id), {
       method: 'PUT',
       headers: {
         'Content-Type': 'application/json',
+        ...(await authHeaders(request)),
       },
       body: JSON.stringify(body),
     });
@@ -45,3 +68,28 @@ export async function PUT(
     );
   }
 }
+
+export async function DELETE(
+  request: Request,
+  { params }: { params: Promise<{ id: string }> }
+) {
+  try {
+    const { id } = await params;
+    const response = await fetch(ticketURL('/api/tickets', id), {
+      method: 'DELETE',
+      headers: await authHeaders(request),
+    });
+
+    if (response.status === 204) {
+      return new NextResponse(null, { status: 204 });
+    }
+    const data = await response.json();
+    return NextResponse.json(data, { status: response.status });
+  } catch (error) {
+    console.error('Delete ticket error:', error);
+    return NextResponse.json(
+      { errors: [{ message: 'Failed to delete ticket' }] },
+      { status: 500 }
+    );
+  }
+}
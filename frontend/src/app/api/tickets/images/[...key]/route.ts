@@ -0,0 +1,24 @@
+const API_URL = process.env.API_URL || 'http://localhost:8080';
+
+// Serves ticket images and thumbnails, the url and thumbnailUrl of a ticket's
+// images, from the Tickets service.
+export async function GET(
+  request: Request,
+  { params }: { params: Promise<{ key: string[] }> }
+) {
+  try {
+    const { key } = await params;
+    const response = await fetch(
+      `${API_URL}/api/tickets/images/${key.map(encodeURIComponent).join('/')}`
+    );
+    const headers = new Headers();
+    for (const name of ['Content-Type', 'Cache-Control', 'X-Content-Type-Options']) {
+      const value = response.headers.get(name);
+      if (value) headers.set(name, value);
+    }
+    return new Response(response.body, { status: response.status, headers });
+  } catch (error) {
+    console.error('Get ticket image error:', error);
+    return new Response(null, { status: 502 });
+  }
+}
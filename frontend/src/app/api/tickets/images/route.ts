@@ -0,0 +1,56 @@
+import { NextResponse } from 'next/server';
+import { cookies } from 'next/headers';
+
+const API_URL = process.env.API_URL || 'http://localhost:8080';
+
+// authCookie forwards the caller's session to the tickets service.
+async function authCookie(): Promise<Record<string, string>> {
+  const jwt = (await cookies()).get('jwt');
+  return jwt ? { Cookie: `jwt=${jwt.value}` } : {};
+}
+
+// Uploads are multipart; pass the body and its boundary through untouched.
+export async function POST(request: Request) {
+  try {
+    const { search } = new URL(request.url);
+    const response = await fetch(`${API_URL}/api/tickets/images${search}`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': request.headers.get('Content-Type') || '',
+        ...(await authCookie()),
+      },
+      body: await request.arrayBuffer(),
+    });
+
+    const data = await response.json();
+    return NextResponse.json(data, { status: response.status });
+  } catch (error) {
+    console.error('Upload ticket image error:', error);
+    return NextResponse.json(
+      { errors: [{ message: 'Failed to upload image' }] },
+      { status: 500 }
+    );
+  }
+}
+
+export async function DELETE(request: Request) {
+  try {
+    const { search } = new URL(request.url);
+    const response = await fetch(`${API_URL}/api/tickets/images${search}`, {
+      method: 'DELETE',
+      headers: await authCookie(),
+    });
+
+    if (response.status === 204) {
+      return new NextResponse(null, { status: 204 });
+    }
+    const data = await response.json();
+    return NextResponse.json(data, { status: response.status });
+  } catch (error) {
+    console.error('Delete ticket image error:', error);
+    return NextResponse.json(
+      { errors: [{ message: 'Failed to delete image' }] },
+      { status: 500 }
+    );
+  }
+}
@@ -1,12 +1,14 @@
 import { NextResponse } from 'next/server';
+import { cookies } from 'next/headers';
 
 const API_URL = process.env.API_URL || 'http://localhost:8080';
 
-export async function GET() {
+export async function GET(request: Request) {
   try {
-    const response = await fetch(`${API_URL}/api/tickets`);
+    const { search } = new URL(request.url);
+    const response = await fetch(`${API_URL}/api/tickets${search}`);
     const data = await response.json();
-    return NextResponse.json(data);
+    return NextResponse.json(data, { status: response.status });
   } catch (error) {
     console.error('Tickets list error:', error);
     return NextResponse.json(
@@ -19,10 +21,12 @@ export async function GET() {
 export async function POST(request: Request) {
   try {
     const body = await request.json();
+    const jwt = (await cookies()).get('jwt');
     const response = await fetch(`${API_URL}/api/tickets`, {
       method: 'POST',
       headers: {
         'Content-Type': 'application/json',
+        ...(jwt ? { Cookie: `jwt=${jwt.value}` } : {}),
       },
       body: JSON.stringify(body),
     });
@@ -0,0 +1,18 @@
+import { NextResponse } from 'next/server';
+
+const API_URL = process.env.API_URL || 'http://localhost:8080';
+
+export async function GET(request: Request) {
+  try {
+    const { search } = new URL(request.url);
+    const response = await fetch(`${API_URL}/api/tickets/search${search}`);
+    const data = await response.json();
+    return NextResponse.json(data, { status: response.status });
+  } catch (error) {
+    console.error('Ticket search error:', error);
+    return NextResponse.json(
+      { errors: [{ message: 'Failed to search tickets' }] },
+      { status: 500 }
+    );
+  }
+}
@@ -5,9 +5,9 @@ import { ticketsApi } from '@/lib/api/tickets';
 import { TicketList } from '@/components/tickets/TicketList';
 
 export default function TicketsPage() {
-  const { data: tickets, isLoading, error } = useQuery({
+  const { data: page, isLoading, error } = useQuery({
     queryKey: ['tickets'],
-    queryFn: ticketsApi.list,
+    queryFn: () => ticketsApi.list({ available: true }),
   });
 
   if (isLoading) {
@@ -31,7 +31,7 @@ export default function TicketsPage() {
       <div className="flex justify-between items-center mb-8">
         <h1 className="text-4xl font-bold">Available Tickets</h1>
       </div>
-      <TicketList tickets={tickets || []} />
+      <TicketList tickets={page?.items || []} />
     </div>
   );
 }
@@ -13,6 +13,7 @@ interface TicketCardProps {
 
 export function TicketCard({ ticket }: TicketCardProps) {
   const isReserved = !!ticket.orderId;
+  const isSold = ticket.status === 'sold';
 
   return (
     <Card className="hover:shadow-lg transition-shadow">
@@ -20,7 +21,7 @@ export function TicketCard({ ticket }: TicketCardProps) {
         <div className="flex justify-between items-start">
           <CardTitle className="text-xl">{ticket.title}</CardTitle>
           {isReserved && (
-            <Badge variant="secondary">Reserved</Badge>
+            <Badge variant="secondary">{isSold ? 'Sold' : 'Reserved'}</Badge>
           )}
         </div>
       </CardHeader>
@@ -42,6 +42,7 @@ export function TicketDetail({ ticket }: TicketDetailProps) {
   };
 
   const isReserved = !!ticket.orderId;
+  const isSold = ticket.status === 'sold';
 
   return (
     <Card className="max-w-2xl mx-auto">
@@ -53,7 +54,7 @@ export function TicketDetail({ ticket }: TicketDetailProps) {
           </div>
           {isReserved && (
             <Badge variant="secondary" className="text-sm">
-              Reserved
+              {isSold ? 'Sold' : 'Reserved'}
             </Badge>
           )}
         </div>
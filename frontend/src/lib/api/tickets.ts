@@ -1,12 +1,26 @@
 import { apiClient } from './client';
-import type { Ticket, CreateTicketInput, UpdateTicketInput } from '../types/ticket';
+import type {
+  Ticket,
+  TicketListQuery,
+  TicketPage,
+  TicketSearchResult,
+  CreateTicketInput,
+  UpdateTicketInput,
+} from '../types/ticket';
 
 export const ticketsApi = {
-  list: async (): Promise<Ticket[]> => {
-    const { data } = await apiClient.get<Ticket[]>('/tickets');
+  list: async (query: TicketListQuery = {}): Promise<TicketPage> => {
+    const { data } = await apiClient.get<TicketPage>('/tickets', { params: query });
     return data;
   },
 
+  search: async (q: string, limit?: number): Promise<TicketSearchResult[]> => {
+    const { data } = await apiClient.get<{ items: TicketSearchResult[] }>('/tickets/search', {
+      params: { q, limit },
+    });
+    return data.items;
+  },
+
   get: async (id: string): Promise<Ticket> => {
     const { data } = await apiClient.get<Ticket>(`/tickets/${id}`);
     return data;
@@ -0,0 +1,35 @@
+export interface Venue {
+  id: string;
+  name: string;
+  address: string;
+  city: string;
+  capacity: number;
+  userId: string;
+  version: number;
+  createdAt: string;
+}
+
+export interface Event {
+  id: string;
+  name: string;
+  description: string;
+  startsAt: string;
+  venueId: string;
+  venue?: Venue;
+  userId: string;
+  version: number;
+  createdAt: string;
+}
+
+// The event of an order, as replicated by the orders service
+export interface EventSummary {
+  id: string;
+  name: string;
+  startsAt: string;
+  venue: {
+    id: string;
+    name: string;
+    city: string;
+  };
+  version: number;
+}
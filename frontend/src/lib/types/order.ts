@@ -1,3 +1,6 @@
+import type { EventSummary } from './event';
+import type { Seat } from './ticket';
+
 export enum OrderStatus {
   Created = 'created',
   Cancelled = 'cancelled',
@@ -7,6 +10,8 @@ export enum OrderStatus {
 export interface Order {
   id: string;
   userId: string;
+  // Current holder of the units; differs from userId after a transfer
+  holderId: string;
   status: OrderStatus;
   expiresAt: string;
   ticket: {
@@ -14,11 +19,13 @@ export interface Order {
     title: string;
     price: number;
   };
+  quantity: number;
+  event?: EventSummary;
+  seat?: Seat;
   version: number;
   createdAt: string;
   updatedAt: string;
 }
 
-export interface CreateOrderInput {
-  ticketId: string;
-}
+// Order a ticket by ID, or a seat of an event
+export type CreateOrderInput = { ticketId: string; quantity?: number } | { eventId: string; seat: Seat };
@@ -1,20 +1,132 @@
+export type TicketStatus = 'available' | 'reserved' | 'sold';
+
+export interface Seat {
+  section: string;
+  row: string;
+  number: number;
+}
+
+export interface TicketImage {
+  id: string;
+  url: string;
+  thumbnailUrl: string;
+  contentType: string;
+  width: number;
+  height: number;
+}
+
 export interface Ticket {
   id: string;
   title: string;
+  description: string;
   price: number;
   userId: string;
+  eventId?: string;
+  // Set for reserved seating
+  seat?: Seat;
+  // Units for sale; more than 1 for general admission
+  quantityTotal: number;
+  quantityHeld: number;
+  quantitySold: number;
   version: number;
   orderId?: string;
+  status: TicketStatus;
+  // Seller's pictures, oldest first
+  images?: TicketImage[];
   createdAt: string;
   updatedAt: string;
 }
 
+export type TicketSort = '-createdAt' | 'createdAt' | 'price' | '-price';
+
+export interface TicketListQuery {
+  limit?: number;
+  cursor?: string;
+  sort?: TicketSort;
+  minPrice?: number;
+  maxPrice?: number;
+  userId?: string;
+  eventId?: string;
+  available?: boolean;
+  createdAfter?: string;
+  createdBefore?: string;
+}
+
+export interface TicketPage {
+  items: Ticket[];
+  nextCursor?: string;
+}
+
+export interface TicketSearchResult extends Ticket {
+  rank: number;
+  // HTML: escaped ticket text with matches wrapped in <mark>
+  highlight: {
+    title: string;
+    description: string;
+  };
+}
+
 export interface CreateTicketInput {
+  // Optional; tickets without one belong to no event
+  eventId?: string;
   title: string;
+  description?: string;
   price: number;
+  quantity?: number;
 }
 
 export interface UpdateTicketInput {
   title?: string;
+  description?: string;
   price?: number;
 }
+
+export type SeatStatus = 'free' | 'held' | 'sold';
+
+export interface SeatAvailability {
+  eventId: string;
+  sections: {
+    name: string;
+    rows: {
+      name: string;
+      seats: {
+        number: number;
+        ticketId: string;
+        price: number;
+        status: SeatStatus;
+      }[];
+    }[];
+  }[];
+}
+
+// Paid units of an order, as seen by their current holder
+export interface Holding {
+  orderId: string;
+  ticketId: string;
+  quantity: number;
+  userId: string;
+  // Shown at the door; replaced by every transfer
+  credential: string;
+  transfers: number;
+}
+
+export interface Owner {
+  userId: string;
+  since: string;
+  transferId?: string;
+}
+
+export type TransferStatus = 'pending' | 'accepted' | 'cancelled';
+
+export interface Transfer {
+  id: string;
+  orderId: string;
+  ticketId: string;
+  quantity: number;
+  fromUserId: string;
+  toEmail: string;
+  toUserId?: string;
+  status: TransferStatus;
+  createdAt: string;
+  acceptedAt?: string;
+}
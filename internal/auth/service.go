@@ -6,8 +6,6 @@ import (
 	"os"
 	"time"
 
-	"encoding/json"
-
 	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
 	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/pubsub"
 	"github.com/go-playground/validator/v10"
@@ -61,8 +59,7 @@ func (s *Service) Signup(ctx context.Context, in SignupInput) (*User, string, er
 	// emit user:created (optional)
 	if s.pub != nil {
 		evt := events.UserCreatedData{ID: u.ID, Email: u.Email}
-		b, _ := json.Marshal(evt)
-		_ = s.pub.Publish(ctx, string(events.SubjectUserCreated), b)
+		_ = events.Publish(ctx, s.pub, events.SubjectUserCreated, evt)
 	}
 	return u, token, nil
 }
@@ -0,0 +1,100 @@
+// Package blob stores uploaded files, such as ticket images, in a local
+// directory or an S3-compatible bucket. Services write through Store and
+// serve blobs back with Handler, so clients never talk to the storage
+// backend directly.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ErrNotFound is returned by Get for a key with no blob.
+var ErrNotFound = errors.New("blob: not found")
+
+// ErrInvalidKey is returned for keys that are empty, absolute or step out of
+// the store with "..".
+var ErrInvalidKey = errors.New("blob: invalid key")
+
+// Store saves blobs by key. Keys are slash-separated relative paths such as
+// "tickets/<id>/<image>.jpg".
+type Store interface {
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	// Get returns the blob's content, which the caller must close, and its
+	// content type.
+	Get(ctx context.Context, key string) (io.ReadCloser, string, error)
+	// Delete removes a blob; deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Supported values for the BLOB_DRIVER setting.
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+// Config selects and configures a Store implementation.
+type Config struct {
+	Driver string // "local" (default) or "s3"
+	Dir    string // root directory of the local driver
+	S3     S3Config
+}
+
+// Open returns the Store selected by cfg.Driver.
+func Open(cfg Config) (Store, error) {
+	switch cfg.Driver {
+	case "", DriverLocal:
+		return NewLocal(cfg.Dir)
+	case DriverS3:
+		s, err := NewS3(cfg.S3)
+		if err != nil {
+			return nil, err // not a typed nil Store
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("blob: unknown driver %q", cfg.Driver)
+	}
+}
+
+// checkKey rejects keys that could address anything outside the store.
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || key == ".." || strings.HasPrefix(key, "../") {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return nil
+}
+
+// Handler serves blobs from s at their key, relative to the request path
+// after any prefix has been stripped. Keys are never reused for different
+// content, so responses may be cached for good.
+func Handler(s Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, contentType, err := s.Get(r.Context(), strings.TrimPrefix(r.URL.Path, "/"))
+		switch {
+		case errors.Is(err, ErrNotFound), errors.Is(err, ErrInvalidKey):
+			http.NotFound(w, r)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer body.Close()
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(365*24*60*60)+", immutable")
+		if r.Method == http.MethodGet {
+			_, _ = io.Copy(w, body)
+		}
+	})
+}
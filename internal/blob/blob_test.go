@@ -0,0 +1,72 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLocalStoresAndServesBlobs(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	if err := s.Put(ctx, "tickets/t1/a.png", "image/png", []byte("png bytes")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	srv := httptest.NewServer(http.StripPrefix("/images", Handler(s)))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/images/tickets/t1/a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "png bytes" || resp.Header.Get("Content-Type") != "image/png" {
+		t.Errorf("GET = %d %q %q", resp.StatusCode, resp.Header.Get("Content-Type"), body)
+	}
+
+	if err := s.Delete(ctx, "tickets/t1/a.png"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := s.Delete(ctx, "tickets/t1/a.png"); err != nil {
+		t.Errorf("Delete of a missing blob: %v", err)
+	}
+	if _, _, err := s.Get(ctx, "tickets/t1/a.png"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: %v, want ErrNotFound", err)
+	}
+	if resp, _ := http.Get(srv.URL + "/images/tickets/t1/a.png"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET after Delete = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestKeysStayInsideTheStore(t *testing.T) {
+	s, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	for _, key := range []string{"", "/etc/passwd", "../secret", "a/../../secret", "a//b", "a/./b"} {
+		if err := s.Put(context.Background(), key, "text/plain", nil); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q) = %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
+
+func TestOpenSelectsDriver(t *testing.T) {
+	if s, err := Open(Config{Dir: t.TempDir()}); err != nil {
+		t.Errorf("Open local: %v", err)
+	} else if _, ok := s.(*Local); !ok {
+		t.Errorf("default driver = %T, want *Local", s)
+	}
+	if _, err := Open(Config{Driver: DriverS3, S3: S3Config{Endpoint: "minio:9000"}}); err == nil {
+		t.Error("Open s3 with an invalid endpoint succeeded")
+	}
+	if _, err := Open(Config{Driver: "ftp"}); err == nil {
+		t.Error("Open with an unknown driver succeeded")
+	}
+}
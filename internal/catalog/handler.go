@@ -0,0 +1,250 @@
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	apperr "github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/errors"
+	cmw "github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/middleware"
+)
+
+type HTTPHandler struct{ svc *Service }
+
+func NewHTTPHandler(s *Service) *HTTPHandler { return &HTTPHandler{svc: s} }
+
+// Routes registers the venue and event endpoints on r. Reads are public;
+// writes need a signed-in user and are limited to the creator.
+func (h *HTTPHandler) Routes(r chi.Router) {
+	r.Get("/api/venues", h.ListVenues)
+	r.Get("/api/venues/{id}", h.ShowVenue)
+	r.Get("/api/venues/{id}/seats", h.ShowSeatMap)
+	r.Get("/api/events", h.ListEvents)
+	r.Get("/api/events/{id}", h.ShowEvent)
+	r.Group(func(r chi.Router) {
+		r.Use(cmw.RequireAuth)
+		r.Post("/api/venues", h.CreateVenue)
+		r.Put("/api/venues/{id}", h.UpdateVenue)
+		r.Delete("/api/venues/{id}", h.DeleteVenue)
+		r.Put("/api/venues/{id}/seats", h.UpdateSeatMap)
+		r.Post("/api/events", h.CreateEvent)
+		r.Put("/api/events/{id}", h.UpdateEvent)
+		r.Delete("/api/events/{id}", h.DeleteEvent)
+	})
+}
+
+// Codes of the 409 responses.
+const (
+	CodeVersionConflict = "version_conflict"
+	CodeInUse           = "in_use"
+)
+
+// writeError maps a repository error to its HTTP response.
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		err = apperr.NewNotFound(err.Error())
+	case errors.Is(err, ErrNotOwner):
+		err = apperr.NewForbidden(err.Error())
+	case errors.Is(err, ErrVersionConflict):
+		err = apperr.WithCode(apperr.NewConflict(err.Error()), CodeVersionConflict)
+	case errors.Is(err, ErrInUse):
+		err = apperr.WithCode(apperr.NewConflict(err.Error()), CodeInUse)
+	case errors.Is(err, ErrOverCapacity):
+		err = apperr.NewValidation("invalid payload", map[string]string{"sections": err.Error()})
+	case errors.Is(err, ErrUnknownVenue):
+		err = apperr.NewValidation("invalid payload", map[string]string{"venueId": err.Error()})
+	}
+	cmw.JSONError(w, err)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// decode reads a create or update body into dst and then validates in,
+// the input within dst.
+func decode(r *http.Request, dst any, in interface{ Validate() map[string]string }) error {
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		return apperr.NewBadRequest("invalid payload")
+	}
+	if bad := in.Validate(); bad != nil {
+		return apperr.NewValidation("invalid payload", bad)
+	}
+	return nil
+}
+
+type updateVenueReq struct {
+	VenueInput
+	Version int `json:"version"`
+}
+
+type updateEventReq struct {
+	EventInput
+	Version int `json:"version"`
+}
+
+func (h *HTTPHandler) CreateVenue(w http.ResponseWriter, r *http.Request) {
+	var in VenueInput
+	if err := decode(r, &in, &in); err != nil {
+		cmw.JSONError(w, err)
+		return
+	}
+	v, err := h.svc.CreateVenue(r.Context(), in, cmw.GetCurrentUser(r.Context()).ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, v)
+}
+
+func (h *HTTPHandler) ShowVenue(w http.ResponseWriter, r *http.Request) {
+	v, err := h.svc.GetVenue(r.Context(), chi.URLParam(r, "id"))
+	if err == nil && v == nil {
+		err = ErrNotFound
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func (h *HTTPHandler) ListVenues(w http.ResponseWriter, r *http.Request) {
+	list, err := h.svc.ListVenues(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *HTTPHandler) UpdateVenue(w http.ResponseWriter, r *http.Request) {
+	var req updateVenueReq
+	if err := decode(r, &req, &req.VenueInput); err != nil {
+		cmw.JSONError(w, err)
+		return
+	}
+	v, err := h.svc.UpdateVenue(r.Context(), chi.URLParam(r, "id"), req.Version, req.VenueInput, cmw.GetCurrentUser(r.Context()).ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func (h *HTTPHandler) DeleteVenue(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.DeleteVenue(r.Context(), chi.URLParam(r, "id"), cmw.GetCurrentUser(r.Context()).ID); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HTTPHandler) ShowSeatMap(w http.ResponseWriter, r *http.Request) {
+	m, err := h.svc.GetSeatMap(r.Context(), chi.URLParam(r, "id"))
+	if err == nil && m == nil {
+		err = ErrNotFound
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, m)
+}
+
+// UpdateSeatMap replaces a venue's seat map with the sections in the body.
+func (h *HTTPHandler) UpdateSeatMap(w http.ResponseWriter, r *http.Request) {
+	var m SeatMap
+	if err := decode(r, &m, &m); err != nil {
+		cmw.JSONError(w, err)
+		return
+	}
+	m.VenueID = chi.URLParam(r, "id")
+	saved, err := h.svc.SetSeatMap(r.Context(), m, cmw.GetCurrentUser(r.Context()).ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, saved)
+}
+
+func (h *HTTPHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
+	var in EventInput
+	if err := decode(r, &in, &in); err != nil {
+		cmw.JSONError(w, err)
+		return
+	}
+	e, err := h.svc.CreateEvent(r.Context(), in, cmw.GetCurrentUser(r.Context()).ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, e)
+}
+
+func (h *HTTPHandler) ShowEvent(w http.ResponseWriter, r *http.Request) {
+	e, err := h.svc.GetEvent(r.Context(), chi.URLParam(r, "id"))
+	if err == nil && e == nil {
+		err = ErrNotFound
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, e)
+}
+
+// ListEvents serves events by start time, optionally only those at ?venueId=
+// and starting within [?from, ?to) (RFC 3339).
+func (h *HTTPHandler) ListEvents(w http.ResponseWriter, r *http.Request) {
+	q := EventQuery{VenueID: r.URL.Query().Get("venueId")}
+	bad := map[string]string{}
+	for name, dst := range map[string]**time.Time{"from": &q.From, "to": &q.To} {
+		if s := r.URL.Query().Get(name); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				bad[name] = "must be an RFC 3339 timestamp"
+				continue
+			}
+			*dst = &t
+		}
+	}
+	if len(bad) > 0 {
+		cmw.JSONError(w, apperr.NewValidation("invalid query parameters", bad))
+		return
+	}
+	list, err := h.svc.ListEvents(r.Context(), q)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *HTTPHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
+	var req updateEventReq
+	if err := decode(r, &req, &req.EventInput); err != nil {
+		cmw.JSONError(w, err)
+		return
+	}
+	e, err := h.svc.UpdateEvent(r.Context(), chi.URLParam(r, "id"), req.Version, req.EventInput, cmw.GetCurrentUser(r.Context()).ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, e)
+}
+
+func (h *HTTPHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.DeleteEvent(r.Context(), chi.URLParam(r, "id"), cmw.GetCurrentUser(r.Context()).ID); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
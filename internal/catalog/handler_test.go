@@ -0,0 +1,132 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+
+	apperr "github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/errors"
+	cmw "github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/middleware"
+)
+
+// fakeRepo keeps events in memory. Methods the tests do not use panic
+// through the nil embedded Repository.
+type fakeRepo struct {
+	Repository
+	venues map[string]*Venue
+	events map[string]*Event
+	// inUse holds the events that have tickets.
+	inUse map[string]bool
+}
+
+func (r *fakeRepo) CreateEvent(ctx context.Context, in EventInput, userID string) (*Event, error) {
+	v := r.venues[in.VenueID]
+	if v == nil {
+		return nil, ErrUnknownVenue
+	}
+	e := &Event{ID: "e" + in.Name, Name: in.Name, StartsAt: in.StartsAt, VenueID: v.ID, Venue: v, UserID: userID}
+	r.events[e.ID] = e
+	return e, nil
+}
+
+func (r *fakeRepo) checkEvent(id, userID string) (*Event, error) {
+	e := r.events[id]
+	if e == nil {
+		return nil, ErrNotFound
+	}
+	if e.UserID != userID {
+		return nil, ErrNotOwner
+	}
+	return e, nil
+}
+
+func (r *fakeRepo) UpdateEvent(ctx context.Context, id string, expectedVersion int, in EventInput, userID string) (*Event, error) {
+	e, err := r.checkEvent(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if e.Version != expectedVersion {
+		return nil, ErrVersionConflict
+	}
+	e.Name, e.StartsAt = in.Name, in.StartsAt
+	e.Version++
+	return e, nil
+}
+
+func (r *fakeRepo) DeleteEvent(ctx context.Context, id, userID string) error {
+	if _, err := r.checkEvent(id, userID); err != nil {
+		return err
+	}
+	if r.inUse[id] {
+		return ErrInUse
+	}
+	delete(r.events, id)
+	return nil
+}
+
+func TestEventEndpoints(t *testing.T) {
+	t.Setenv("JWT_KEY", "test-key")
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &cmw.UserClaims{ID: "organizer"}).SignedString([]byte("test-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	venue := &Venue{ID: "v1", Name: "Arena", City: "Helsinki", UserID: "organizer"}
+	startsAt := time.Date(2026, 6, 1, 19, 0, 0, 0, time.UTC)
+	repo := &fakeRepo{
+		venues: map[string]*Venue{"v1": venue},
+		events: map[string]*Event{
+			"held":   {ID: "held", Name: "Play", StartsAt: startsAt, VenueID: "v1", UserID: "organizer", Version: 1},
+			"theirs": {ID: "theirs", Name: "Opera", StartsAt: startsAt, VenueID: "v1", UserID: "someone else"},
+		},
+		inUse: map[string]bool{"held": true},
+	}
+	r := chi.NewRouter()
+	r.Use(cmw.CurrentUser)
+	NewHTTPHandler(NewService(repo)).Routes(r)
+
+	for _, tc := range []struct {
+		method, path, body string
+		anonymous          bool
+		status             int
+		code, field        string
+	}{
+		{method: http.MethodPost, path: "/api/events", body: `{"name":"Concert","startsAt":"2026-06-01T19:00:00Z","venueId":"v1"}`, anonymous: true, status: http.StatusUnauthorized},
+		{method: http.MethodPost, path: "/api/events", body: `{"name":"Concert","venueId":"v1"}`, status: http.StatusBadRequest, field: "startsAt"},
+		{method: http.MethodPost, path: "/api/events", body: `{"name":"Concert","startsAt":"2026-06-01T19:00:00Z","venueId":"v2"}`, status: http.StatusBadRequest, field: "venueId"},
+		{method: http.MethodPost, path: "/api/events", body: `{"name":"Concert","startsAt":"2026-06-01T19:00:00Z","venueId":"v1"}`, status: http.StatusCreated},
+		{method: http.MethodPut, path: "/api/events/held", body: `{"name":"Play","startsAt":"2026-06-02T19:00:00Z","venueId":"v1","version":0}`, status: http.StatusConflict, code: CodeVersionConflict},
+		{method: http.MethodPut, path: "/api/events/held", body: `{"name":"Play","startsAt":"2026-06-02T19:00:00Z","venueId":"v1","version":1}`, status: http.StatusOK},
+		{method: http.MethodPut, path: "/api/events/theirs", body: `{"name":"Opera","startsAt":"2026-06-02T19:00:00Z","venueId":"v1"}`, status: http.StatusForbidden},
+		{method: http.MethodDelete, path: "/api/events/held", status: http.StatusConflict, code: CodeInUse},
+		{method: http.MethodDelete, path: "/api/events/missing", status: http.StatusNotFound},
+		{method: http.MethodDelete, path: "/api/events/eConcert", status: http.StatusNoContent},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		if !tc.anonymous {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Errorf("%s %s: status %d, want %d (%s)", tc.method, tc.path, rec.Code, tc.status, rec.Body)
+			continue
+		}
+		if tc.code == "" && tc.field == "" {
+			continue
+		}
+		var resp apperr.ErrorResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("%s %s: decode error response: %v", tc.method, tc.path, err)
+		}
+		if resp.Code != tc.code || (tc.field != "" && resp.FieldErrors[tc.field] == "") {
+			t.Errorf("%s %s: response %+v, want code %q and field %q", tc.method, tc.path, resp, tc.code, tc.field)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Package catalog manages the events tickets are sold for and the venues
+// they take place at. It runs in the tickets service and shares its
+// database and outbox.
+package catalog
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Venue struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Address   string    `json:"address"`
+	City      string    `json:"city"`
+	Capacity  int       `json:"capacity"`
+	UserID    string    `json:"userId"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type Event struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	StartsAt    time.Time `json:"startsAt"`
+	VenueID     string    `json:"venueId"`
+	Venue       *Venue    `json:"venue,omitempty"`
+	UserID      string    `json:"userId"`
+	Version     int       `json:"version"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// VenueInput holds the fields of a venue set on create and update.
+type VenueInput struct {
+	Name     string `json:"name"`
+	Address  string `json:"address"`
+	City     string `json:"city"`
+	Capacity int    `json:"capacity"`
+}
+
+// Validate reports invalid fields by JSON name, or returns nil.
+func (in VenueInput) Validate() map[string]string {
+	bad := map[string]string{}
+	if strings.TrimSpace(in.Name) == "" {
+		bad["name"] = "is required"
+	}
+	if strings.TrimSpace(in.City) == "" {
+		bad["city"] = "is required"
+	}
+	if in.Capacity < 0 {
+		bad["capacity"] = "must not be negative"
+	}
+	if len(bad) > 0 {
+		return bad
+	}
+	return nil
+}
+
+// EventInput holds the fields of an event set on create and update.
+type EventInput struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	StartsAt    time.Time `json:"startsAt"`
+	VenueID     string    `json:"venueId"`
+}
+
+// Validate reports invalid fields by JSON name, or returns nil.
+func (in EventInput) Validate() map[string]string {
+	bad := map[string]string{}
+	if strings.TrimSpace(in.Name) == "" {
+		bad["name"] = "is required"
+	}
+	if in.StartsAt.IsZero() {
+		bad["startsAt"] = "is required"
+	}
+	if in.VenueID == "" {
+		bad["venueId"] = "is required"
+	}
+	if len(bad) > 0 {
+		return bad
+	}
+	return nil
+}
+
+// EventQuery filters ListEvents. Zero fields do not filter.
+type EventQuery struct {
+	VenueID string
+	From    *time.Time // events starting at or after
+	To      *time.Time // events starting before
+}
+
+// SeatMap is the seating layout of a venue: named sections of named rows,
+// each with seats numbered from 1. Tickets are generated per seat (see
+// tickets.Repository.CreateSeatTickets).
+type SeatMap struct {
+	VenueID  string    `json:"venueId"`
+	Sections []Section `json:"sections"`
+}
+
+type Section struct {
+	Name string `json:"name"`
+	Rows []Row  `json:"rows"`
+}
+
+type Row struct {
+	Name  string `json:"name"`
+	Seats int    `json:"seats"`
+}
+
+// MaxRowSeats is the most seats one row can have.
+const MaxRowSeats = 1000
+
+// Seats returns the number of seats in m.
+func (m SeatMap) Seats() int {
+	n := 0
+	for _, s := range m.Sections {
+		for _, r := range s.Rows {
+			n += r.Seats
+		}
+	}
+	return n
+}
+
+// Validate reports invalid fields by JSON path, such as
+// "sections[0].rows[2].seats", or returns nil. Section names are unique
+// within the map and row names within their section.
+func (m SeatMap) Validate() map[string]string {
+	bad := map[string]string{}
+	if len(m.Sections) == 0 {
+		bad["sections"] = "must have at least one section"
+	}
+	sections := map[string]bool{}
+	for i, s := range m.Sections {
+		at := fmt.Sprintf("sections[%d]", i)
+		switch name := strings.TrimSpace(s.Name); {
+		case name == "":
+			bad[at+".name"] = "is required"
+		case sections[name]:
+			bad[at+".name"] = "is used by another section"
+		default:
+			sections[name] = true
+		}
+		if len(s.Rows) == 0 {
+			bad[at+".rows"] = "must have at least one row"
+		}
+		rows := map[string]bool{}
+		for j, r := range s.Rows {
+			at := fmt.Sprintf("%s.rows[%d]", at, j)
+			switch name := strings.TrimSpace(r.Name); {
+			case name == "":
+				bad[at+".name"] = "is required"
+			case rows[name]:
+				bad[at+".name"] = "is used by another row in the section"
+			default:
+				rows[name] = true
+			}
+			if r.Seats < 1 || r.Seats > MaxRowSeats {
+				bad[at+".seats"] = fmt.Sprintf("must be between 1 and %d", MaxRowSeats)
+			}
+		}
+	}
+	if len(bad) > 0 {
+		return bad
+	}
+	return nil
+}
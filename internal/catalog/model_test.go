@@ -0,0 +1,37 @@
+package catalog
+
+import "testing"
+
+func TestSeatMapValidate(t *testing.T) {
+	m := SeatMap{Sections: []Section{
+		{Name: "Stalls", Rows: []Row{{Name: "A", Seats: 20}, {Name: "B", Seats: 22}}},
+		{Name: "Balcony", Rows: []Row{{Name: "A", Seats: 10}}},
+	}}
+	if bad := m.Validate(); bad != nil {
+		t.Fatalf("Validate = %v", bad)
+	}
+	if m.Seats() != 52 {
+		t.Errorf("Seats = %d, want 52", m.Seats())
+	}
+
+	bad := SeatMap{Sections: []Section{
+		{Name: "Stalls", Rows: []Row{{Name: "A", Seats: 20}, {Name: "A", Seats: 0}}},
+		{Name: "Stalls"},
+		{Rows: []Row{{Seats: MaxRowSeats + 1}}},
+	}}.Validate()
+	for _, field := range []string{
+		"sections[0].rows[1].name", "sections[0].rows[1].seats",
+		"sections[1].name", "sections[1].rows",
+		"sections[2].name", "sections[2].rows[0].name", "sections[2].rows[0].seats",
+	} {
+		if bad[field] == "" {
+			t.Errorf("no error for %s in %v", field, bad)
+		}
+	}
+	if len(bad) != 7 {
+		t.Errorf("Validate = %v, want 7 errors", bad)
+	}
+	if bad := (SeatMap{}).Validate(); bad["sections"] == "" {
+		t.Errorf("empty map: Validate = %v", bad)
+	}
+}
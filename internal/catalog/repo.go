@@ -0,0 +1,485 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/outbox"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/store"
+)
+
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrNotOwner        = errors.New("belongs to another user")
+	ErrVersionConflict = errors.New("changed by another request")
+	ErrUnknownVenue    = errors.New("venue does not exist")
+	// ErrInUse is returned when deleting a venue with events or an event
+	// with tickets, and when changing seats that have tickets.
+	ErrInUse = errors.New("still in use")
+	// ErrOverCapacity is returned by SetSeatMap for more seats than the
+	// venue's capacity.
+	ErrOverCapacity = errors.New("more seats than the venue's capacity")
+)
+
+// Repository stores venues and events. Creating or updating an event records
+// event:created / event:updated in the outbox within the same transaction,
+// and updating a venue records event:updated for each of its events, so
+// replicas of events carry current venue details.
+//
+// Updates and deletes are only allowed for the user who created the venue or
+// event; updates also need its current version.
+type Repository interface {
+	EnsureSchema(ctx context.Context) error
+
+	CreateVenue(ctx context.Context, in VenueInput, userID string) (*Venue, error)
+	GetVenue(ctx context.Context, id string) (*Venue, error)
+	ListVenues(ctx context.Context) ([]*Venue, error)
+	UpdateVenue(ctx context.Context, id string, expectedVersion int, in VenueInput, userID string) (*Venue, error)
+	DeleteVenue(ctx context.Context, id, userID string) error
+	// SetSeatMap replaces the seat map of venue m.VenueID. It fails with
+	// ErrInUse once tickets have been generated for the venue's seats.
+	SetSeatMap(ctx context.Context, m SeatMap, userID string) (*SeatMap, error)
+	// GetSeatMap returns the seat map of a venue, without sections if it has
+	// none, or nil if the venue does not exist.
+	GetSeatMap(ctx context.Context, venueID string) (*SeatMap, error)
+
+	CreateEvent(ctx context.Context, in EventInput, userID string) (*Event, error)
+	GetEvent(ctx context.Context, id string) (*Event, error)
+	// ListEvents returns events matching q by start time.
+	ListEvents(ctx context.Context, q EventQuery) ([]*Event, error)
+	UpdateEvent(ctx context.Context, id string, expectedVersion int, in EventInput, userID string) (*Event, error)
+	DeleteEvent(ctx context.Context, id, userID string) error
+}
+
+type repo struct {
+	db     *sql.DB
+	outbox *outbox.Store
+}
+
+// NewRepository returns a Postgres repository writing events to ob, the
+// tickets service outbox.
+func NewRepository(db *sql.DB, ob *outbox.Store) Repository { return &repo{db: db, outbox: ob} }
+
+func (r *repo) EnsureSchema(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+CREATE TABLE IF NOT EXISTS venues (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    address TEXT NOT NULL DEFAULT '',
+    city TEXT NOT NULL,
+    capacity INT NOT NULL DEFAULT 0,
+    user_id TEXT NOT NULL,
+    version INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE TABLE IF NOT EXISTS events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    starts_at TIMESTAMPTZ NOT NULL,
+    venue_id UUID NOT NULL REFERENCES venues(id),
+    user_id TEXT NOT NULL,
+    version INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_events_venue_id ON events(venue_id);
+CREATE INDEX IF NOT EXISTS idx_events_starts_at ON events(starts_at);
+CREATE TABLE IF NOT EXISTS venue_seats (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    venue_id UUID NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+    section TEXT NOT NULL,
+    seat_row TEXT NOT NULL,
+    seat_number INT NOT NULL,
+    position INT NOT NULL,
+    UNIQUE (venue_id, section, seat_row, seat_number)
+);
+`)
+	if err != nil {
+		return err
+	}
+	return r.outbox.EnsureSchema(ctx)
+}
+
+const venueColumns = `id, name, address, city, capacity, user_id, version, created_at`
+
+func scanVenue(row interface{ Scan(...any) error }, v *Venue) error {
+	return row.Scan(&v.ID, &v.Name, &v.Address, &v.City, &v.Capacity, &v.UserID, &v.Version, &v.CreatedAt)
+}
+
+func (r *repo) CreateVenue(ctx context.Context, in VenueInput, userID string) (*Venue, error) {
+	var v Venue
+	err := scanVenue(r.db.QueryRowContext(ctx, `
+INSERT INTO venues (name, address, city, capacity, user_id)
+VALUES ($1,$2,$3,$4,$5)
+RETURNING `+venueColumns, in.Name, in.Address, in.City, in.Capacity, userID), &v)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *repo) GetVenue(ctx context.Context, id string) (*Venue, error) {
+	var v Venue
+	if err := scanVenue(r.db.QueryRowContext(ctx, `SELECT `+venueColumns+` FROM venues WHERE id=$1`, id), &v); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *repo) ListVenues(ctx context.Context) ([]*Venue, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+venueColumns+` FROM venues ORDER BY name, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []*Venue{}
+	for rows.Next() {
+		var v Venue
+		if err := scanVenue(rows, &v); err != nil {
+			return nil, err
+		}
+		out = append(out, &v)
+	}
+	return out, rows.Err()
+}
+
+func (r *repo) UpdateVenue(ctx context.Context, id string, expectedVersion int, in VenueInput, userID string) (*Venue, error) {
+	var v Venue
+	err := store.WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		if err := checkWritable(ctx, tx, "venues", id, userID, &expectedVersion); err != nil {
+			return err
+		}
+		err := scanVenue(tx.QueryRowContext(ctx, `
+UPDATE venues SET name=$2, address=$3, city=$4, capacity=$5, version=version+1
+WHERE id=$1
+RETURNING `+venueColumns, id, in.Name, in.Address, in.City, in.Capacity), &v)
+		if err != nil {
+			return err
+		}
+
+		// Events carry their venue's name and city, so each one moves to a
+		// new version.
+		rows, err := tx.QueryContext(ctx, `
+UPDATE events SET version=version+1 WHERE venue_id=$1
+RETURNING `+eventColumns, id)
+		if err != nil {
+			return err
+		}
+		var changed []*Event
+		for rows.Next() {
+			var e Event
+			if err := scanEvent(rows, &e); err != nil {
+				rows.Close()
+				return err
+			}
+			e.Venue = &v
+			changed = append(changed, &e)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		for _, e := range changed {
+			if err := r.record(ctx, tx, events.SubjectEventUpdated, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *repo) DeleteVenue(ctx context.Context, id, userID string) error {
+	return store.WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		if err := checkWritable(ctx, tx, "venues", id, userID, nil); err != nil {
+			return err
+		}
+		var used bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM events WHERE venue_id=$1)`, id).Scan(&used); err != nil {
+			return err
+		}
+		if used {
+			return ErrInUse
+		}
+		_, err := tx.ExecContext(ctx, `DELETE FROM venues WHERE id=$1`, id)
+		return err
+	})
+}
+
+func (r *repo) SetSeatMap(ctx context.Context, m SeatMap, userID string) (*SeatMap, error) {
+	err := store.WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		if err := checkWritable(ctx, tx, "venues", m.VenueID, userID, nil); err != nil {
+			return err
+		}
+		var capacity int
+		var ticketed bool
+		err := tx.QueryRowContext(ctx, `
+SELECT capacity, EXISTS (SELECT 1 FROM tickets t JOIN venue_seats s ON s.id = t.seat_id WHERE s.venue_id=$1)
+FROM venues WHERE id=$1`, m.VenueID).Scan(&capacity, &ticketed)
+		switch {
+		case err != nil:
+			return err
+		case ticketed:
+			return ErrInUse
+		case capacity > 0 && m.Seats() > capacity:
+			return ErrOverCapacity
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM venue_seats WHERE venue_id=$1`, m.VenueID); err != nil {
+			return err
+		}
+		// Positions keep the seats in map order.
+		position := 0
+		for _, s := range m.Sections {
+			for _, row := range s.Rows {
+				_, err := tx.ExecContext(ctx, `
+INSERT INTO venue_seats (venue_id, section, seat_row, seat_number, position)
+SELECT $1, $2, $3, n, $4 + n FROM generate_series(1, $5::int) n`, m.VenueID, s.Name, row.Name, position, row.Seats)
+				if err != nil {
+					return err
+				}
+				position += row.Seats
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *repo) GetSeatMap(ctx context.Context, venueID string) (*SeatMap, error) {
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM venues WHERE id=$1)`, venueID).Scan(&exists); err != nil || !exists {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, `
+SELECT section, seat_row, count(*) FROM venue_seats WHERE venue_id=$1
+GROUP BY section, seat_row
+ORDER BY min(position)`, venueID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	m := &SeatMap{VenueID: venueID, Sections: []Section{}}
+	for rows.Next() {
+		var section string
+		var row Row
+		if err := rows.Scan(&section, &row.Name, &row.Seats); err != nil {
+			return nil, err
+		}
+		if n := len(m.Sections); n == 0 || m.Sections[n-1].Name != section {
+			m.Sections = append(m.Sections, Section{Name: section})
+		}
+		last := &m.Sections[len(m.Sections)-1]
+		last.Rows = append(last.Rows, row)
+	}
+	return m, rows.Err()
+}
+
+const eventColumns = `id, name, description, starts_at, venue_id, user_id, version, created_at`
+
+func scanEvent(row interface{ Scan(...any) error }, e *Event) error {
+	return row.Scan(&e.ID, &e.Name, &e.Description, &e.StartsAt, &e.VenueID, &e.UserID, &e.Version, &e.CreatedAt)
+}
+
+// selectEvents reads events with their venues.
+const selectEvents = `
+SELECT e.id, e.name, e.description, e.starts_at, e.venue_id, e.user_id, e.version, e.created_at,
+       v.id, v.name, v.address, v.city, v.capacity, v.user_id, v.version, v.created_at
+FROM events e JOIN venues v ON v.id = e.venue_id
+`
+
+func scanEventWithVenue(row interface{ Scan(...any) error }) (*Event, error) {
+	var e Event
+	var v Venue
+	err := row.Scan(&e.ID, &e.Name, &e.Description, &e.StartsAt, &e.VenueID, &e.UserID, &e.Version, &e.CreatedAt,
+		&v.ID, &v.Name, &v.Address, &v.City, &v.Capacity, &v.UserID, &v.Version, &v.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	e.Venue = &v
+	return &e, nil
+}
+
+func (r *repo) CreateEvent(ctx context.Context, in EventInput, userID string) (*Event, error) {
+	var e Event
+	err := store.WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		venue, err := lockVenue(ctx, tx, in.VenueID)
+		if err != nil {
+			return err
+		}
+		err = scanEvent(tx.QueryRowContext(ctx, `
+INSERT INTO events (name, description, starts_at, venue_id, user_id)
+VALUES ($1,$2,$3,$4,$5)
+RETURNING `+eventColumns, in.Name, in.Description, in.StartsAt.UTC(), in.VenueID, userID), &e)
+		if err != nil {
+			return err
+		}
+		e.Venue = venue
+		return r.record(ctx, tx, events.SubjectEventCreated, &e)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *repo) GetEvent(ctx context.Context, id string) (*Event, error) {
+	e, err := scanEventWithVenue(r.db.QueryRowContext(ctx, selectEvents+`WHERE e.id=$1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return e, err
+}
+
+func (r *repo) ListEvents(ctx context.Context, q EventQuery) ([]*Event, error) {
+	var venueID, from, to any
+	if q.VenueID != "" {
+		venueID = q.VenueID
+	}
+	if q.From != nil {
+		from = *q.From
+	}
+	if q.To != nil {
+		to = *q.To
+	}
+	rows, err := r.db.QueryContext(ctx, selectEvents+`
+WHERE ($1::uuid IS NULL OR e.venue_id = $1::uuid)
+  AND ($2::timestamptz IS NULL OR e.starts_at >= $2::timestamptz)
+  AND ($3::timestamptz IS NULL OR e.starts_at < $3::timestamptz)
+ORDER BY e.starts_at, e.id
+`, venueID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []*Event{}
+	for rows.Next() {
+		e, err := scanEventWithVenue(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func (r *repo) UpdateEvent(ctx context.Context, id string, expectedVersion int, in EventInput, userID string) (*Event, error) {
+	var e Event
+	err := store.WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		if err := checkWritable(ctx, tx, "events", id, userID, &expectedVersion); err != nil {
+			return err
+		}
+		// Seat tickets belong to the seats of the current venue.
+		var seated bool
+		err := tx.QueryRowContext(ctx, `
+SELECT venue_id <> $2::uuid AND EXISTS (SELECT 1 FROM tickets WHERE event_id=$1 AND seat_id IS NOT NULL)
+FROM events WHERE id=$1`, id, in.VenueID).Scan(&seated)
+		if err != nil {
+			return err
+		}
+		if seated {
+			return ErrInUse
+		}
+		venue, err := lockVenue(ctx, tx, in.VenueID)
+		if err != nil {
+			return err
+		}
+		err = scanEvent(tx.QueryRowContext(ctx, `
+UPDATE events SET name=$2, description=$3, starts_at=$4, venue_id=$5, version=version+1
+WHERE id=$1
+RETURNING `+eventColumns, id, in.Name, in.Description, in.StartsAt.UTC(), in.VenueID), &e)
+		if err != nil {
+			return err
+		}
+		e.Venue = venue
+		return r.record(ctx, tx, events.SubjectEventUpdated, &e)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *repo) DeleteEvent(ctx context.Context, id, userID string) error {
+	return store.WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		if err := checkWritable(ctx, tx, "events", id, userID, nil); err != nil {
+			return err
+		}
+		var used bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM tickets WHERE event_id=$1)`, id).Scan(&used); err != nil {
+			return err
+		}
+		if used {
+			return ErrInUse
+		}
+		_, err := tx.ExecContext(ctx, `DELETE FROM events WHERE id=$1`, id)
+		return err
+	})
+}
+
+// checkWritable locks the row id of table and checks that userID owns it
+// and, unless expectedVersion is nil, that it is at that version.
+func checkWritable(ctx context.Context, tx *sql.Tx, table, id, userID string, expectedVersion *int) error {
+	var owner string
+	var version int
+	err := tx.QueryRowContext(ctx, `SELECT user_id, version FROM `+table+` WHERE id=$1 FOR UPDATE`, id).Scan(&owner, &version)
+	switch {
+	case err == sql.ErrNoRows:
+		return ErrNotFound
+	case err != nil:
+		return err
+	case owner != userID:
+		return ErrNotOwner
+	case expectedVersion != nil && version != *expectedVersion:
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// lockVenue reads a venue and keeps it from changing until tx ends, so the
+// venue details recorded with an event are current.
+func lockVenue(ctx context.Context, tx *sql.Tx, id string) (*Venue, error) {
+	var v Venue
+	if err := scanVenue(tx.QueryRowContext(ctx, `SELECT `+venueColumns+` FROM venues WHERE id=$1 FOR SHARE`, id), &v); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUnknownVenue
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+// record adds event:created or event:updated for e, which must have its
+// venue set, to the outbox.
+func (r *repo) record(ctx context.Context, tx *sql.Tx, subject events.Subject, e *Event) error {
+	d := events.EventUpdatedData{
+		ID:       e.ID,
+		Name:     e.Name,
+		StartsAt: e.StartsAt.UTC(),
+		Venue:    events.EventVenue{ID: e.Venue.ID, Name: e.Venue.Name, City: e.Venue.City},
+		UserID:   e.UserID,
+		Version:  e.Version,
+	}
+	var b []byte
+	var err error
+	if subject == events.SubjectEventCreated {
+		b, err = events.Marshal(ctx, subject, events.EventCreatedData(d))
+	} else {
+		b, err = events.Marshal(ctx, subject, d)
+	}
+	if err != nil {
+		return err
+	}
+	return r.outbox.Add(ctx, tx, e.ID, string(subject), b)
+}
@@ -0,0 +1,59 @@
+package catalog
+
+import "context"
+
+// Service holds venue and event business logic. Event events are written to
+// the outbox by the repository and published by the tickets service relay.
+type Service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) CreateVenue(ctx context.Context, in VenueInput, userID string) (*Venue, error) {
+	return s.repo.CreateVenue(ctx, in, userID)
+}
+
+func (s *Service) GetVenue(ctx context.Context, id string) (*Venue, error) {
+	return s.repo.GetVenue(ctx, id)
+}
+
+func (s *Service) ListVenues(ctx context.Context) ([]*Venue, error) { return s.repo.ListVenues(ctx) }
+
+func (s *Service) UpdateVenue(ctx context.Context, id string, version int, in VenueInput, userID string) (*Venue, error) {
+	return s.repo.UpdateVenue(ctx, id, version, in, userID)
+}
+
+func (s *Service) DeleteVenue(ctx context.Context, id, userID string) error {
+	return s.repo.DeleteVenue(ctx, id, userID)
+}
+
+func (s *Service) CreateEvent(ctx context.Context, in EventInput, userID string) (*Event, error) {
+	return s.repo.CreateEvent(ctx, in, userID)
+}
+
+func (s *Service) GetEvent(ctx context.Context, id string) (*Event, error) {
+	return s.repo.GetEvent(ctx, id)
+}
+
+func (s *Service) ListEvents(ctx context.Context, q EventQuery) ([]*Event, error) {
+	return s.repo.ListEvents(ctx, q)
+}
+
+func (s *Service) UpdateEvent(ctx context.Context, id string, version int, in EventInput, userID string) (*Event, error) {
+	return s.repo.UpdateEvent(ctx, id, version, in, userID)
+}
+
+func (s *Service) DeleteEvent(ctx context.Context, id, userID string) error {
+	return s.repo.DeleteEvent(ctx, id, userID)
+}
+
+func (s *Service) SetSeatMap(ctx context.Context, m SeatMap, userID string) (*SeatMap, error) {
+	return s.repo.SetSeatMap(ctx, m, userID)
+}
+
+func (s *Service) GetSeatMap(ctx context.Context, venueID string) (*SeatMap, error) {
+	return s.repo.GetSeatMap(ctx, venueID)
+}
@@ -7,7 +7,10 @@ import (
 
 // ErrorResponse is the standard error envelope returned by services.
 type ErrorResponse struct {
-	Message     string            `json:"message"`
+	Message string `json:"message"`
+	// Code is a stable, machine-readable reason for errors that clients
+	// handle differently from others with the same status.
+	Code        string            `json:"code,omitempty"`
 	FieldErrors map[string]string `json:"fieldErrors,omitempty"`
 }
 
@@ -49,3 +52,32 @@ func NewUnauthorized(msg string) HTTPError {
 func NewForbidden(msg string) HTTPError { return baseHTTPError{msg: msg, code: http.StatusForbidden} }
 func NewNotFound(msg string) HTTPError  { return baseHTTPError{msg: msg, code: http.StatusNotFound} }
 func NewConflict(msg string) HTTPError  { return baseHTTPError{msg: msg, code: http.StatusConflict} }
+
+// NewValidation is a bad request naming the invalid fields, keyed by field
+// name.
+func NewValidation(msg string, fieldErrors map[string]string) HTTPError {
+	return validationError{baseHTTPError: baseHTTPError{msg: msg, code: http.StatusBadRequest}, fields: fieldErrors}
+}
+
+type validationError struct {
+	baseHTTPError
+	fields map[string]string
+}
+
+func (e validationError) Response() ErrorResponse {
+	return ErrorResponse{Message: e.msg, FieldErrors: e.fields}
+}
+
+// WithCode returns err with code set in its response.
+func WithCode(err HTTPError, code string) HTTPError { return codedError{HTTPError: err, code: code} }
+
+type codedError struct {
+	HTTPError
+	code string
+}
+
+func (e codedError) Response() ErrorResponse {
+	resp := e.HTTPError.Response()
+	resp.Code = e.code
+	return resp
+}
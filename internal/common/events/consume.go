@@ -0,0 +1,101 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/pubsub"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/replica"
+)
+
+// DefaultMaxDeliveries is the delivery on which a consumer gives up on a
+// message when it has no explicit limit.
+const DefaultMaxDeliveries = 5
+
+// FailedMessage describes a message a service gave up on.
+type FailedMessage struct {
+	Service     string
+	Subject     Subject
+	EventID     string // empty if the envelope could not be decoded
+	ContentType string // the message's Content-Type header
+	Payload     []byte // the message exactly as received
+	Err         error
+	Attempts    int
+}
+
+// DeadLetterSink stores messages that could not be processed.
+type DeadLetterSink interface {
+	DeadLetter(ctx context.Context, m FailedMessage) error
+}
+
+// Consumer holds the per-service settings shared by its subscriptions.
+type Consumer struct {
+	Sub pubsub.Subscriber
+	// Service is the consuming service; it is the queue group name and the
+	// owner recorded on dead letters.
+	Service string
+	// MaxDeliveries is the delivery on which a still-failing message is
+	// dead-lettered instead of redelivered (default DefaultMaxDeliveries).
+	// Keep it within the broker's own delivery limit.
+	MaxDeliveries int
+	DeadLetters   DeadLetterSink // nil only logs failures
+}
+
+// Subscribe decodes envelopes on subject, in JSON or protobuf according to
+// their Content-Type header, and passes them to h, shared across the
+// consumer's queue group. The handler context is the broker's
+// per-message context, carrying the processing deadline and the event's
+// correlation ID. Once ctx is done, messages are left for redelivery.
+//
+// A handler error is returned to the broker, which redelivers the message
+// after a backoff; on delivery MaxDeliveries the message goes to the
+// dead-letter sink with the failure reason instead. A replica.ErrVersionGap
+// does not count toward that limit: the missing versions are still on their
+// way, so the message is marked pubsub.ErrRetryLater and redelivered with the
+// broker's longest backoff until they arrive. Messages that fail
+// envelope verification are dead-lettered immediately. A message is only
+// acknowledged once its dead letter is stored; if the sink fails, the
+// message is redelivered and dead-lettered again.
+func Subscribe[T any](ctx context.Context, c *Consumer, subject Subject, h func(ctx context.Context, e Envelope[T]) error) error {
+	max := c.MaxDeliveries
+	if max <= 0 {
+		max = DefaultMaxDeliveries
+	}
+	handler := func(mctx context.Context, m pubsub.Message) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		env, err := DecodeMessage[T](subject, m)
+		if err != nil {
+			return c.deadLetter(mctx, FailedMessage{Subject: subject, ContentType: contentTypeOf(m), Payload: m.Data, Err: err, Attempts: m.Delivery})
+		}
+		err = h(WithCorrelationID(mctx, env.CorrelationID), env)
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, replica.ErrVersionGap) {
+			return fmt.Errorf("%s %s: %w: %w", subject, env.ID, pubsub.ErrRetryLater, err)
+		}
+		if m.Delivery >= max {
+			return c.deadLetter(mctx, FailedMessage{Subject: subject, EventID: env.ID, ContentType: contentTypeOf(m), Payload: m.Data, Err: err, Attempts: m.Delivery})
+		}
+		return fmt.Errorf("%s %s: %w", subject, env.ID, err)
+	}
+	return c.Sub.QueueSubscribe(string(subject), c.Service, handler)
+}
+
+// deadLetter hands m to the sink. It returns the sink's error so the
+// message is not acknowledged before its dead letter is stored.
+func (c *Consumer) deadLetter(ctx context.Context, m FailedMessage) error {
+	m.Service = c.Service
+	log.Printf("%s %s: dead-lettering after %d attempt(s): %v", m.Subject, m.EventID, m.Attempts, m.Err)
+	if c.DeadLetters == nil {
+		return nil
+	}
+	if err := c.DeadLetters.DeadLetter(ctx, m); err != nil {
+		return fmt.Errorf("%s %s: store dead letter: %w", m.Subject, m.EventID, err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/pubsub"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/replica"
+)
+
+type sinkFunc func(ctx context.Context, m FailedMessage) error
+
+func (f sinkFunc) DeadLetter(ctx context.Context, m FailedMessage) error { return f(ctx, m) }
+
+func TestSubscribeRedeliversThenDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	bus := pubsub.NewMemory()
+	var dead []FailedMessage
+	c := &Consumer{
+		Sub:           bus,
+		Service:       "orders",
+		MaxDeliveries: 3,
+		DeadLetters:   sinkFunc(func(_ context.Context, m FailedMessage) error { dead = append(dead, m); return nil }),
+	}
+
+	calls := 0
+	err := Subscribe(ctx, c, SubjectTicketUpdated, func(ctx context.Context, e Envelope[TicketUpdatedData]) error {
+		calls++
+		if CorrelationID(ctx) != "req-1" {
+			t.Errorf("correlation ID = %q", CorrelationID(ctx))
+		}
+		return errors.New("upsert failed")
+	})
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	msg, _ := Marshal(WithCorrelationID(ctx, "req-1"), SubjectTicketUpdated, TicketUpdatedData{ID: "t1", Version: 2})
+	_ = bus.Publish(ctx, string(SubjectTicketUpdated), msg)
+
+	if calls != 3 {
+		t.Errorf("handler called %d times, want 3", calls)
+	}
+	if len(dead) != 1 {
+		t.Fatalf("dead letters = %d, want 1", len(dead))
+	}
+	if d := dead[0]; d.Service != "orders" || d.EventID != "ticket:updated:t1:2" || d.Attempts != 3 || d.Err == nil || string(d.Payload) != string(msg) {
+		t.Errorf("unexpected dead letter: %+v", d)
+	}
+
+	// Undecodable messages are dead-lettered without calling the handler.
+	calls = 0
+	_ = bus.Publish(ctx, string(SubjectTicketUpdated), []byte(`not json`))
+	if calls != 0 || len(dead) != 2 || !errors.Is(dead[1].Err, ErrInvalidEnvelope) {
+		t.Errorf("invalid message: calls=%d dead=%+v", calls, dead)
+	}
+}
+
+func TestSubscribeRedeliversUntilDeadLetterIsStored(t *testing.T) {
+	ctx := context.Background()
+	bus := pubsub.NewMemory()
+	var dead []FailedMessage
+	sinkDown := true
+	c := &Consumer{
+		Sub:           bus,
+		Service:       "orders",
+		MaxDeliveries: 2,
+		DeadLetters: sinkFunc(func(_ context.Context, m FailedMessage) error {
+			if sinkDown {
+				sinkDown = false
+				return errors.New("database unavailable")
+			}
+			dead = append(dead, m)
+			return nil
+		}),
+	}
+	calls := 0
+	if err := Subscribe(ctx, c, SubjectTicketUpdated, func(ctx context.Context, e Envelope[TicketUpdatedData]) error {
+		calls++
+		return errors.New("upsert failed")
+	}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	msg, _ := Marshal(ctx, SubjectTicketUpdated, TicketUpdatedData{ID: "t1", Version: 2})
+	_ = bus.Publish(ctx, string(SubjectTicketUpdated), msg)
+
+	// Delivery 2 fails to store its dead letter, so delivery 3 tries again.
+	if calls != 3 || len(dead) != 1 || dead[0].Attempts != 3 {
+		t.Errorf("calls=%d dead=%+v, want 3 calls and one dead letter on attempt 3", calls, dead)
+	}
+}
+
+func TestSubscribeRedeliversVersionGapsPastDeadLetterLimit(t *testing.T) {
+	ctx := context.Background()
+	bus := pubsub.NewMemory()
+	var dead []FailedMessage
+	c := &Consumer{
+		Sub:           bus,
+		Service:       "orders",
+		MaxDeliveries: 3,
+		DeadLetters:   sinkFunc(func(_ context.Context, m FailedMessage) error { dead = append(dead, m); return nil }),
+	}
+	calls := 0
+	if err := Subscribe(ctx, c, SubjectTicketUpdated, func(ctx context.Context, e Envelope[TicketUpdatedData]) error {
+		calls++
+		if calls <= 5 {
+			_, err := replica.Check("orders_tickets", e.Data.ID, 0, true, e.Data.Version)
+			return err
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	msg, _ := Marshal(ctx, SubjectTicketUpdated, TicketUpdatedData{ID: "t1", Version: 2})
+	_ = bus.Publish(ctx, string(SubjectTicketUpdated), msg)
+
+	// Version 1 shows up while the gap is redelivered, past MaxDeliveries.
+	if calls != 6 || len(dead) != 0 {
+		t.Errorf("calls=%d dead=%+v, want 6 calls and no dead letter", calls, dead)
+	}
+}
@@ -1,62 +1,214 @@
 package events
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // TicketCreatedEvent
 type TicketCreatedData struct {
-	ID      string `json:"id"`
-	Title   string `json:"title"`
-	Price   int64  `json:"price"`
-	UserID  string `json:"userId"`
-	Version int    `json:"version"`
+	ID      string `json:"id" proto:"1"`
+	Title   string `json:"title" proto:"2"`
+	Price   int64  `json:"price" proto:"3"`
+	UserID  string `json:"userId" proto:"4"`
+	Version int    `json:"version" proto:"5"`
+	// EventRef is the ID of the event the ticket is for (EventID is taken
+	// by the method); empty for tickets listed before events existed.
+	EventRef string `json:"eventId,omitempty" proto:"6"`
+	// Seat is set for tickets generated from a venue's seat map.
+	Seat *TicketSeat `json:"seat,omitempty" proto:"7"`
+	// QuantityTotal is the number of units of a general-admission ticket;
+	// 0 from producers before it was added means 1.
+	QuantityTotal int `json:"quantityTotal,omitempty" proto:"8"`
+}
+
+// TicketSeat is the seat a ticket is for. Seats do not change once a ticket
+// has been generated for them.
+type TicketSeat struct {
+	Section string `json:"section" proto:"1"`
+	Row     string `json:"row" proto:"2"`
+	Number  int    `json:"number" proto:"3"`
 }
 
 // TicketUpdatedEvent
 type TicketUpdatedData struct {
-	ID      string  `json:"id"`
-	Title   string  `json:"title"`
-	Price   int64   `json:"price"`
-	UserID  string  `json:"userId"`
-	OrderID *string `json:"orderId,omitempty"`
-	Version int     `json:"version"`
+	ID      string  `json:"id" proto:"1"`
+	Title   string  `json:"title" proto:"2"`
+	Price   int64   `json:"price" proto:"3"`
+	UserID  string  `json:"userId" proto:"4"`
+	OrderID *string `json:"orderId,omitempty" proto:"5"`
+	Version int     `json:"version" proto:"6"`
+	// Status is available, reserved or sold; producers before it was added
+	// leave it empty.
+	Status   string      `json:"status,omitempty" proto:"7"`
+	EventRef string      `json:"eventId,omitempty" proto:"8"`
+	Seat     *TicketSeat `json:"seat,omitempty" proto:"9"`
+	// Units in total, held by unpaid orders and sold, as in
+	// TicketCreatedData.
+	QuantityTotal int `json:"quantityTotal,omitempty" proto:"10"`
+	QuantityHeld  int `json:"quantityHeld,omitempty" proto:"11"`
+	QuantitySold  int `json:"quantitySold,omitempty" proto:"12"`
+}
+
+// TicketDeletedEvent is emitted when the seller withdraws a ticket. Version
+// is the ticket's version after the withdrawal, so replicas apply it after
+// every ticket:updated.
+type TicketDeletedData struct {
+	ID      string `json:"id" proto:"1"`
+	UserID  string `json:"userId" proto:"2"`
+	Version int    `json:"version" proto:"3"`
+}
+
+// TicketTransferredEvent is emitted when the holder of a paid order gives its
+// units to another user, who accepted the transfer. ID is the ticket and
+// UserID the new holder. Version counts the transfers of the order's units,
+// so consumers apply them in order.
+type TicketTransferredData struct {
+	ID         string `json:"id" proto:"1"`
+	OrderID    string `json:"orderId" proto:"2"`
+	TransferID string `json:"transferId" proto:"3"`
+	FromUserID string `json:"fromUserId" proto:"4"`
+	UserID     string `json:"userId" proto:"5"`
+	Quantity   int    `json:"quantity" proto:"6"`
+	Version    int    `json:"version" proto:"7"`
 }
 
 // OrderCreatedEvent
 type OrderCreatedData struct {
-	ID        string            `json:"id"`
-	Version   int               `json:"version"`
-	Status    string            `json:"status"`
-	UserID    string            `json:"userId"`
-	ExpiresAt time.Time         `json:"expiresAt"`
-	Ticket    OrderTicketDetail `json:"ticket"`
+	ID        string            `json:"id" proto:"1"`
+	Version   int               `json:"version" proto:"2"`
+	Status    string            `json:"status" proto:"3"`
+	UserID    string            `json:"userId" proto:"4"`
+	ExpiresAt time.Time         `json:"expiresAt" proto:"5"`
+	Ticket    OrderTicketDetail `json:"ticket" proto:"6"`
+	// Quantity is the number of units of the ticket ordered; 0 from
+	// producers before it was added means 1.
+	Quantity int `json:"quantity,omitempty" proto:"7"`
 }
 
+// Units returns the number of units ordered.
+func (d OrderCreatedData) Units() int { return units(d.Quantity) }
+
+// Amount returns the price of the order: the unit price times the units.
+func (d OrderCreatedData) Amount() int64 { return d.Ticket.Price * int64(d.Units()) }
+
 type OrderTicketDetail struct {
-	ID    string `json:"id"`
-	Price int64  `json:"price"`
+	ID    string `json:"id" proto:"1"`
+	Price int64  `json:"price" proto:"2"`
 }
 
 // OrderCancelledEvent
 type OrderCancelledData struct {
-	ID      string            `json:"id"`
-	Version int               `json:"version"`
-	Ticket  OrderTicketDetail `json:"ticket"`
+	ID      string            `json:"id" proto:"1"`
+	Version int               `json:"version" proto:"2"`
+	Ticket  OrderTicketDetail `json:"ticket" proto:"3"`
+	// Quantity is the number of units released, as in OrderCreatedData.
+	Quantity int `json:"quantity,omitempty" proto:"4"`
+}
+
+// Units returns the number of units released.
+func (d OrderCancelledData) Units() int { return units(d.Quantity) }
+
+func units(quantity int) int {
+	if quantity < 1 {
+		return 1
+	}
+	return quantity
 }
 
 // ExpirationCompleteEvent
 type ExpirationCompleteData struct {
-	OrderID string `json:"orderId"`
+	OrderID string `json:"orderId" proto:"1"`
 }
 
 // PaymentCreatedEvent
 type PaymentCreatedData struct {
-	ID       string `json:"id"`
-	OrderID  string `json:"orderId"`
-	StripeID string `json:"stripeId"`
+	ID       string `json:"id" proto:"1"`
+	OrderID  string `json:"orderId" proto:"2"`
+	StripeID string `json:"stripeId" proto:"3"`
 }
 
 // UserCreatedEvent
 type UserCreatedData struct {
-	ID    string `json:"id"`
-	Email string `json:"email"`
+	ID    string `json:"id" proto:"1"`
+	Email string `json:"email" proto:"2"`
+}
+
+// EventCreatedEvent
+type EventCreatedData struct {
+	ID       string     `json:"id" proto:"1"`
+	Name     string     `json:"name" proto:"2"`
+	StartsAt time.Time  `json:"startsAt" proto:"3"`
+	Venue    EventVenue `json:"venue" proto:"4"`
+	UserID   string     `json:"userId" proto:"5"`
+	Version  int        `json:"version" proto:"6"`
+}
+
+// EventVenue is the venue of an event as carried in event:created and
+// event:updated, so consumers need not replicate venues.
+type EventVenue struct {
+	ID   string `json:"id" proto:"1"`
+	Name string `json:"name" proto:"2"`
+	City string `json:"city" proto:"3"`
+}
+
+// EventUpdatedEvent is also emitted for every event at a venue when the
+// venue changes.
+type EventUpdatedData struct {
+	ID       string     `json:"id" proto:"1"`
+	Name     string     `json:"name" proto:"2"`
+	StartsAt time.Time  `json:"startsAt" proto:"3"`
+	Venue    EventVenue `json:"venue" proto:"4"`
+	UserID   string     `json:"userId" proto:"5"`
+	Version  int        `json:"version" proto:"6"`
+}
+
+// Event IDs identify one occurrence of an event. They are derived from the
+// subject, aggregate ID and version so every redelivery or producer retry of
+// the same event carries the same ID, which consumers use for deduplication.
+
+func (d TicketCreatedData) EventID() string {
+	return fmt.Sprintf("%s:%s:%d", SubjectTicketCreated, d.ID, d.Version)
+}
+
+func (d TicketUpdatedData) EventID() string {
+	return fmt.Sprintf("%s:%s:%d", SubjectTicketUpdated, d.ID, d.Version)
+}
+
+func (d TicketDeletedData) EventID() string {
+	return fmt.Sprintf("%s:%s:%d", SubjectTicketDeleted, d.ID, d.Version)
+}
+
+// TicketTransferredData is identified by the order, as general-admission
+// tickets have many holders.
+func (d TicketTransferredData) EventID() string {
+	return fmt.Sprintf("%s:%s:%d", SubjectTicketTransferred, d.OrderID, d.Version)
+}
+
+func (d OrderCreatedData) EventID() string {
+	return fmt.Sprintf("%s:%s:%d", SubjectOrderCreated, d.ID, d.Version)
+}
+
+func (d OrderCancelledData) EventID() string {
+	return fmt.Sprintf("%s:%s:%d", SubjectOrderCancelled, d.ID, d.Version)
+}
+
+func (d ExpirationCompleteData) EventID() string {
+	return fmt.Sprintf("%s:%s", SubjectExpirationComplete, d.OrderID)
+}
+
+func (d PaymentCreatedData) EventID() string {
+	return fmt.Sprintf("%s:%s", SubjectPaymentCreated, d.ID)
+}
+
+func (d UserCreatedData) EventID() string {
+	return fmt.Sprintf("%s:%s", SubjectUserCreated, d.ID)
+}
+
+func (d EventCreatedData) EventID() string {
+	return fmt.Sprintf("%s:%s:%d", SubjectEventCreated, d.ID, d.Version)
+}
+
+func (d EventUpdatedData) EventID() string {
+	return fmt.Sprintf("%s:%s:%d", SubjectEventUpdated, d.ID, d.Version)
 }
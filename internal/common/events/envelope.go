@@ -0,0 +1,172 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/pubsub"
+)
+
+// Envelope is the wire format of every message on the bus: event metadata
+// plus the subject-specific contract in Data.
+type Envelope[T any] struct {
+	ID            string    `json:"id"`
+	Subject       Subject   `json:"subject"`
+	SchemaVersion int       `json:"schemaVersion"`
+	Producer      string    `json:"producer"`
+	OccurredAt    time.Time `json:"occurredAt"`
+	CorrelationID string    `json:"correlationId,omitempty"`
+	Data          T         `json:"data"`
+}
+
+// SchemaVersions is the current contract version per subject. Bump a
+// subject's version when its Data changes incompatibly; consumers reject
+// envelopes newer than they understand.
+var SchemaVersions = map[Subject]int{
+	SubjectTicketCreated:      1,
+	SubjectTicketUpdated:      1,
+	SubjectTicketDeleted:      1,
+	SubjectTicketTransferred:  1,
+	SubjectOrderCreated:       1,
+	SubjectOrderCancelled:     1,
+	SubjectExpirationComplete: 1,
+	SubjectPaymentCreated:     1,
+	SubjectUserCreated:        1,
+	SubjectEventCreated:       1,
+	SubjectEventUpdated:       1,
+}
+
+var producer atomic.Value
+
+// SetProducer names the service stamped into every envelope this process
+// produces. Call it once at startup.
+func SetProducer(name string) { producer.Store(name) }
+
+func producerName() string {
+	if p, ok := producer.Load().(string); ok && p != "" {
+		return p
+	}
+	return "unknown"
+}
+
+type correlationKey struct{}
+
+// WithCorrelationID returns a context whose published events carry id.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+// CorrelationID returns the correlation ID carried by ctx, if any.
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+// Identified is implemented by contracts with a deterministic event ID.
+type Identified interface{ EventID() string }
+
+// NewEnvelope stamps data with metadata for subject. The ID comes from the
+// contract's EventID when it has one, so retries produce the same ID; the
+// correlation ID is taken from ctx and otherwise starts a new chain.
+func NewEnvelope[T any](ctx context.Context, subject Subject, data T) Envelope[T] {
+	id := ""
+	if d, ok := any(data).(Identified); ok {
+		id = d.EventID()
+	}
+	if id == "" {
+		id = uuid.NewString()
+	}
+	corr := CorrelationID(ctx)
+	if corr == "" {
+		corr = id
+	}
+	return Envelope[T]{
+		ID:            id,
+		Subject:       subject,
+		SchemaVersion: SchemaVersions[subject],
+		Producer:      producerName(),
+		OccurredAt:    time.Now().UTC(),
+		CorrelationID: corr,
+		Data:          data,
+	}
+}
+
+// Marshal wraps data in an envelope and encodes it for the wire.
+func Marshal[T any](ctx context.Context, subject Subject, data T) ([]byte, error) {
+	return json.Marshal(NewEnvelope(ctx, subject, data))
+}
+
+// ErrInvalidEnvelope is returned for messages that fail envelope checks.
+var ErrInvalidEnvelope = errors.New("invalid event envelope")
+
+// Decode parses and verifies an envelope received on subject. Its data is
+// validated against the schema of its subject and version (see schema.go)
+// before it is decoded into T.
+func Decode[T any](subject Subject, msg []byte) (Envelope[T], error) {
+	var raw Envelope[json.RawMessage]
+	if err := json.Unmarshal(msg, &raw); err != nil {
+		return Envelope[T]{}, fmt.Errorf("%w: %v", ErrInvalidEnvelope, err)
+	}
+	env := Envelope[T]{
+		ID:            raw.ID,
+		Subject:       raw.Subject,
+		SchemaVersion: raw.SchemaVersion,
+		Producer:      raw.Producer,
+		OccurredAt:    raw.OccurredAt,
+		CorrelationID: raw.CorrelationID,
+	}
+	if err := verify(subject, env); err != nil {
+		return env, err
+	}
+	if err := ValidateData(subject, env.SchemaVersion, raw.Data); err != nil {
+		return env, err
+	}
+	if len(raw.Data) > 0 {
+		if err := json.Unmarshal(raw.Data, &env.Data); err != nil {
+			return env, fmt.Errorf("%w: %v", ErrInvalidEnvelope, err)
+		}
+	}
+	return env, nil
+}
+
+// verify checks the metadata of an envelope received on subject.
+func verify[T any](subject Subject, env Envelope[T]) error {
+	switch {
+	case env.ID == "":
+		return fmt.Errorf("%w: missing id", ErrInvalidEnvelope)
+	case env.Subject != subject:
+		return fmt.Errorf("%w: subject %q received on %q", ErrInvalidEnvelope, env.Subject, subject)
+	case env.Producer == "":
+		return fmt.Errorf("%w: missing producer", ErrInvalidEnvelope)
+	case env.OccurredAt.IsZero():
+		return fmt.Errorf("%w: missing occurredAt", ErrInvalidEnvelope)
+	case env.SchemaVersion < 1 || env.SchemaVersion > SchemaVersions[subject]:
+		return fmt.Errorf("%w: unsupported schema version %d for %s", ErrInvalidEnvelope, env.SchemaVersion, subject)
+	}
+	return nil
+}
+
+// Publish wraps data in an envelope and publishes it on subject in the
+// process's wire format (see SetEncoding).
+func Publish[T any](ctx context.Context, pub pubsub.Publisher, subject Subject, data T) error {
+	ct := publishContentType()
+	var b []byte
+	var err error
+	if ct == ContentTypeProtobuf {
+		env := NewEnvelope(ctx, subject, data)
+		b, err = marshalProto(env, reflect.ValueOf(env.Data))
+	} else {
+		b, err = Marshal(ctx, subject, data)
+	}
+	if err != nil {
+		return err
+	}
+	return pub.PublishMsg(ctx, pubsub.Message{Subject: string(subject), Data: b, Header: pubsub.Header{HeaderContentType: ct}})
+}
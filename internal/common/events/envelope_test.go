@@ -0,0 +1,54 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	SetProducer("tickets")
+	ctx := WithCorrelationID(context.Background(), "req-1")
+	data := TicketUpdatedData{ID: "t1", Title: "Concert", Price: 2000, UserID: "u1", Version: 3}
+
+	b, err := Marshal(ctx, SubjectTicketUpdated, data)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	env, err := Decode[TicketUpdatedData](SubjectTicketUpdated, b)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if env.ID != data.EventID() {
+		t.Errorf("ID = %q, want deterministic %q", env.ID, data.EventID())
+	}
+	if env.Producer != "tickets" || env.CorrelationID != "req-1" || env.SchemaVersion != 1 || env.OccurredAt.IsZero() {
+		t.Errorf("metadata not stamped: %+v", env)
+	}
+	if env.Data != data {
+		t.Errorf("Data = %+v, want %+v", env.Data, data)
+	}
+}
+
+func TestDecodeRejectsInvalidEnvelopes(t *testing.T) {
+	valid := NewEnvelope(context.Background(), SubjectOrderCancelled, OrderCancelledData{ID: "o1", Version: 1})
+
+	cases := map[string]func(e *Envelope[OrderCancelledData]){
+		"missing id":      func(e *Envelope[OrderCancelledData]) { e.ID = "" },
+		"wrong subject":   func(e *Envelope[OrderCancelledData]) { e.Subject = SubjectOrderCreated },
+		"future version":  func(e *Envelope[OrderCancelledData]) { e.SchemaVersion = 99 },
+		"missing version": func(e *Envelope[OrderCancelledData]) { e.SchemaVersion = 0 },
+	}
+	for name, mutate := range cases {
+		env := valid
+		mutate(&env)
+		b, _ := json.Marshal(env)
+		if _, err := Decode[OrderCancelledData](SubjectOrderCancelled, b); !errors.Is(err, ErrInvalidEnvelope) {
+			t.Errorf("%s: err = %v, want ErrInvalidEnvelope", name, err)
+		}
+	}
+	if _, err := Decode[OrderCancelledData](SubjectOrderCancelled, []byte(`{"id":"o1","version":1}`)); err == nil {
+		t.Error("bare contract JSON accepted without an envelope")
+	}
+}
@@ -0,0 +1,57 @@
+// Package eventstest provides assertions over events recorded by
+// pubsub.Memory.
+package eventstest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/pubsub"
+)
+
+// Published decodes every envelope published on subject, failing the test if
+// any of them is invalid.
+func Published[T any](t testing.TB, bus *pubsub.Memory, subject events.Subject) []events.Envelope[T] {
+	t.Helper()
+	var out []events.Envelope[T]
+	for _, m := range bus.Messages() {
+		if m.Subject != string(subject) {
+			continue
+		}
+		env, err := events.DecodeMessage[T](subject, m)
+		if err != nil {
+			t.Fatalf("%s: %v", subject, err)
+		}
+		out = append(out, env)
+	}
+	return out
+}
+
+// RequirePublished fails unless at least one event was published on subject
+// and returns the most recent one.
+func RequirePublished[T any](t testing.TB, bus *pubsub.Memory, subject events.Subject) events.Envelope[T] {
+	t.Helper()
+	envs := Published[T](t, bus, subject)
+	if len(envs) == 0 {
+		t.Fatalf("no event published on %s", subject)
+	}
+	return envs[len(envs)-1]
+}
+
+// RequireNotPublished fails if anything was published on subject.
+func RequireNotPublished(t testing.TB, bus *pubsub.Memory, subject events.Subject) {
+	t.Helper()
+	if n := len(bus.Published(string(subject))); n > 0 {
+		t.Fatalf("%d unexpected event(s) published on %s", n, subject)
+	}
+}
+
+// Publish encodes data in an envelope and delivers it through bus, failing
+// the test on error.
+func Publish[T any](t testing.TB, bus *pubsub.Memory, subject events.Subject, data T) {
+	t.Helper()
+	if err := events.Publish(context.Background(), bus, subject, data); err != nil {
+		t.Fatalf("publish %s: %v", subject, err)
+	}
+}
@@ -0,0 +1,751 @@
+package events
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/pubsub"
+)
+
+// Events travel as JSON unless their Content-Type header says otherwise, so
+// producers can switch to protobuf one at a time while every consumer reads
+// both formats.
+const (
+	HeaderContentType   = "Content-Type"
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+var wireContentType atomic.Value
+
+// SetEncoding selects the wire format of the events this process publishes:
+// "json" (the default, also for "") or "protobuf". Call it once at startup.
+func SetEncoding(name string) error {
+	switch name {
+	case "", "json":
+		wireContentType.Store(ContentTypeJSON)
+	case "protobuf":
+		wireContentType.Store(ContentTypeProtobuf)
+	default:
+		return fmt.Errorf("unknown event encoding %q (want json or protobuf)", name)
+	}
+	return nil
+}
+
+func publishContentType() string {
+	if ct, ok := wireContentType.Load().(string); ok {
+		return ct
+	}
+	return ContentTypeJSON
+}
+
+// WireMessage prepares a JSON envelope for publishing in this process's wire
+// format. The outbox stores JSON and relays through it.
+func WireMessage(subject string, payload []byte) (pubsub.Message, error) {
+	ct := publishContentType()
+	data, err := Transcode(Subject(subject), ContentTypeJSON, ct, payload)
+	if err != nil {
+		return pubsub.Message{}, err
+	}
+	return pubsub.Message{Subject: subject, Data: data, Header: pubsub.Header{HeaderContentType: ct}}, nil
+}
+
+func contentTypeOf(m pubsub.Message) string {
+	if ct := m.Header[HeaderContentType]; ct != "" {
+		return ct
+	}
+	return ContentTypeJSON
+}
+
+// DecodeMessage decodes m according to its Content-Type header and verifies
+// it like Decode. Protobuf data is typed by the wire format and not checked
+// against the JSON Schema.
+func DecodeMessage[T any](subject Subject, m pubsub.Message) (Envelope[T], error) {
+	switch ct := contentTypeOf(m); ct {
+	case ContentTypeJSON:
+		return Decode[T](subject, m.Data)
+	case ContentTypeProtobuf:
+		return decodeProto[T](subject, m.Data)
+	default:
+		return Envelope[T]{}, fmt.Errorf("%w: unsupported content type %q", ErrInvalidEnvelope, ct)
+	}
+}
+
+// Transcode converts an envelope received on subject between content types.
+func Transcode(subject Subject, from, to string, msg []byte) ([]byte, error) {
+	if from == to {
+		return msg, nil
+	}
+	var env Envelope[any]
+	switch from {
+	case ContentTypeJSON:
+		var raw Envelope[json.RawMessage]
+		if err := json.Unmarshal(msg, &raw); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidEnvelope, err)
+		}
+		data, err := newContract(subject)
+		if err != nil {
+			return nil, err
+		}
+		if len(raw.Data) > 0 {
+			if err := json.Unmarshal(raw.Data, data); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidEnvelope, err)
+			}
+		}
+		env = envelopeWith(raw, data)
+	case ContentTypeProtobuf:
+		meta, data, err := unmarshalProtoEnvelope(msg)
+		if err != nil {
+			return nil, err
+		}
+		v, err := newContract(subject)
+		if err != nil {
+			return nil, err
+		}
+		if err := decodeProtoMessage(data, reflect.ValueOf(v).Elem()); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidEnvelope, err)
+		}
+		env = envelopeWith(meta, v)
+	default:
+		return nil, fmt.Errorf("%w: unsupported content type %q", ErrInvalidEnvelope, from)
+	}
+
+	switch to {
+	case ContentTypeJSON:
+		return json.Marshal(env)
+	case ContentTypeProtobuf:
+		return marshalProto(env, reflect.ValueOf(env.Data).Elem())
+	}
+	return nil, fmt.Errorf("unsupported content type %q", to)
+}
+
+// ToJSON returns the JSON envelope of a message received on subject with
+// the given Content-Type header.
+func ToJSON(subject Subject, contentType string, msg []byte) ([]byte, error) {
+	if contentType == "" {
+		contentType = ContentTypeJSON
+	}
+	return Transcode(subject, contentType, ContentTypeJSON, msg)
+}
+
+func newContract(subject Subject) (any, error) {
+	c, ok := Contracts[subject]
+	if !ok {
+		return nil, fmt.Errorf("%w: no contract for %s", ErrInvalidEnvelope, subject)
+	}
+	return reflect.New(reflect.TypeOf(c)).Interface(), nil
+}
+
+func envelopeWith[T, U any](meta Envelope[T], data U) Envelope[U] {
+	return Envelope[U]{
+		ID:            meta.ID,
+		Subject:       meta.Subject,
+		SchemaVersion: meta.SchemaVersion,
+		Producer:      meta.Producer,
+		OccurredAt:    meta.OccurredAt,
+		CorrelationID: meta.CorrelationID,
+		Data:          data,
+	}
+}
+
+func decodeProto[T any](subject Subject, msg []byte) (Envelope[T], error) {
+	meta, data, err := unmarshalProtoEnvelope(msg)
+	env := envelopeWith(meta, *new(T))
+	if err != nil {
+		return env, err
+	}
+	if err := verify(subject, meta); err != nil {
+		return env, err
+	}
+	if _, ok := Contracts[subject]; ok && reflect.TypeOf(env.Data) != reflect.TypeOf(Contracts[subject]) {
+		// Not the contract type (e.g. json.RawMessage): go through JSON.
+		b, err := ToJSON(subject, ContentTypeProtobuf, msg)
+		if err != nil {
+			return env, err
+		}
+		return Decode[T](subject, b)
+	}
+	if err := decodeProtoMessage(data, reflect.ValueOf(&env.Data).Elem()); err != nil {
+		return env, fmt.Errorf("%w: %v", ErrInvalidEnvelope, err)
+	}
+	return env, nil
+}
+
+// The protobuf Envelope message; see schemas/events.proto.
+const (
+	envID = iota + 1
+	envSubject
+	envSchemaVersion
+	envProducer
+	envOccurredAt
+	envCorrelationID
+	envData
+)
+
+func marshalProto[T any](env Envelope[T], data reflect.Value) ([]byte, error) {
+	body, err := encodeProtoMessage(nil, data)
+	if err != nil {
+		return nil, err
+	}
+	var b []byte
+	b = appendString(b, envID, env.ID)
+	b = appendString(b, envSubject, string(env.Subject))
+	b = appendVarint(b, envSchemaVersion, uint64(env.SchemaVersion))
+	b = appendString(b, envProducer, env.Producer)
+	b = appendBytes(b, envOccurredAt, encodeTimestamp(env.OccurredAt))
+	b = appendString(b, envCorrelationID, env.CorrelationID)
+	b = appendBytes(b, envData, body)
+	return b, nil
+}
+
+func unmarshalProtoEnvelope(msg []byte) (Envelope[struct{}], []byte, error) {
+	var env Envelope[struct{}]
+	var data []byte
+	err := eachField(msg, func(num int, wt wireType, v uint64, b []byte) error {
+		switch num {
+		case envID:
+			env.ID = string(b)
+		case envSubject:
+			env.Subject = Subject(b)
+		case envSchemaVersion:
+			env.SchemaVersion = int(int64(v))
+		case envProducer:
+			env.Producer = string(b)
+		case envOccurredAt:
+			t, err := decodeTimestamp(b)
+			if err != nil {
+				return err
+			}
+			env.OccurredAt = t
+		case envCorrelationID:
+			env.CorrelationID = string(b)
+		case envData:
+			data = b
+		}
+		return nil
+	})
+	if err != nil {
+		return env, nil, fmt.Errorf("%w: %v", ErrInvalidEnvelope, err)
+	}
+	return env, data, nil
+}
+
+// Contract structs map to protobuf messages through `proto:"<field number>"`
+// tags. Field numbers are part of the contract: never change or reuse one.
+
+type wireType uint64
+
+const (
+	wireVarint  wireType = 0
+	wireFixed64 wireType = 1
+	wireBytes   wireType = 2
+	wireFixed32 wireType = 5
+)
+
+func appendTag(b []byte, num int, wt wireType) []byte {
+	return binary.AppendUvarint(b, uint64(num)<<3|uint64(wt))
+}
+
+func appendVarint(b []byte, num int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	return binary.AppendUvarint(appendTag(b, num, wireVarint), v)
+}
+
+func appendBytes(b []byte, num int, v []byte) []byte {
+	b = appendTag(b, num, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendString(b []byte, num int, v string) []byte {
+	if v == "" {
+		return b
+	}
+	return appendBytes(b, num, []byte(v))
+}
+
+// google.protobuf.Timestamp
+func encodeTimestamp(t time.Time) []byte {
+	var b []byte
+	b = appendVarint(b, 1, uint64(t.Unix()))
+	b = appendVarint(b, 2, uint64(t.Nanosecond()))
+	return b
+}
+
+func decodeTimestamp(b []byte) (time.Time, error) {
+	var sec, nsec int64
+	err := eachField(b, func(num int, _ wireType, v uint64, _ []byte) error {
+		switch num {
+		case 1:
+			sec = int64(v)
+		case 2:
+			nsec = int64(v)
+		}
+		return nil
+	})
+	return time.Unix(sec, nsec).UTC(), err
+}
+
+// eachField calls fn for every field in the message b. Varint and fixed
+// values arrive in v, length-delimited ones in raw.
+func eachField(b []byte, fn func(num int, wt wireType, v uint64, raw []byte) error) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errors.New("truncated tag")
+		}
+		b = b[n:]
+		num, wt := int(tag>>3), wireType(tag&7)
+		var v uint64
+		var raw []byte
+		switch wt {
+		case wireVarint:
+			v, n = binary.Uvarint(b)
+			if n <= 0 {
+				return errors.New("truncated varint")
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return errors.New("truncated fixed64")
+			}
+			v, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return errors.New("truncated fixed32")
+			}
+			v, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return errors.New("truncated bytes")
+			}
+			raw, b = b[n:n+int(l)], b[n+int(l):]
+		default:
+			return fmt.Errorf("unsupported wire type %d", wt)
+		}
+		if err := fn(num, wt, v, raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type protoField struct {
+	num   int
+	index int
+}
+
+func protoFields(t reflect.Type) ([]protoField, error) {
+	var fields []protoField
+	seen := map[int]string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || f.Tag.Get("json") == "-" {
+			continue
+		}
+		tag := f.Tag.Get("proto")
+		num, err := strconv.Atoi(tag)
+		if err != nil || num <= 0 {
+			return nil, fmt.Errorf("%s.%s: missing or invalid proto tag %q", t.Name(), f.Name, tag)
+		}
+		if other, dup := seen[num]; dup {
+			return nil, fmt.Errorf("%s: fields %s and %s share proto field %d", t.Name(), other, f.Name, num)
+		}
+		seen[num] = f.Name
+		fields = append(fields, protoField{num: num, index: i})
+	}
+	return fields, nil
+}
+
+func encodeProtoMessage(b []byte, v reflect.Value) ([]byte, error) {
+	fields, err := protoFields(v.Type())
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		if b, err = encodeProtoField(b, f.num, v.Field(f.index), false); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// encodeProtoField appends field num holding v. Zero scalars are omitted as
+// in proto3 unless present is set (pointers, repeated and map entries).
+func encodeProtoField(b []byte, num int, v reflect.Value, present bool) ([]byte, error) {
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if t.IsZero() && !present {
+			return b, nil
+		}
+		return appendBytes(b, num, encodeTimestamp(t)), nil
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return b, nil
+		}
+		return encodeProtoField(b, num, v.Elem(), true)
+	case reflect.String:
+		if v.Len() == 0 && !present {
+			return b, nil
+		}
+		return appendBytes(b, num, []byte(v.String())), nil
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		x := scalarBits(v)
+		if x == 0 && !present {
+			return b, nil
+		}
+		return binary.AppendUvarint(appendTag(b, num, wireVarint), x), nil
+	case reflect.Float32:
+		if v.Float() == 0 && !present {
+			return b, nil
+		}
+		return binary.LittleEndian.AppendUint32(appendTag(b, num, wireFixed32), math.Float32bits(float32(v.Float()))), nil
+	case reflect.Float64:
+		if v.Float() == 0 && !present {
+			return b, nil
+		}
+		return binary.LittleEndian.AppendUint64(appendTag(b, num, wireFixed64), math.Float64bits(v.Float())), nil
+	case reflect.Struct:
+		body, err := encodeProtoMessage(nil, v)
+		if err != nil {
+			return nil, err
+		}
+		return appendBytes(b, num, body), nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if v.Len() == 0 && !present {
+				return b, nil
+			}
+			return appendBytes(b, num, v.Bytes()), nil
+		}
+		if isPackable(v.Type().Elem()) {
+			if v.Len() == 0 {
+				return b, nil
+			}
+			var packed []byte
+			for i := 0; i < v.Len(); i++ {
+				var err error
+				if packed, err = encodeProtoField(packed, 0, v.Index(i), true); err != nil {
+					return nil, err
+				}
+				packed = packed[1:] // drop the placeholder tag of field 0
+			}
+			return appendBytes(b, num, packed), nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			var err error
+			if b, err = encodeProtoField(b, num, v.Index(i), true); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, k := range keys {
+			entry := appendBytes(nil, 1, []byte(k.String()))
+			entry, err := encodeProtoField(entry, 2, v.MapIndex(k), true)
+			if err != nil {
+				return nil, err
+			}
+			b = appendBytes(b, num, entry)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", v.Type())
+}
+
+func isPackable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func scalarBits(v reflect.Value) uint64 {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return 1
+		}
+		return 0
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint64(v.Int())
+	}
+	return v.Uint()
+}
+
+func decodeProtoMessage(b []byte, v reflect.Value) error {
+	fields, err := protoFields(v.Type())
+	if err != nil {
+		return err
+	}
+	byNum := make(map[int]reflect.Value, len(fields))
+	for _, f := range fields {
+		byNum[f.num] = v.Field(f.index)
+	}
+	return eachField(b, func(num int, wt wireType, x uint64, raw []byte) error {
+		fv, ok := byNum[num]
+		if !ok {
+			return nil // unknown fields come from newer producers
+		}
+		return decodeProtoField(fv, wt, x, raw)
+	})
+}
+
+func decodeProtoField(v reflect.Value, wt wireType, x uint64, raw []byte) error {
+	if v.Type() == timeType {
+		t, err := decodeTimestamp(raw)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		e := reflect.New(v.Type().Elem())
+		if err := decodeProtoField(e.Elem(), wt, x, raw); err != nil {
+			return err
+		}
+		v.Set(e)
+	case reflect.String:
+		v.SetString(string(raw))
+	case reflect.Bool:
+		v.SetBool(x != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(int64(x))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(x)
+	case reflect.Float32:
+		v.SetFloat(float64(math.Float32frombits(uint32(x))))
+	case reflect.Float64:
+		v.SetFloat(math.Float64frombits(x))
+	case reflect.Struct:
+		return decodeProtoMessage(raw, v)
+	case reflect.Slice:
+		elem := v.Type().Elem()
+		if elem.Kind() == reflect.Uint8 {
+			v.SetBytes(append([]byte(nil), raw...))
+			return nil
+		}
+		if isPackable(elem) && wt == wireBytes {
+			ewt := wireVarint
+			switch elem.Kind() {
+			case reflect.Float32:
+				ewt = wireFixed32
+			case reflect.Float64:
+				ewt = wireFixed64
+			}
+			// Packed values: a run of tagless scalars.
+			for len(raw) > 0 {
+				var n int
+				switch ewt {
+				case wireVarint:
+					x, n = binary.Uvarint(raw)
+					if n <= 0 {
+						return errors.New("truncated packed varint")
+					}
+				case wireFixed32:
+					if len(raw) < 4 {
+						return errors.New("truncated packed fixed32")
+					}
+					x, n = uint64(binary.LittleEndian.Uint32(raw)), 4
+				case wireFixed64:
+					if len(raw) < 8 {
+						return errors.New("truncated packed fixed64")
+					}
+					x, n = binary.LittleEndian.Uint64(raw), 8
+				}
+				raw = raw[n:]
+				e := reflect.New(elem).Elem()
+				if err := decodeProtoField(e, ewt, x, nil); err != nil {
+					return err
+				}
+				v.Set(reflect.Append(v, e))
+			}
+			return nil
+		}
+		e := reflect.New(elem).Elem()
+		if err := decodeProtoField(e, wt, x, raw); err != nil {
+			return err
+		}
+		v.Set(reflect.Append(v, e))
+	case reflect.Map:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		k := reflect.New(v.Type().Key()).Elem()
+		e := reflect.New(v.Type().Elem()).Elem()
+		err := eachField(raw, func(num int, wt wireType, x uint64, raw []byte) error {
+			switch num {
+			case 1:
+				return decodeProtoField(k, wt, x, raw)
+			case 2:
+				return decodeProtoField(e, wt, x, raw)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		v.SetMapIndex(k, e)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// ProtoFile renders the protobuf definitions of the envelope and every
+// contract, checked in as schemas/events.proto for consumers in other
+// languages.
+func ProtoFile() (string, error) {
+	var sb strings.Builder
+	sb.WriteString(`// Code generated by eventschema from internal/common/events. DO NOT EDIT.
+
+syntax = "proto3";
+
+package ticketing.events;
+
+import "google/protobuf/timestamp.proto";
+
+// Envelope is sent with "Content-Type: application/x-protobuf". data holds
+// the encoded contract message of the subject, e.g. TicketUpdatedData for
+// "ticket:updated".
+message Envelope {
+  string id = 1;
+  string subject = 2;
+  int32 schema_version = 3;
+  string producer = 4;
+  google.protobuf.Timestamp occurred_at = 5;
+  string correlation_id = 6;
+  bytes data = 7;
+}
+`)
+	subjects := make([]string, 0, len(Contracts))
+	for s := range Contracts {
+		subjects = append(subjects, string(s))
+	}
+	sort.Strings(subjects)
+
+	done := map[reflect.Type]bool{}
+	var write func(t reflect.Type, comment string) error
+	write = func(t reflect.Type, comment string) error {
+		if done[t] {
+			return nil
+		}
+		done[t] = true
+		fields, err := protoFields(t)
+		if err != nil {
+			return err
+		}
+		var nested []reflect.Type
+		sb.WriteString("\n")
+		if comment != "" {
+			sb.WriteString("// " + comment + "\n")
+		}
+		sb.WriteString("message " + t.Name() + " {\n")
+		for _, f := range fields {
+			sf := t.Field(f.index)
+			typ, inner, err := protoType(sf.Type)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %w", t.Name(), sf.Name, err)
+			}
+			if inner != nil {
+				nested = append(nested, inner)
+			}
+			name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+			fmt.Fprintf(&sb, "  %s %s = %d;\n", typ, snakeCase(name), f.num)
+		}
+		sb.WriteString("}\n")
+		for _, n := range nested {
+			if err := write(n, ""); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, s := range subjects {
+		subject := Subject(s)
+		if err := write(reflect.TypeOf(Contracts[subject]), fmt.Sprintf("%s v%d", subject, SchemaVersions[subject])); err != nil {
+			return "", err
+		}
+	}
+	return sb.String(), nil
+}
+
+// protoType names t in the .proto file, returning the struct type it
+// refers to, if any.
+func protoType(t reflect.Type) (string, reflect.Type, error) {
+	if t == timeType {
+		return "google.protobuf.Timestamp", nil, nil
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		s, inner, err := protoType(t.Elem())
+		if t.Elem().Kind() == reflect.Struct && t.Elem() != timeType {
+			return s, inner, err // message fields already have presence
+		}
+		return "optional " + s, inner, err
+	case reflect.String:
+		return "string", nil, nil
+	case reflect.Bool:
+		return "bool", nil, nil
+	case reflect.Int, reflect.Int64:
+		return "int64", nil, nil
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return "int32", nil, nil
+	case reflect.Uint, reflect.Uint64:
+		return "uint64", nil, nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return "uint32", nil, nil
+	case reflect.Float32:
+		return "float", nil, nil
+	case reflect.Float64:
+		return "double", nil, nil
+	case reflect.Struct:
+		return t.Name(), t, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytes", nil, nil
+		}
+		s, inner, err := protoType(t.Elem())
+		return "repeated " + s, inner, err
+	case reflect.Map:
+		s, inner, err := protoType(t.Elem())
+		return "map<string, " + s + ">", inner, err
+	}
+	return "", nil, fmt.Errorf("unsupported type %s", t)
+}
+
+func snakeCase(s string) string {
+	var sb strings.Builder
+	for i, r := range s {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				sb.WriteByte('_')
+			}
+			r += 'a' - 'A'
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,130 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/pubsub"
+)
+
+func TestProtobufRoundTrip(t *testing.T) {
+	SetProducer("orders")
+	ctx := WithCorrelationID(context.Background(), "req-1")
+	orderID := "o1"
+	cases := map[Subject]any{
+		SubjectOrderCreated: OrderCreatedData{ID: "o1", Version: 1, Status: "created", UserID: "u1",
+			ExpiresAt: time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC), Ticket: OrderTicketDetail{ID: "t1", Price: 2000}},
+		SubjectTicketUpdated: TicketUpdatedData{ID: "t1", Title: "Concert", Price: 2000, UserID: "u1", OrderID: &orderID, Version: 3},
+	}
+	for subject, data := range cases {
+		js, err := Marshal(ctx, subject, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pb, err := Transcode(subject, ContentTypeJSON, ContentTypeProtobuf, js)
+		if err != nil {
+			t.Fatalf("%s: to protobuf: %v", subject, err)
+		}
+		if len(pb) >= len(js) {
+			t.Errorf("%s: protobuf is %d bytes, JSON %d", subject, len(pb), len(js))
+		}
+		back, err := ToJSON(subject, ContentTypeProtobuf, pb)
+		if err != nil {
+			t.Fatalf("%s: to JSON: %v", subject, err)
+		}
+		var want, got map[string]any
+		_ = json.Unmarshal(js, &want)
+		_ = json.Unmarshal(back, &got)
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("%s: round trip\n got %s\nwant %s", subject, back, js)
+		}
+	}
+
+	// A typed decode reads the contract straight from the wire.
+	js, _ := Marshal(ctx, SubjectTicketUpdated, cases[SubjectTicketUpdated])
+	pb, _ := Transcode(SubjectTicketUpdated, ContentTypeJSON, ContentTypeProtobuf, js)
+	env, err := DecodeMessage[TicketUpdatedData](SubjectTicketUpdated, pubsub.Message{Data: pb, Header: pubsub.Header{HeaderContentType: ContentTypeProtobuf}})
+	if err != nil {
+		t.Fatalf("DecodeMessage: %v", err)
+	}
+	if got := env.Data; got.ID != "t1" || got.Price != 2000 || got.OrderID == nil || *got.OrderID != "o1" || got.Version != 3 {
+		t.Errorf("Data = %+v", got)
+	}
+	if env.ID != "ticket:updated:t1:3" || env.Producer != "orders" || env.CorrelationID != "req-1" || env.SchemaVersion != 1 {
+		t.Errorf("metadata = %+v", env)
+	}
+}
+
+func TestDecodeMessageVerifiesProtobufEnvelopes(t *testing.T) {
+	js, _ := Marshal(context.Background(), SubjectOrderCancelled, OrderCancelledData{ID: "o1", Version: 2})
+	pb, _ := Transcode(SubjectOrderCancelled, ContentTypeJSON, ContentTypeProtobuf, js)
+	proto := pubsub.Header{HeaderContentType: ContentTypeProtobuf}
+
+	cases := map[string]pubsub.Message{
+		"wrong subject":      {Data: pb, Header: proto, Subject: "ignored"},
+		"truncated":          {Data: pb[:len(pb)-3], Header: proto},
+		"unknown type":       {Data: pb, Header: pubsub.Header{HeaderContentType: "application/xml"}},
+		"proto read as json": {Data: pb},
+	}
+	for name, m := range cases {
+		subject := SubjectOrderCancelled
+		if name == "wrong subject" {
+			subject = SubjectOrderCreated
+		}
+		if _, err := DecodeMessage[OrderCancelledData](subject, m); !errors.Is(err, ErrInvalidEnvelope) {
+			t.Errorf("%s: err = %v, want ErrInvalidEnvelope", name, err)
+		}
+	}
+}
+
+func TestSubscribeConsumesBothEncodings(t *testing.T) {
+	defer SetEncoding("json")
+	ctx := context.Background()
+	bus := pubsub.NewMemory()
+	var got []int
+	c := &Consumer{Sub: bus, Service: "orders"}
+	if err := Subscribe(ctx, c, SubjectTicketUpdated, func(_ context.Context, e Envelope[TicketUpdatedData]) error {
+		got = append(got, e.Data.Version)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, enc := range []string{"json", "protobuf"} {
+		if err := SetEncoding(enc); err != nil {
+			t.Fatal(err)
+		}
+		if err := Publish(ctx, bus, SubjectTicketUpdated, TicketUpdatedData{ID: "t1", Title: "x", Price: 1, UserID: "u1", Version: i + 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	msgs := bus.Messages()
+	if msgs[0].Header[HeaderContentType] != ContentTypeJSON || msgs[1].Header[HeaderContentType] != ContentTypeProtobuf {
+		t.Errorf("content types = %q, %q", msgs[0].Header[HeaderContentType], msgs[1].Header[HeaderContentType])
+	}
+	if !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("handled versions %v, want [1 2]", got)
+	}
+	if err := SetEncoding("avro"); err == nil {
+		t.Error("unknown encoding accepted")
+	}
+}
+
+func TestCheckedInProtoMatchesContracts(t *testing.T) {
+	want, err := ProtoFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("schemas/events.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Error("schemas/events.proto is stale; run go generate ./internal/common/events")
+	}
+}
@@ -0,0 +1,386 @@
+package events
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:generate go run ../../../cmd/eventschema -dir schemas
+
+// Contracts maps every subject to the Go type of its Data. The JSON Schemas
+// and events.proto in schemas/ are generated from these types.
+var Contracts = map[Subject]any{
+	SubjectTicketCreated:      TicketCreatedData{},
+	SubjectTicketUpdated:      TicketUpdatedData{},
+	SubjectTicketDeleted:      TicketDeletedData{},
+	SubjectTicketTransferred:  TicketTransferredData{},
+	SubjectOrderCreated:       OrderCreatedData{},
+	SubjectOrderCancelled:     OrderCancelledData{},
+	SubjectExpirationComplete: ExpirationCompleteData{},
+	SubjectPaymentCreated:     PaymentCreatedData{},
+	SubjectUserCreated:        UserCreatedData{},
+	SubjectEventCreated:       EventCreatedData{},
+	SubjectEventUpdated:       EventUpdatedData{},
+}
+
+// Schema is the subset of JSON Schema the event contracts need.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	ID                   string             `json:"$id,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Type                 Types              `json:"type"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	// ProtoField is the property's protobuf field number (see proto.go).
+	ProtoField int `json:"x-protoField,omitempty"`
+}
+
+// Types is a schema's "type": a single type name, or a list when the value
+// may be one of several (a pointer field is ["string", "null"]).
+type Types []string
+
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+func (t *Types) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*t = Types{one}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(t))
+}
+
+func (t Types) has(name string) bool {
+	for _, n := range t {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SchemaFile is the file name of subject's schema at version, e.g.
+// "ticket.updated.v1.json".
+func SchemaFile(subject Subject, version int) string {
+	return fmt.Sprintf("%s.v%d.json", strings.ReplaceAll(string(subject), ":", "."), version)
+}
+
+// GenerateSchema builds the schema of subject's current contract.
+func GenerateSchema(subject Subject) (*Schema, error) {
+	c, ok := Contracts[subject]
+	if !ok {
+		return nil, fmt.Errorf("no contract for %s", subject)
+	}
+	s, err := schemaOf(reflect.TypeOf(c))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", subject, err)
+	}
+	version := SchemaVersions[subject]
+	s.Schema = "https://json-schema.org/draft/2020-12/schema"
+	s.ID = SchemaFile(subject, version)
+	s.Title = fmt.Sprintf("%s v%d", subject, version)
+	return s, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func schemaOf(t reflect.Type) (*Schema, error) {
+	if t == timeType {
+		return &Schema{Type: Types{"string"}, Format: "date-time"}, nil
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		s, err := schemaOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		s.Type = append(s.Type, "null")
+		return s, nil
+	case reflect.String:
+		return &Schema{Type: Types{"string"}}, nil
+	case reflect.Bool:
+		return &Schema{Type: Types{"boolean"}}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: Types{"integer"}}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: Types{"number"}}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := schemaOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: Types{"array"}, Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map key %s is not a string", t.Key())
+		}
+		values, err := schemaOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: Types{"object"}, AdditionalProperties: values}, nil
+	case reflect.Struct:
+		s := &Schema{Type: Types{"object"}, Properties: map[string]*Schema{}}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			p, err := schemaOf(f.Type)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			p.ProtoField, _ = strconv.Atoi(f.Tag.Get("proto"))
+			s.Properties[name] = p
+			// Fields the encoder always writes are required.
+			if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Pointer {
+				s.Required = append(s.Required, name)
+			}
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// Validate checks the JSON document b against s.
+func (s *Schema) Validate(b []byte) error {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return err
+	}
+	return s.validate(v, "data")
+}
+
+func (s *Schema) validate(v any, path string) error {
+	var got string
+	switch v := v.(type) {
+	case nil:
+		got = "null"
+	case bool:
+		got = "boolean"
+	case string:
+		got = "string"
+	case json.Number:
+		got = "number"
+		if _, err := v.Int64(); err == nil {
+			got = "integer"
+		}
+	case []any:
+		got = "array"
+	case map[string]any:
+		got = "object"
+	}
+	if !s.Type.has(got) && !(got == "integer" && s.Type.has("number")) {
+		return fmt.Errorf("%s: %s, want %s", path, got, strings.Join(s.Type, " or "))
+	}
+
+	switch v := v.(type) {
+	case string:
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+				return fmt.Errorf("%s: %q is not a date-time", path, v)
+			}
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range v {
+				if err := s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s.%s: required", path, name)
+			}
+		}
+		// Unknown properties are allowed, so producers can add optional
+		// fields before every consumer knows them.
+		for name, pv := range v {
+			p := s.Properties[name]
+			if p == nil {
+				p = s.AdditionalProperties
+			}
+			if p == nil {
+				continue
+			}
+			if err := p.validate(pv, path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Compatible lists the ways next breaks consumers of prev, or nil when every
+// message valid under prev is still valid under next and the other way
+// round, in JSON and protobuf. Adding optional properties is compatible.
+// Removing a property, changing its type, format or protobuf field number,
+// and adding or dropping a requirement are not: they need a new schema
+// version.
+func Compatible(prev, next *Schema) []string {
+	return compatible(prev, next, "data", nil)
+}
+
+func compatible(prev, next *Schema, path string, breaks []string) []string {
+	if !sameTypes(prev.Type, next.Type) {
+		return append(breaks, fmt.Sprintf("%s: type changed from %s to %s", path, strings.Join(prev.Type, "|"), strings.Join(next.Type, "|")))
+	}
+	if prev.ProtoField != 0 && prev.ProtoField != next.ProtoField {
+		breaks = append(breaks, fmt.Sprintf("%s: proto field number changed from %d to %d", path, prev.ProtoField, next.ProtoField))
+	}
+	if prev.Format != next.Format {
+		breaks = append(breaks, fmt.Sprintf("%s: format changed from %q to %q", path, prev.Format, next.Format))
+	}
+
+	names := make([]string, 0, len(prev.Properties))
+	for name := range prev.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		np, ok := next.Properties[name]
+		if !ok {
+			breaks = append(breaks, fmt.Sprintf("%s.%s: removed", path, name))
+			continue
+		}
+		breaks = compatible(prev.Properties[name], np, path+"."+name, breaks)
+	}
+
+	prevReq, nextReq := set(prev.Required), set(next.Required)
+	for _, name := range prev.Required {
+		if !nextReq[name] {
+			breaks = append(breaks, fmt.Sprintf("%s.%s: no longer required", path, name))
+		}
+	}
+	for _, name := range next.Required {
+		if !prevReq[name] {
+			breaks = append(breaks, fmt.Sprintf("%s.%s: newly required", path, name))
+		}
+	}
+
+	for _, sub := range []struct {
+		name       string
+		prev, next *Schema
+	}{{"[]", prev.Items, next.Items}, {"{}", prev.AdditionalProperties, next.AdditionalProperties}} {
+		switch {
+		case sub.prev != nil && sub.next != nil:
+			breaks = compatible(sub.prev, sub.next, path+sub.name, breaks)
+		case sub.prev != nil || sub.next != nil:
+			breaks = append(breaks, fmt.Sprintf("%s%s: element schema changed", path, sub.name))
+		}
+	}
+	return breaks
+}
+
+func sameTypes(a, b Types) bool {
+	return set(a).equal(set(b))
+}
+
+type stringSet map[string]bool
+
+func set(names []string) stringSet {
+	s := make(stringSet, len(names))
+	for _, n := range names {
+		s[n] = true
+	}
+	return s
+}
+
+func (s stringSet) equal(o stringSet) bool {
+	if len(s) != len(o) {
+		return false
+	}
+	for n := range s {
+		if !o[n] {
+			return false
+		}
+	}
+	return true
+}
+
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+var (
+	loadSchemas sync.Once
+	schemas     map[string]*Schema
+	schemasErr  error
+)
+
+// CheckedInSchema returns the schema checked in for subject at version, or
+// nil if there is none.
+func CheckedInSchema(subject Subject, version int) (*Schema, error) {
+	loadSchemas.Do(func() {
+		schemas = map[string]*Schema{}
+		entries, err := schemaFiles.ReadDir("schemas")
+		if err != nil {
+			schemasErr = err
+			return
+		}
+		for _, e := range entries {
+			b, err := schemaFiles.ReadFile("schemas/" + e.Name())
+			if err != nil {
+				schemasErr = err
+				return
+			}
+			var s Schema
+			if err := json.Unmarshal(b, &s); err != nil {
+				schemasErr = fmt.Errorf("schemas/%s: %w", e.Name(), err)
+				return
+			}
+			schemas[e.Name()] = &s
+		}
+	})
+	if schemasErr != nil {
+		return nil, schemasErr
+	}
+	return schemas[SchemaFile(subject, version)], nil
+}
+
+// ErrSchemaViolation is returned for envelopes whose data does not match the
+// schema of their subject and version.
+var ErrSchemaViolation = fmt.Errorf("%w: schema violation", ErrInvalidEnvelope)
+
+// ValidateData checks data received on subject against the checked-in
+// schema for version. Subjects without a schema are not checked.
+func ValidateData(subject Subject, version int, data []byte) error {
+	s, err := CheckedInSchema(subject, version)
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		return nil
+	}
+	if err := s.Validate(data); err != nil {
+		return fmt.Errorf("%w: %s v%d: %v", ErrSchemaViolation, subject, version, err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/pubsub"
+)
+
+func TestCheckedInSchemasMatchContracts(t *testing.T) {
+	for subject := range Contracts {
+		want, err := GenerateSchema(subject)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := CheckedInSchema(subject, SchemaVersions[subject])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got == nil {
+			t.Errorf("%s: no checked-in schema; run go generate ./internal/common/events", subject)
+			continue
+		}
+		gb, _ := json.Marshal(got)
+		wb, _ := json.Marshal(want)
+		if !bytes.Equal(gb, wb) {
+			t.Errorf("%s: checked-in schema is stale; run go generate ./internal/common/events", subject)
+		}
+	}
+}
+
+func TestDecodeRejectsDataViolatingSchema(t *testing.T) {
+	ctx := context.Background()
+	valid, _ := Marshal(ctx, SubjectTicketUpdated, TicketUpdatedData{ID: "t1", Title: "Concert", Price: 2000, UserID: "u1", Version: 2})
+	if _, err := Decode[TicketUpdatedData](SubjectTicketUpdated, valid); err != nil {
+		t.Fatalf("valid message rejected: %v", err)
+	}
+
+	cases := map[string]func(data map[string]any){
+		"price as string":  func(d map[string]any) { d["price"] = "2000" },
+		"fractional price": func(d map[string]any) { d["price"] = 20.5 },
+		"missing version":  func(d map[string]any) { delete(d, "version") },
+		"orderId number":   func(d map[string]any) { d["orderId"] = 7 },
+	}
+	for name, mutate := range cases {
+		var env map[string]any
+		_ = json.Unmarshal(valid, &env)
+		mutate(env["data"].(map[string]any))
+		b, _ := json.Marshal(env)
+		_, err := Decode[TicketUpdatedData](SubjectTicketUpdated, b)
+		if !errors.Is(err, ErrSchemaViolation) || !errors.Is(err, ErrInvalidEnvelope) {
+			t.Errorf("%s: err = %v, want ErrSchemaViolation", name, err)
+		}
+	}
+
+	// Optional and unknown properties may be null or absent.
+	var env map[string]any
+	_ = json.Unmarshal(valid, &env)
+	env["data"].(map[string]any)["orderId"] = nil
+	env["data"].(map[string]any)["venue"] = "Arena"
+	b, _ := json.Marshal(env)
+	if _, err := Decode[TicketUpdatedData](SubjectTicketUpdated, b); err != nil {
+		t.Errorf("null orderId and unknown property rejected: %v", err)
+	}
+}
+
+func TestSubscribeDeadLettersSchemaViolations(t *testing.T) {
+	ctx := context.Background()
+	bus := pubsub.NewMemory()
+	var dead []FailedMessage
+	c := &Consumer{Sub: bus, Service: "orders", DeadLetters: sinkFunc(func(_ context.Context, m FailedMessage) error {
+		dead = append(dead, m)
+		return nil
+	})}
+	calls := 0
+	if err := Subscribe(ctx, c, SubjectOrderCancelled, func(context.Context, Envelope[OrderCancelledData]) error {
+		calls++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, _ := Marshal(ctx, SubjectOrderCancelled, map[string]any{"id": "o1", "ticket": map[string]any{"id": "t1", "price": 10}})
+	_ = bus.Publish(ctx, string(SubjectOrderCancelled), msg)
+
+	if calls != 0 || len(dead) != 1 || !errors.Is(dead[0].Err, ErrSchemaViolation) || dead[0].Attempts != 1 {
+		t.Fatalf("calls=%d dead=%+v", calls, dead)
+	}
+}
+
+func TestCompatible(t *testing.T) {
+	base := func() *Schema {
+		s, err := GenerateSchema(SubjectOrderCreated)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	cases := []struct {
+		name   string
+		change func(s *Schema)
+		breaks string
+	}{
+		{"unchanged", func(*Schema) {}, ""},
+		{"optional property added", func(s *Schema) { s.Properties["venue"] = &Schema{Type: Types{"string", "null"}} }, ""},
+		{"property removed", func(s *Schema) { delete(s.Properties, "status") }, "data.status: removed"},
+		{"type changed", func(s *Schema) { s.Properties["ticket"].Properties["price"].Type = Types{"string"} }, "data.ticket.price: type changed"},
+		{"made nullable", func(s *Schema) { s.Properties["userId"].Type = Types{"string", "null"} }, "data.userId: type changed"},
+		{"proto field renumbered", func(s *Schema) { s.Properties["status"].ProtoField = 9 }, "data.status: proto field number changed"},
+		{"format changed", func(s *Schema) { s.Properties["expiresAt"].Format = "" }, "data.expiresAt: format changed"},
+		{"required dropped", func(s *Schema) { s.Required = s.Required[1:] }, "data.id: no longer required"},
+		{"required added", func(s *Schema) {
+			s.Properties["venue"] = &Schema{Type: Types{"string"}}
+			s.Required = append(s.Required, "venue")
+		}, "data.venue: newly required"},
+	}
+	for _, c := range cases {
+		next := base()
+		c.change(next)
+		breaks := Compatible(base(), next)
+		got := strings.Join(breaks, "\n")
+		if c.breaks == "" && len(breaks) > 0 || !strings.Contains(got, c.breaks) {
+			t.Errorf("%s: breaks = %q, want %q", c.name, breaks, c.breaks)
+		}
+	}
+}
@@ -6,9 +6,31 @@ type Subject string
 const (
 	SubjectTicketCreated      Subject = "ticket:created"
 	SubjectTicketUpdated      Subject = "ticket:updated"
+	SubjectTicketDeleted      Subject = "ticket:deleted"
+	SubjectTicketTransferred  Subject = "ticket:transferred"
 	SubjectOrderCreated       Subject = "order:created"
 	SubjectOrderCancelled     Subject = "order:cancelled"
 	SubjectExpirationComplete Subject = "expiration:complete"
 	SubjectPaymentCreated     Subject = "payment:created"
 	SubjectUserCreated        Subject = "user:created"
+	SubjectEventCreated       Subject = "event:created"
+	SubjectEventUpdated       Subject = "event:updated"
 )
+
+// AllSubjects lists every subject in the system. Durable brokers use it to
+// provision streams up front.
+func AllSubjects() []string {
+	return []string{
+		string(SubjectTicketCreated),
+		string(SubjectTicketUpdated),
+		string(SubjectTicketDeleted),
+		string(SubjectTicketTransferred),
+		string(SubjectOrderCreated),
+		string(SubjectOrderCancelled),
+		string(SubjectExpirationComplete),
+		string(SubjectPaymentCreated),
+		string(SubjectUserCreated),
+		string(SubjectEventCreated),
+		string(SubjectEventUpdated),
+	}
+}
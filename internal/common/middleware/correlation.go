@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
+)
+
+// CorrelationHeader carries the correlation ID between services and clients.
+const CorrelationHeader = "X-Correlation-ID"
+
+// Correlation attaches a correlation ID to the request context so events
+// published while serving the request can be traced back to it. An incoming
+// X-Correlation-ID header wins; otherwise chi's request ID is used.
+func Correlation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(CorrelationHeader)
+		if id == "" {
+			id = chimw.GetReqID(r.Context())
+		}
+		if id == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set(CorrelationHeader, id)
+		next.ServeHTTP(w, r.WithContext(events.WithCorrelationID(r.Context(), id)))
+	})
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// OpenInternalEnv is the environment variable that, set to "true" while
+// INTERNAL_API_TOKEN is unset, leaves the /internal endpoints open. It is for
+// local development only.
+const OpenInternalEnv = "INTERNAL_API_OPEN"
+
+// RequireInternalToken guards service-to-service and operator endpoints
+// under /internal: requests must send "Authorization: Bearer <token>". An
+// empty token refuses every request with 503, so a deployment that forgot
+// to configure one does not serve its internal endpoints to anyone.
+func RequireInternalToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == "" {
+				http.Error(w, "internal endpoint disabled: no token configured", http.StatusServiceUnavailable)
+				return
+			}
+			got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// InternalToken is RequireInternalToken with INTERNAL_API_TOKEN, except that
+// without a token and with INTERNAL_API_OPEN=true it lets every request
+// through.
+func InternalToken() func(http.Handler) http.Handler {
+	token := os.Getenv("INTERNAL_API_TOKEN")
+	if token == "" && os.Getenv(OpenInternalEnv) == "true" {
+		log.Printf("warn: INTERNAL_API_TOKEN is not set and %s=true, /internal endpoints are open", OpenInternalEnv)
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return RequireInternalToken(token)
+}
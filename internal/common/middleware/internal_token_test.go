@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInternalTokenFailsClosed(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for _, tc := range []struct {
+		name  string
+		token string
+		open  string
+		auth  string
+		want  int
+	}{
+		{"no token configured", "", "", "", http.StatusServiceUnavailable},
+		{"no token configured, any bearer", "", "", "Bearer guess", http.StatusServiceUnavailable},
+		{"open for development", "", "true", "", http.StatusOK},
+		{"token ignores open", "secret", "true", "", http.StatusUnauthorized},
+		{"wrong token", "secret", "", "Bearer guess", http.StatusUnauthorized},
+		{"right token", "secret", "", "Bearer secret", http.StatusOK},
+	} {
+		t.Setenv("INTERNAL_API_TOKEN", tc.token)
+		t.Setenv(OpenInternalEnv, tc.open)
+		req := httptest.NewRequest(http.MethodGet, "/internal/tickets/snapshot", nil)
+		if tc.auth != "" {
+			req.Header.Set("Authorization", tc.auth)
+		}
+		rec := httptest.NewRecorder()
+		InternalToken()(ok).ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, rec.Code, tc.want)
+		}
+	}
+}
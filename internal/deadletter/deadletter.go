@@ -0,0 +1,142 @@
+// Package deadletter stores events a service failed to process so they can be
+// inspected and replayed with cmd/eventctl.
+package deadletter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/pubsub"
+)
+
+// Entry is one dead-lettered message.
+type Entry struct {
+	ID          int64      `json:"id"`
+	Service     string     `json:"service"`
+	Subject     string     `json:"subject"`
+	EventID     string     `json:"eventId,omitempty"`
+	ContentType string     `json:"contentType"`
+	Payload     []byte     `json:"payload"`
+	Reason      string     `json:"reason"`
+	Attempts    int        `json:"attempts"`
+	FailedAt    time.Time  `json:"failedAt"`
+	ReplayedAt  *time.Time `json:"replayedAt,omitempty"`
+}
+
+// Filter narrows List results. Zero values match everything.
+type Filter struct {
+	Service         string
+	Subject         string
+	IncludeReplayed bool
+	Limit           int
+}
+
+// Store keeps dead letters in the dead_letters table of a service database.
+type Store struct{ db *sql.DB }
+
+func NewStore(db *sql.DB) *Store { return &Store{db: db} }
+
+func (s *Store) EnsureSchema(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS dead_letters (
+    id BIGSERIAL PRIMARY KEY,
+    service TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    event_id TEXT NOT NULL DEFAULT '',
+    content_type TEXT NOT NULL DEFAULT 'application/json',
+    payload BYTEA NOT NULL,
+    reason TEXT NOT NULL,
+    attempts INT NOT NULL,
+    failed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    replayed_at TIMESTAMPTZ NULL
+);
+ALTER TABLE dead_letters ADD COLUMN IF NOT EXISTS content_type TEXT NOT NULL DEFAULT 'application/json';
+CREATE INDEX IF NOT EXISTS dead_letters_pending_idx ON dead_letters (service, failed_at) WHERE replayed_at IS NULL;
+`)
+	return err
+}
+
+// DeadLetter implements events.DeadLetterSink.
+func (s *Store) DeadLetter(ctx context.Context, m events.FailedMessage) error {
+	reason := "unknown"
+	if m.Err != nil {
+		reason = m.Err.Error()
+	}
+	contentType := m.ContentType
+	if contentType == "" {
+		contentType = events.ContentTypeJSON
+	}
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO dead_letters (service, subject, event_id, content_type, payload, reason, attempts)
+VALUES ($1,$2,$3,$4,$5,$6,$7)
+`, m.Service, string(m.Subject), m.EventID, contentType, m.Payload, reason, m.Attempts)
+	return err
+}
+
+func (s *Store) List(ctx context.Context, f Filter) ([]*Entry, error) {
+	if f.Limit <= 0 {
+		f.Limit = 50
+	}
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, service, subject, event_id, content_type, payload, reason, attempts, failed_at, replayed_at
+FROM dead_letters
+WHERE ($1 = '' OR service = $1)
+  AND ($2 = '' OR subject = $2)
+  AND ($3 OR replayed_at IS NULL)
+ORDER BY id DESC
+LIMIT $4
+`, f.Service, f.Subject, f.IncludeReplayed, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []*Entry
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.ID, &e.Service, &e.Subject, &e.EventID, &e.ContentType, &e.Payload, &e.Reason, &e.Attempts, &e.FailedAt, &e.ReplayedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, &e)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) Get(ctx context.Context, id int64) (*Entry, error) {
+	var e Entry
+	err := s.db.QueryRowContext(ctx, `
+SELECT id, service, subject, event_id, content_type, payload, reason, attempts, failed_at, replayed_at
+FROM dead_letters WHERE id=$1
+`, id).Scan(&e.ID, &e.Service, &e.Subject, &e.EventID, &e.ContentType, &e.Payload, &e.Reason, &e.Attempts, &e.FailedAt, &e.ReplayedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &e, nil
+}
+
+// ErrNotFound is returned by Replay for unknown entries.
+var ErrNotFound = errors.New("dead letter not found")
+
+// Replay republishes an entry's original payload and content type on its
+// original subject and marks it replayed. Every subscriber of the subject receives it again;
+// consumers that already processed the event skip it via their inbox.
+func (s *Store) Replay(ctx context.Context, pub pubsub.Publisher, id int64) error {
+	e, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if e == nil {
+		return ErrNotFound
+	}
+	msg := pubsub.Message{Subject: e.Subject, Data: e.Payload, Header: pubsub.Header{events.HeaderContentType: e.ContentType}}
+	if err := pub.PublishMsg(ctx, msg); err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `UPDATE dead_letters SET replayed_at=now() WHERE id=$1`, id)
+	return err
+}
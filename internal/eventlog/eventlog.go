@@ -0,0 +1,192 @@
+// Package eventlog archives every event on the bus so the history of an
+// order, ticket or user can be read back as one timeline.
+package eventlog
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
+)
+
+// Entry is one archived event.
+type Entry struct {
+	ID            int64           `json:"id"`
+	EventID       string          `json:"eventId"`
+	Subject       string          `json:"subject"`
+	SchemaVersion int             `json:"schemaVersion"`
+	Producer      string          `json:"producer"`
+	CorrelationID string          `json:"correlationId,omitempty"`
+	OccurredAt    time.Time       `json:"occurredAt"`
+	ReceivedAt    time.Time       `json:"receivedAt"`
+	AggregateID   string          `json:"aggregateId"`
+	OrderID       string          `json:"orderId,omitempty"`
+	TicketID      string          `json:"ticketId,omitempty"`
+	UserID        string          `json:"userId,omitempty"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// Parse builds the archive entry for a message received on subject. The
+// order, ticket and user an event concerns are read from its data by
+// convention: "id" is the aggregate named by the subject's prefix
+// ("order:cancelled" is about an order), and "orderId", "ticketId",
+// "ticket.id" and "userId" name the others. New subjects following these
+// conventions are indexed without changes here.
+func Parse(subject string, msg []byte) (*Entry, error) {
+	var env events.Envelope[json.RawMessage]
+	if err := json.Unmarshal(msg, &env); err != nil {
+		return nil, fmt.Errorf("%w: %v", events.ErrInvalidEnvelope, err)
+	}
+	if env.ID == "" {
+		return nil, fmt.Errorf("%w: missing id", events.ErrInvalidEnvelope)
+	}
+	var data struct {
+		ID       string `json:"id"`
+		OrderID  string `json:"orderId"`
+		TicketID string `json:"ticketId"`
+		UserID   string `json:"userId"`
+		Ticket   struct {
+			ID string `json:"id"`
+		} `json:"ticket"`
+	}
+	// Data that is not an object simply has no references.
+	_ = json.Unmarshal(env.Data, &data)
+
+	e := &Entry{
+		EventID:       env.ID,
+		Subject:       subject,
+		SchemaVersion: env.SchemaVersion,
+		Producer:      env.Producer,
+		CorrelationID: env.CorrelationID,
+		OccurredAt:    env.OccurredAt,
+		OrderID:       data.OrderID,
+		TicketID:      data.TicketID,
+		UserID:        data.UserID,
+		Data:          env.Data,
+	}
+	if e.TicketID == "" {
+		e.TicketID = data.Ticket.ID
+	}
+	if len(e.Data) == 0 {
+		e.Data = json.RawMessage("null")
+	}
+
+	e.AggregateID = data.ID
+	switch family, _, _ := strings.Cut(subject, ":"); family {
+	case "order":
+		e.OrderID = data.ID
+	case "ticket":
+		e.TicketID = data.ID
+	case "user":
+		e.UserID = data.ID
+	}
+	if e.AggregateID == "" {
+		e.AggregateID = e.OrderID // e.g. expiration:complete
+	}
+	return e, nil
+}
+
+// Store keeps the archive in the event_log table.
+type Store struct{ db *sql.DB }
+
+func NewStore(db *sql.DB) *Store { return &Store{db: db} }
+
+func (s *Store) EnsureSchema(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS event_log (
+    id BIGSERIAL PRIMARY KEY,
+    event_id TEXT NOT NULL UNIQUE,
+    subject TEXT NOT NULL,
+    schema_version INT NOT NULL,
+    producer TEXT NOT NULL,
+    correlation_id TEXT NOT NULL DEFAULT '',
+    occurred_at TIMESTAMPTZ NOT NULL,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    aggregate_id TEXT NOT NULL DEFAULT '',
+    order_id TEXT NULL,
+    ticket_id TEXT NULL,
+    user_id TEXT NULL,
+    data JSONB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS event_log_order_idx ON event_log (order_id, occurred_at) WHERE order_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS event_log_ticket_idx ON event_log (ticket_id, occurred_at) WHERE ticket_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS event_log_user_idx ON event_log (user_id, occurred_at) WHERE user_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS event_log_correlation_idx ON event_log (correlation_id, occurred_at);
+`)
+	return err
+}
+
+// Append archives e. Events already archived (same event ID) are ignored,
+// so redeliveries and replays are harmless.
+func (s *Store) Append(ctx context.Context, e *Entry) error {
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO event_log (event_id, subject, schema_version, producer, correlation_id, occurred_at, aggregate_id, order_id, ticket_id, user_id, data)
+VALUES ($1,$2,$3,$4,$5,$6,$7,NULLIF($8,''),NULLIF($9,''),NULLIF($10,''),$11)
+ON CONFLICT (event_id) DO NOTHING
+`, e.EventID, e.Subject, e.SchemaVersion, e.Producer, e.CorrelationID, e.OccurredAt, e.AggregateID, e.OrderID, e.TicketID, e.UserID, []byte(e.Data))
+	return err
+}
+
+// Timeline kinds accepted by Store.Timeline.
+const (
+	ByOrder       = "orders"
+	ByTicket      = "tickets"
+	ByUser        = "users"
+	ByCorrelation = "correlations"
+)
+
+// timelineWhere selects the events of each timeline kind. Ticket and user
+// timelines include the events of every order placed for the ticket or by
+// the user, so payments and expirations show up alongside.
+var timelineWhere = map[string]string{
+	ByOrder: `order_id = $1`,
+	ByTicket: `ticket_id = $1 OR order_id IN (
+    SELECT order_id FROM event_log WHERE ticket_id = $1 AND order_id IS NOT NULL)`,
+	ByUser: `user_id = $1 OR order_id IN (
+    SELECT order_id FROM event_log WHERE user_id = $1 AND order_id IS NOT NULL)`,
+	ByCorrelation: `correlation_id = $1`,
+}
+
+// ErrUnknownTimeline is returned for a timeline kind Store.Timeline does not know.
+var ErrUnknownTimeline = errors.New("eventlog: unknown timeline")
+
+// Timeline returns up to limit events concerning id, oldest first. kind is
+// one of ByOrder, ByTicket, ByUser or ByCorrelation.
+func (s *Store) Timeline(ctx context.Context, kind, id string, limit int) ([]*Entry, error) {
+	where, ok := timelineWhere[kind]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTimeline, kind)
+	}
+	if limit <= 0 {
+		limit = 500
+	}
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, event_id, subject, schema_version, producer, correlation_id, occurred_at, received_at,
+       aggregate_id, COALESCE(order_id, ''), COALESCE(ticket_id, ''), COALESCE(user_id, ''), data
+FROM event_log
+WHERE `+where+`
+ORDER BY occurred_at, id
+LIMIT $2
+`, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []*Entry{}
+	for rows.Next() {
+		var e Entry
+		var data []byte
+		if err := rows.Scan(&e.ID, &e.EventID, &e.Subject, &e.SchemaVersion, &e.Producer, &e.CorrelationID, &e.OccurredAt, &e.ReceivedAt,
+			&e.AggregateID, &e.OrderID, &e.TicketID, &e.UserID, &data); err != nil {
+			return nil, err
+		}
+		e.Data = data
+		out = append(out, &e)
+	}
+	return out, rows.Err()
+}
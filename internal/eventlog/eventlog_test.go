@@ -0,0 +1,51 @@
+package eventlog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
+)
+
+func TestParseIndexesRelatedAggregates(t *testing.T) {
+	ctx := events.WithCorrelationID(context.Background(), "req-1")
+	orderID := "o1"
+	cases := []struct {
+		subject events.Subject
+		data    any
+		want    Entry
+	}{
+		{events.SubjectTicketCreated, events.TicketCreatedData{ID: "t1", UserID: "seller"},
+			Entry{AggregateID: "t1", TicketID: "t1", UserID: "seller"}},
+		{events.SubjectTicketUpdated, events.TicketUpdatedData{ID: "t1", UserID: "seller", OrderID: &orderID, Version: 1},
+			Entry{AggregateID: "t1", TicketID: "t1", UserID: "seller", OrderID: "o1"}},
+		{events.SubjectOrderCreated, events.OrderCreatedData{ID: "o1", UserID: "buyer", Ticket: events.OrderTicketDetail{ID: "t1"}},
+			Entry{AggregateID: "o1", OrderID: "o1", TicketID: "t1", UserID: "buyer"}},
+		{events.SubjectExpirationComplete, events.ExpirationCompleteData{OrderID: "o1"},
+			Entry{AggregateID: "o1", OrderID: "o1"}},
+		{events.SubjectPaymentCreated, events.PaymentCreatedData{ID: "p1", OrderID: "o1"},
+			Entry{AggregateID: "p1", OrderID: "o1"}},
+		{events.SubjectUserCreated, events.UserCreatedData{ID: "u1"},
+			Entry{AggregateID: "u1", UserID: "u1"}},
+	}
+	for _, c := range cases {
+		msg, err := events.Marshal(ctx, c.subject, c.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err := Parse(string(c.subject), msg)
+		if err != nil {
+			t.Fatalf("%s: %v", c.subject, err)
+		}
+		if e.AggregateID != c.want.AggregateID || e.OrderID != c.want.OrderID || e.TicketID != c.want.TicketID || e.UserID != c.want.UserID {
+			t.Errorf("%s: refs = agg %q order %q ticket %q user %q, want %+v", c.subject, e.AggregateID, e.OrderID, e.TicketID, e.UserID, c.want)
+		}
+		if e.EventID == "" || e.CorrelationID != "req-1" || e.Subject != string(c.subject) || len(e.Data) == 0 {
+			t.Errorf("%s: metadata = %+v", c.subject, e)
+		}
+	}
+
+	if _, err := Parse("order:created", []byte(`{"data":{}}`)); err == nil {
+		t.Error("envelope without id parsed")
+	}
+}
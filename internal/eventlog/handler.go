@@ -0,0 +1,34 @@
+package eventlog
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type HTTPHandler struct{ store *Store }
+
+func NewHTTPHandler(s *Store) *HTTPHandler { return &HTTPHandler{store: s} }
+
+// Timeline serves the events concerning one order, ticket, user or
+// correlation ID, oldest first:
+//
+//	GET /internal/events/{kind}/{id}?limit=500
+func (h *HTTPHandler) Timeline(w http.ResponseWriter, r *http.Request) {
+	kind, id := chi.URLParam(r, "kind"), chi.URLParam(r, "id")
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	list, err := h.store.Timeline(r.Context(), kind, id, limit)
+	if errors.Is(err, ErrUnknownTimeline) {
+		http.Error(w, "unknown timeline: use orders, tickets, users or correlations", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(list)
+}
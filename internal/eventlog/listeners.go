@@ -0,0 +1,37 @@
+package eventlog
+
+import (
+	"context"
+	"log"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/pubsub"
+)
+
+// QueueGroup is the queue group shared by all eventlog replicas.
+const QueueGroup = "eventlog"
+
+// RegisterNATSListeners archives every subject in events.AllSubjects.
+// Protobuf messages are archived as their JSON equivalent. A message that is
+// not a valid envelope is logged and dropped; a failed insert is redelivered
+// by the broker.
+func RegisterNATSListeners(sub pubsub.Subscriber, s *Store) error {
+	for _, subject := range events.AllSubjects() {
+		if err := sub.QueueSubscribe(subject, QueueGroup, func(ctx context.Context, m pubsub.Message) error {
+			b, err := events.ToJSON(events.Subject(m.Subject), m.Header[events.HeaderContentType], m.Data)
+			if err != nil {
+				log.Printf("eventlog %s: %v", m.Subject, err)
+				return nil
+			}
+			e, err := Parse(m.Subject, b)
+			if err != nil {
+				log.Printf("eventlog %s: %v", m.Subject, err)
+				return nil
+			}
+			return s.Append(ctx, e)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
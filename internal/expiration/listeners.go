@@ -2,27 +2,27 @@ package expiration
 
 import (
 	"context"
-	"encoding/json"
 	"log"
 
 	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
 	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/pubsub"
 )
 
-// RegisterNATSListeners subscribes to order:created events to schedule expirations.
-func RegisterNATSListeners(ctx context.Context, sub pubsub.Subscriber, queue *ExpirationQueue) error {
-	// Listen for order:created to schedule expiration jobs
-	if err := sub.Subscribe(string(events.SubjectOrderCreated), func(msg []byte) {
-		var d events.OrderCreatedData
-		if err := json.Unmarshal(msg, &d); err != nil {
-			log.Printf("order:created unmarshal: %v", err)
-			return
-		}
+// QueueGroup is the queue group of the expiration replicas, so each order
+// gets one expiration job.
+const QueueGroup = "expiration"
+
+// RegisterNATSListeners subscribes to order:created events to schedule
+// expirations. Jobs are keyed by order ID, so redelivered events don't
+// schedule duplicates; events that keep failing are handed to dl.
+func RegisterNATSListeners(ctx context.Context, sub pubsub.Subscriber, queue *ExpirationQueue, dl events.DeadLetterSink) error {
+	c := &events.Consumer{Sub: sub, Service: QueueGroup, DeadLetters: dl}
 
+	// Listen for order:created to schedule expiration jobs
+	if err := events.Subscribe(ctx, c, events.SubjectOrderCreated, func(ctx context.Context, e events.Envelope[events.OrderCreatedData]) error {
+		d := e.Data
 		log.Printf("Scheduling expiration for order %s at %v", d.ID, d.ExpiresAt)
-		if err := queue.ScheduleOrderExpiration(d.ID, d.ExpiresAt); err != nil {
-			log.Printf("Failed to schedule expiration: %v", err)
-		}
+		return queue.ScheduleOrderExpiration(ctx, d.ID, d.ExpiresAt)
 	}); err != nil {
 		return err
 	}
@@ -3,6 +3,7 @@ package expiration
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"time"
 
@@ -36,9 +37,14 @@ func (q *ExpirationQueue) Close() error {
 	return q.client.Close()
 }
 
-// ScheduleOrderExpiration schedules an expiration job for an order.
-func (q *ExpirationQueue) ScheduleOrderExpiration(orderID string, expiresAt time.Time) error {
-	payload, err := json.Marshal(map[string]string{"orderId": orderID})
+// ScheduleOrderExpiration schedules an expiration job for an order. The job
+// is keyed by order ID, so scheduling the same order again (for example when
+// order:created is redelivered) is a no-op.
+func (q *ExpirationQueue) ScheduleOrderExpiration(ctx context.Context, orderID string, expiresAt time.Time) error {
+	payload, err := json.Marshal(map[string]string{
+		"orderId":       orderID,
+		"correlationId": events.CorrelationID(ctx),
+	})
 	if err != nil {
 		return err
 	}
@@ -49,10 +55,21 @@ func (q *ExpirationQueue) ScheduleOrderExpiration(orderID string, expiresAt time
 		delay = 0 // Process immediately if already expired
 	}
 
-	_, err = q.client.Enqueue(task, asynq.ProcessIn(delay))
+	_, err = q.client.Enqueue(task,
+		asynq.ProcessIn(delay),
+		asynq.TaskID(expirationTaskID(orderID)),
+		// Keep completed jobs around so a late duplicate can't reschedule them.
+		asynq.Retention(24*time.Hour),
+	)
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		return nil
+	}
 	return err
 }
 
+// expirationTaskID is the asynq task ID used to deduplicate expirations.
+func expirationTaskID(orderID string) string { return TypeOrderExpiration + ":" + orderID }
+
 // ExpirationWorker processes expiration jobs.
 type ExpirationWorker struct {
 	server *asynq.Server
@@ -93,11 +110,15 @@ func (w *ExpirationWorker) Stop() {
 // handleOrderExpiration processes an order expiration job.
 func (w *ExpirationWorker) handleOrderExpiration(ctx context.Context, t *asynq.Task) error {
 	var payload struct {
-		OrderID string `json:"orderId"`
+		OrderID       string `json:"orderId"`
+		CorrelationID string `json:"correlationId"`
 	}
 	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
 		return err
 	}
+	if payload.CorrelationID != "" {
+		ctx = events.WithCorrelationID(ctx, payload.CorrelationID)
+	}
 
 	log.Printf("Processing expiration for order: %s", payload.OrderID)
 
@@ -105,8 +126,7 @@ func (w *ExpirationWorker) handleOrderExpiration(ctx context.Context, t *asynq.T
 	evt := events.ExpirationCompleteData{
 		OrderID: payload.OrderID,
 	}
-	b, _ := json.Marshal(evt)
-	if err := w.pub.Publish(ctx, string(events.SubjectExpirationComplete), b); err != nil {
+	if err := events.Publish(ctx, w.pub, events.SubjectExpirationComplete, evt); err != nil {
 		log.Printf("Failed to publish expiration event: %v", err)
 		return err
 	}
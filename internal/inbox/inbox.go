@@ -0,0 +1,89 @@
+// Package inbox makes event consumers idempotent. Each processed event ID is
+// recorded in a per-service table in the same transaction as the handler's
+// own writes, so a redelivered event is skipped exactly when its effects have
+// already been committed.
+package inbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/store"
+)
+
+// Inbox records processed event IDs in a per-service table.
+type Inbox struct {
+	db    *sql.DB
+	table string
+}
+
+// New returns an Inbox backed by the given table, e.g. "orders_inbox".
+func New(db *sql.DB, table string) *Inbox { return &Inbox{db: db, table: table} }
+
+func (i *Inbox) EnsureSchema(ctx context.Context) error {
+	_, err := i.db.ExecContext(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+    event_id TEXT PRIMARY KEY,
+    subject TEXT NOT NULL,
+    processed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+`, i.table))
+	return err
+}
+
+// Process runs fn at most once per eventID. fn receives a context carrying the
+// inbox transaction; repository calls made with it commit or roll back
+// together with the processed marker. It reports whether fn ran.
+//
+// Concurrent deliveries of the same event block on the primary key until the
+// first one finishes, then see the marker and skip.
+func (i *Inbox) Process(ctx context.Context, eventID, subject string, fn func(ctx context.Context) error) (bool, error) {
+	ran := false
+	err := store.WithTx(ctx, i.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, fmt.Sprintf(`
+INSERT INTO %s (event_id, subject) VALUES ($1,$2) ON CONFLICT (event_id) DO NOTHING
+`, i.table), eventID, subject)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil
+		}
+		ran = true
+		return fn(store.ContextWithTx(ctx, tx))
+	})
+	if err != nil {
+		return false, err
+	}
+	return ran, nil
+}
+
+// Processor runs event handlers at most once per event ID. *Inbox is the
+// Postgres implementation; Memory is for tests.
+type Processor interface {
+	Process(ctx context.Context, eventID, subject string, fn func(ctx context.Context) error) (bool, error)
+}
+
+// Memory is an in-process Processor for tests. Without a database there is
+// no transaction, so an event counts as processed once fn succeeds.
+type Memory struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func NewMemory() *Memory { return &Memory{seen: map[string]bool{}} }
+
+func (m *Memory) Process(ctx context.Context, eventID, subject string, fn func(ctx context.Context) error) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.seen[eventID] {
+		return false, nil
+	}
+	if err := fn(ctx); err != nil {
+		return false, err
+	}
+	m.seen[eventID] = true
+	return true, nil
+}
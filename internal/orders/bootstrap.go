@@ -0,0 +1,27 @@
+package orders
+
+import (
+	"context"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/snapshot"
+)
+
+// Bootstrap fills the ticket replica from the tickets service snapshot at
+// endpoint (e.g. http://tickets:3000/internal/tickets/snapshot) and returns
+// how many tickets it read. Tickets the replica already holds at the same or
+// a later version are left alone, so it is safe to run on every start. Run
+// it before subscribing, so orders can be placed for tickets created before
+// this service first consumed ticket events.
+func Bootstrap(ctx context.Context, repo Repository, endpoint, token string) (int, error) {
+	n := 0
+	err := snapshot.Fetch(ctx, endpoint, token, func(page []Ticket) error {
+		for i := range page {
+			if err := repo.SeedTicket(ctx, &page[i]); err != nil {
+				return err
+			}
+		}
+		n += len(page)
+		return nil
+	})
+	return n, err
+}
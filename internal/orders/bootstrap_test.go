@@ -0,0 +1,44 @@
+package orders
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/snapshot"
+)
+
+func TestBootstrapSeedsTicketReplica(t *testing.T) {
+	pages := map[string]snapshot.Page[Ticket]{
+		"":   {Items: []Ticket{{ID: "t1", Title: "Concert", Price: 2000, UserID: "seller", Version: 3}}, Next: "t1"},
+		"t1": {Items: []Ticket{{ID: "t2", Title: "Play (old)", Price: 1500, UserID: "seller", Version: 1}}},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		after, _ := snapshot.Params(r)
+		snapshot.Write(w, pages[after])
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	repo := newFakeRepo()
+	_ = repo.UpsertTicket(ctx, &Ticket{ID: "t2", Title: "Play", Price: 1500, UserID: "seller"})
+	_ = repo.UpsertTicket(ctx, &Ticket{ID: "t2", Title: "Play", Price: 1500, UserID: "seller", Version: 1})
+	_ = repo.UpsertTicket(ctx, &Ticket{ID: "t2", Title: "Play (new)", Price: 1500, UserID: "seller", Version: 2})
+
+	n, err := Bootstrap(ctx, repo, srv.URL, "")
+	if err != nil || n != 2 {
+		t.Fatalf("Bootstrap = %d, %v", n, err)
+	}
+	if got, _ := repo.GetTicket(ctx, "t1"); got == nil || got.Version != 3 || got.Title != "Concert" {
+		t.Errorf("t1 = %+v, want seeded at version 3", got)
+	}
+	if got, _ := repo.GetTicket(ctx, "t2"); got.Title != "Play (new)" || got.Version != 2 {
+		t.Errorf("t2 = %+v, want newer replica row kept", got)
+	}
+
+	// Orders can now be placed for tickets that predate the subscription.
+	if _, err := NewService(repo).CreateOrder(ctx, "buyer", "t1", 1); err != nil {
+		t.Errorf("CreateOrder for seeded ticket: %v", err)
+	}
+}
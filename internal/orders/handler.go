@@ -7,6 +7,7 @@ import (
 	"github.com/go-chi/chi/v5"
 
 	cmw "github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/middleware"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/snapshot"
 )
 
 type HTTPHandler struct {
@@ -17,14 +18,20 @@ func NewHTTPHandler(s *Service) *HTTPHandler {
 	return &HTTPHandler{svc: s}
 }
 
+// createOrderReq names the ticket to order by ticketId, or by eventId and
+// seat for reserved seating. Quantity orders units of a general-admission
+// ticket; it defaults to 1.
 type createOrderReq struct {
 	TicketID string `json:"ticketId"`
+	Quantity int    `json:"quantity"`
+	EventID  string `json:"eventId"`
+	Seat     *Seat  `json:"seat"`
 }
 
 // Create creates a new order for the current user.
 func (h *HTTPHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req createOrderReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.TicketID == "" {
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.TicketID == "") == (req.EventID == "" || req.Seat == nil) {
 		http.Error(w, "invalid payload", http.StatusBadRequest)
 		return
 	}
@@ -35,7 +42,16 @@ func (h *HTTPHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	order, err := h.svc.CreateOrder(r.Context(), cu.ID, req.TicketID)
+	var order *Order
+	var err error
+	if req.TicketID != "" {
+		if req.Quantity == 0 {
+			req.Quantity = 1
+		}
+		order, err = h.svc.CreateOrder(r.Context(), cu.ID, req.TicketID, req.Quantity)
+	} else {
+		order, err = h.svc.CreateSeatOrder(r.Context(), cu.ID, req.EventID, *req.Seat)
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -109,3 +125,15 @@ func (h *HTTPHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// Snapshot serves one page of every order with its version, for replicas in
+// other services (see package snapshot).
+func (h *HTTPHandler) Snapshot(w http.ResponseWriter, r *http.Request) {
+	after, limit := snapshot.Params(r)
+	list, err := h.svc.Snapshot(r.Context(), after, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	snapshot.Write(w, snapshot.NewPage(list, limit, func(o *OrderSummary) string { return o.ID }))
+}
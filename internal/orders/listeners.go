@@ -2,80 +2,144 @@ package orders
 
 import (
 	"context"
-	"encoding/json"
 	"log"
 
 	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/inbox"
 	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/pubsub"
 )
 
-// RegisterNATSListeners subscribes to ticket and payment events.
-func RegisterNATSListeners(ctx context.Context, sub pubsub.Subscriber, repo Repository) error {
+// QueueGroup is the queue group of the orders replicas. Only one of them
+// applies each ticket or event change to the replicas and each expiration
+// or payment to its order.
+const QueueGroup = "orders"
+
+// InboxTable records events already processed by the orders service.
+const InboxTable = "orders_inbox"
+
+// RegisterNATSListeners subscribes to ticket, event and payment events. Each handler
+// runs through in, so redelivered events are applied at most once; events
+// that keep failing are handed to dl.
+func RegisterNATSListeners(ctx context.Context, sub pubsub.Subscriber, repo Repository, in inbox.Processor, dl events.DeadLetterSink) error {
+	c := &events.Consumer{Sub: sub, Service: QueueGroup, DeadLetters: dl}
+
 	// Listen for ticket:created to replicate tickets locally
-	if err := sub.Subscribe(string(events.SubjectTicketCreated), func(msg []byte) {
-		var d events.TicketCreatedData
-		if err := json.Unmarshal(msg, &d); err != nil {
-			log.Printf("ticket:created unmarshal: %v", err)
-			return
-		}
-		if err := repo.UpsertTicket(ctx, d.ID, d.Title, d.Price, d.UserID, d.Version); err != nil {
-			log.Printf("ticket:created upsert: %v", err)
-		}
+	if err := events.Subscribe(ctx, c, events.SubjectTicketCreated, func(ctx context.Context, e events.Envelope[events.TicketCreatedData]) error {
+		d := e.Data
+		_, err := in.Process(ctx, e.ID, string(e.Subject), func(ctx context.Context) error {
+			return repo.UpsertTicket(ctx, replicaTicket(d.ID, d.Title, d.Price, d.UserID, d.EventRef, d.Seat, d.QuantityTotal, d.Version))
+		})
+		return err
 	}); err != nil {
 		return err
 	}
 
 	// Listen for ticket:updated to keep tickets in sync
-	if err := sub.Subscribe(string(events.SubjectTicketUpdated), func(msg []byte) {
-		var d events.TicketUpdatedData
-		if err := json.Unmarshal(msg, &d); err != nil {
-			log.Printf("ticket:updated unmarshal: %v", err)
-			return
-		}
-		if err := repo.UpsertTicket(ctx, d.ID, d.Title, d.Price, d.UserID, d.Version); err != nil {
-			log.Printf("ticket:updated upsert: %v", err)
-		}
+	if err := events.Subscribe(ctx, c, events.SubjectTicketUpdated, func(ctx context.Context, e events.Envelope[events.TicketUpdatedData]) error {
+		d := e.Data
+		_, err := in.Process(ctx, e.ID, string(e.Subject), func(ctx context.Context) error {
+			return repo.UpsertTicket(ctx, replicaTicket(d.ID, d.Title, d.Price, d.UserID, d.EventRef, d.Seat, d.QuantityTotal, d.Version))
+		})
+		return err
+	}); err != nil {
+		return err
+	}
+
+	// Listen for ticket:deleted so withdrawn tickets cannot be ordered
+	if err := events.Subscribe(ctx, c, events.SubjectTicketDeleted, func(ctx context.Context, e events.Envelope[events.TicketDeletedData]) error {
+		d := e.Data
+		_, err := in.Process(ctx, e.ID, string(e.Subject), func(ctx context.Context) error {
+			return repo.DeleteTicket(ctx, d.ID, d.Version)
+		})
+		return err
+	}); err != nil {
+		return err
+	}
+
+	// Listen for ticket:transferred so orders show who holds their units
+	if err := events.Subscribe(ctx, c, events.SubjectTicketTransferred, func(ctx context.Context, e events.Envelope[events.TicketTransferredData]) error {
+		d := e.Data
+		_, err := in.Process(ctx, e.ID, string(e.Subject), func(ctx context.Context) error {
+			return repo.TransferOrder(ctx, d.OrderID, d.UserID, d.Version)
+		})
+		return err
+	}); err != nil {
+		return err
+	}
+
+	// Listen for event:created and event:updated to replicate events locally
+	if err := events.Subscribe(ctx, c, events.SubjectEventCreated, func(ctx context.Context, e events.Envelope[events.EventCreatedData]) error {
+		_, err := in.Process(ctx, e.ID, string(e.Subject), func(ctx context.Context) error {
+			return repo.UpsertEvent(ctx, replicaEvent(events.EventUpdatedData(e.Data)))
+		})
+		return err
+	}); err != nil {
+		return err
+	}
+	if err := events.Subscribe(ctx, c, events.SubjectEventUpdated, func(ctx context.Context, e events.Envelope[events.EventUpdatedData]) error {
+		_, err := in.Process(ctx, e.ID, string(e.Subject), func(ctx context.Context) error {
+			return repo.UpsertEvent(ctx, replicaEvent(e.Data))
+		})
+		return err
 	}); err != nil {
 		return err
 	}
 
 	// Listen for expiration:complete to cancel expired orders
-	if err := sub.Subscribe(string(events.SubjectExpirationComplete), func(msg []byte) {
-		var d events.ExpirationCompleteData
-		if err := json.Unmarshal(msg, &d); err != nil {
-			log.Printf("expiration:complete unmarshal: %v", err)
-			return
-		}
-		order, err := repo.GetOrder(ctx, d.OrderID)
-		if err != nil || order == nil {
-			log.Printf("expiration:complete get order: %v", err)
-			return
-		}
-		// Only cancel if still in created state (not already complete)
-		if order.Status == "created" {
+	if err := events.Subscribe(ctx, c, events.SubjectExpirationComplete, func(ctx context.Context, e events.Envelope[events.ExpirationCompleteData]) error {
+		d := e.Data
+		_, err := in.Process(ctx, e.ID, string(e.Subject), func(ctx context.Context) error {
+			order, err := repo.GetOrder(ctx, d.OrderID)
+			if err != nil {
+				return err
+			}
+			if order == nil {
+				log.Printf("expiration:complete: order %s not found", d.OrderID)
+				return nil
+			}
+			// Only cancel if still in created state (not already complete)
+			if order.Status != "created" {
+				return nil
+			}
 			if err := repo.CancelOrder(ctx, order.ID, order.Version); err != nil {
-				log.Printf("expiration:complete cancel: %v", err)
-			} else {
-				log.Printf("order %s expired and cancelled", order.ID)
+				return err
 			}
-		}
+			log.Printf("order %s expired and cancelled", order.ID)
+			return nil
+		})
+		return err
 	}); err != nil {
 		return err
 	}
 
 	// Listen for payment:created to mark order complete
-	if err := sub.Subscribe(string(events.SubjectPaymentCreated), func(msg []byte) {
-		var d events.PaymentCreatedData
-		if err := json.Unmarshal(msg, &d); err != nil {
-			log.Printf("payment:created unmarshal: %v", err)
-			return
-		}
-		if err := repo.CompleteOrder(ctx, d.OrderID); err != nil {
-			log.Printf("payment:created complete: %v", err)
-		}
+	if err := events.Subscribe(ctx, c, events.SubjectPaymentCreated, func(ctx context.Context, e events.Envelope[events.PaymentCreatedData]) error {
+		d := e.Data
+		_, err := in.Process(ctx, e.ID, string(e.Subject), func(ctx context.Context) error {
+			return repo.CompleteOrder(ctx, d.OrderID)
+		})
+		return err
 	}); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+func replicaEvent(d events.EventUpdatedData) *Event {
+	return &Event{
+		ID:       d.ID,
+		Name:     d.Name,
+		StartsAt: d.StartsAt,
+		Venue:    EventVenue{ID: d.Venue.ID, Name: d.Venue.Name, City: d.Venue.City},
+		Version:  d.Version,
+	}
+}
+
+func replicaTicket(id, title string, price int64, userID, eventID string, seat *events.TicketSeat, quantity, version int) *Ticket {
+	t := &Ticket{ID: id, Title: title, Price: price, UserID: userID, EventID: eventID, QuantityTotal: quantity, Version: version}
+	if seat != nil {
+		t.Seat = &Seat{Section: seat.Section, Row: seat.Row, Number: seat.Number}
+	}
+	return t
+}
@@ -0,0 +1,167 @@
+package orders
+
+import (
+	"context"
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events/eventstest"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/inbox"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/pubsub"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/replica"
+)
+
+func startListeners(t *testing.T) (*pubsub.Memory, *fakeRepo) {
+	t.Helper()
+	bus := pubsub.NewMemory()
+	repo := newFakeRepo()
+	if err := RegisterNATSListeners(context.Background(), bus, repo, inbox.NewMemory(), nil); err != nil {
+		t.Fatalf("RegisterNATSListeners: %v", err)
+	}
+	return bus, repo
+}
+
+func TestTicketEventsMaintainReplica(t *testing.T) {
+	bus, repo := startListeners(t)
+	ctx := context.Background()
+
+	eventstest.Publish(t, bus, events.SubjectTicketCreated, events.TicketCreatedData{ID: "t1", Title: "Concert", Price: 2000, UserID: "seller", Version: 0})
+	got, _ := repo.GetTicket(ctx, "t1")
+	if got == nil || got.Title != "Concert" || got.Price != 2000 {
+		t.Fatalf("replica after ticket:created = %+v", got)
+	}
+
+	updated := events.TicketUpdatedData{ID: "t1", Title: "Concert (late show)", Price: 2500, UserID: "seller", Version: 1}
+	eventstest.Publish(t, bus, events.SubjectTicketUpdated, updated)
+	eventstest.Publish(t, bus, events.SubjectTicketUpdated, updated) // redelivery
+
+	got, _ = repo.GetTicket(ctx, "t1")
+	if got.Title != "Concert (late show)" || got.Price != 2500 || got.Version != 1 {
+		t.Fatalf("replica after ticket:updated = %+v", got)
+	}
+	if repo.upserts != 2 {
+		t.Errorf("upserts = %d, want 2 (duplicate should be skipped)", repo.upserts)
+	}
+}
+
+func TestTicketEventsApplyInVersionOrder(t *testing.T) {
+	bus, repo := startListeners(t)
+	ctx := context.Background()
+	gaps := func() int64 {
+		v, _ := replica.Gaps.Get("orders_tickets").(*expvar.Int)
+		if v == nil {
+			return 0
+		}
+		return v.Value()
+	}
+	before := gaps()
+
+	eventstest.Publish(t, bus, events.SubjectTicketCreated, events.TicketCreatedData{ID: "t1", Title: "v0", UserID: "seller", Version: 0})
+	v1 := events.TicketUpdatedData{ID: "t1", Title: "v1", UserID: "seller", Version: 1}
+	v2 := events.TicketUpdatedData{ID: "t1", Title: "v2", UserID: "seller", Version: 2}
+
+	// v2 overtakes v1: it is refused on every delivery instead of applied.
+	eventstest.Publish(t, bus, events.SubjectTicketUpdated, v2)
+	if got, _ := repo.GetTicket(ctx, "t1"); got.Version != 0 {
+		t.Fatalf("replica applied v2 over v0: %+v", got)
+	}
+	if gaps() == before {
+		t.Error("version gap not counted")
+	}
+
+	eventstest.Publish(t, bus, events.SubjectTicketUpdated, v1)
+	eventstest.Publish(t, bus, events.SubjectTicketUpdated, v2) // redelivered later
+	eventstest.Publish(t, bus, events.SubjectTicketUpdated, v1) // stale
+	if got, _ := repo.GetTicket(ctx, "t1"); got.Title != "v2" || got.Version != 2 {
+		t.Fatalf("replica = %+v, want v2", got)
+	}
+}
+
+func TestDeletedTicketCannotBeOrdered(t *testing.T) {
+	bus, repo := startListeners(t)
+	ctx := context.Background()
+	svc := NewService(repo)
+
+	eventstest.Publish(t, bus, events.SubjectTicketCreated, events.TicketCreatedData{ID: "t1", Title: "Concert", Price: 2000, UserID: "seller", Version: 0})
+	eventstest.Publish(t, bus, events.SubjectTicketDeleted, events.TicketDeletedData{ID: "t1", UserID: "seller", Version: 1})
+	if got, _ := repo.GetTicket(ctx, "t1"); got != nil {
+		t.Errorf("withdrawn ticket still served: %+v", got)
+	}
+	if _, err := svc.CreateOrder(ctx, "buyer", "t1", 1); err == nil {
+		t.Error("order for a withdrawn ticket succeeded")
+	}
+}
+
+func TestTransferredOrderShowsForNewHolder(t *testing.T) {
+	bus, repo := startListeners(t)
+	ctx := context.Background()
+	svc := NewService(repo)
+
+	eventstest.Publish(t, bus, events.SubjectTicketCreated, events.TicketCreatedData{ID: "t1", Title: "Concert", Price: 2000, UserID: "seller", Version: 0})
+	o, err := svc.CreateOrder(ctx, "buyer", "t1", 1)
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if err := repo.CompleteOrder(ctx, o.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	eventstest.Publish(t, bus, events.SubjectTicketTransferred, events.TicketTransferredData{
+		ID: "t1", OrderID: o.ID, TransferID: "tr1", FromUserID: "buyer", UserID: "friend", Quantity: 1, Version: 1})
+	got, err := svc.GetOrder(ctx, o.ID, "friend")
+	if err != nil || got.HolderID != "friend" || got.UserID != "buyer" {
+		t.Fatalf("order for new holder = %+v, %v", got, err)
+	}
+	if _, err := svc.GetOrder(ctx, o.ID, "stranger"); err == nil {
+		t.Error("order served to a user who never held it")
+	}
+}
+
+func TestEventAndSeatShowOnOrders(t *testing.T) {
+	bus, repo := startListeners(t)
+	ctx := context.Background()
+	startsAt := time.Date(2026, 6, 1, 19, 0, 0, 0, time.UTC)
+	venue := events.EventVenue{ID: "v1", Name: "Arena", City: "Helsinki"}
+
+	eventstest.Publish(t, bus, events.SubjectEventCreated, events.EventCreatedData{ID: "e1", Name: "Concert", StartsAt: startsAt, Venue: venue, UserID: "seller", Version: 0})
+	eventstest.Publish(t, bus, events.SubjectTicketCreated, events.TicketCreatedData{ID: "t1", Title: "Seat", Price: 2000, UserID: "seller", EventRef: "e1",
+		Seat: &events.TicketSeat{Section: "Stalls", Row: "A", Number: 7}, Version: 0})
+	o, err := repo.CreateOrder(ctx, "buyer", &Ticket{ID: "t1"}, 1, time.Now())
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	venue.Name = "Arena Hall"
+	eventstest.Publish(t, bus, events.SubjectEventUpdated, events.EventUpdatedData{ID: "e1", Name: "Concert", StartsAt: startsAt, Venue: venue, UserID: "seller", Version: 1})
+	eventstest.Publish(t, bus, events.SubjectEventUpdated, events.EventUpdatedData{ID: "e1", Name: "stale", Venue: venue, UserID: "seller", Version: 0})
+
+	got, _ := repo.GetOrder(ctx, o.ID)
+	if got.Event == nil || got.Event.Name != "Concert" || got.Event.Venue.Name != "Arena Hall" || !got.Event.StartsAt.Equal(startsAt) || got.Event.Version != 1 {
+		t.Fatalf("order event = %+v", got.Event)
+	}
+	if got.Seat == nil || *got.Seat != (Seat{Section: "Stalls", Row: "A", Number: 7}) {
+		t.Errorf("order seat = %+v", got.Seat)
+	}
+}
+
+func TestExpirationCancelsOnlyPendingOrders(t *testing.T) {
+	bus, repo := startListeners(t)
+	ctx := context.Background()
+	_ = repo.UpsertTicket(ctx, &Ticket{ID: "t1", Title: "Concert", Price: 2000, UserID: "seller"})
+	_ = repo.UpsertTicket(ctx, &Ticket{ID: "t2", Title: "Play", Price: 1500, UserID: "seller"})
+	pending, _ := repo.CreateOrder(ctx, "buyer", &Ticket{ID: "t1"}, 1, time.Now())
+	paid, _ := repo.CreateOrder(ctx, "buyer", &Ticket{ID: "t2"}, 1, time.Now())
+
+	eventstest.Publish(t, bus, events.SubjectPaymentCreated, events.PaymentCreatedData{ID: "pay_" + paid.ID, OrderID: paid.ID})
+	eventstest.Publish(t, bus, events.SubjectExpirationComplete, events.ExpirationCompleteData{OrderID: pending.ID})
+	eventstest.Publish(t, bus, events.SubjectExpirationComplete, events.ExpirationCompleteData{OrderID: paid.ID})
+
+	if o, _ := repo.GetOrder(ctx, pending.ID); o.Status != "cancelled" {
+		t.Errorf("pending order status = %s, want cancelled", o.Status)
+	}
+	if o, _ := repo.GetOrder(ctx, paid.ID); o.Status != "complete" {
+		t.Errorf("paid order status = %s, want complete", o.Status)
+	}
+}
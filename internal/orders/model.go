@@ -3,13 +3,26 @@ package orders
 import "time"
 
 type Order struct {
-	ID        string    `json:"id"`
-	UserID    string    `json:"userId"`
+	ID     string `json:"id"`
+	UserID string `json:"userId"`
+	// HolderID is the user holding the units once the order is paid: the
+	// buyer, until they transfer them to someone else.
+	HolderID  string    `json:"holderId"`
 	Status    string    `json:"status"`
 	ExpiresAt time.Time `json:"expiresAt"`
 	TicketID  string    `json:"ticketId"`
+	// Quantity is the number of units of the ticket ordered; 1 unless the
+	// ticket is general admission.
+	Quantity int `json:"quantity"`
+	// Price is the unit price the order was placed at.
+	Price     int64     `json:"price"`
 	Version   int       `json:"version"`
 	CreatedAt time.Time `json:"createdAt"`
+	// Event is the event the ticket is for, from the event replica; nil if
+	// the ticket has none or the event is not replicated yet.
+	Event *Event `json:"event,omitempty"`
+	// Seat is the seat of the ticket, for reserved seating.
+	Seat *Seat `json:"seat,omitempty"`
 }
 
 type Ticket struct {
@@ -18,5 +31,51 @@ type Ticket struct {
 	Price   int64   `json:"price"`
 	UserID  string  `json:"userId"`
 	OrderID *string `json:"orderId,omitempty"`
-	Version int     `json:"version"`
+	EventID string  `json:"eventId,omitempty"`
+	Seat    *Seat   `json:"seat,omitempty"`
+	// QuantityTotal is the number of units for sale: 1 for a single ticket,
+	// more for general admission. QuantityHeld counts the units of orders
+	// that are not cancelled, paid or not; it is owned by this service.
+	QuantityTotal int `json:"quantityTotal"`
+	QuantityHeld  int `json:"quantityHeld"`
+	Version       int `json:"version"`
+	// DeletedAt is set for tickets the seller withdrew. It only comes from
+	// the tickets snapshot; withdrawn tickets are not served by GetTicket.
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+}
+
+// free returns the number of units that can still be ordered.
+func (t *Ticket) free() int { return t.QuantityTotal - t.QuantityHeld }
+
+// Seat is a seat of an event's venue. Each seat has its own ticket.
+type Seat struct {
+	Section string `json:"section"`
+	Row     string `json:"row"`
+	Number  int    `json:"number"`
+}
+
+// Event is the replica of an event from the tickets service, with the venue
+// details its events carry.
+type Event struct {
+	ID       string     `json:"id"`
+	Name     string     `json:"name"`
+	StartsAt time.Time  `json:"startsAt"`
+	Venue    EventVenue `json:"venue"`
+	Version  int        `json:"version"`
+}
+
+type EventVenue struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	City string `json:"city"`
+}
+
+// OrderSummary is an order as served to replicas in other services, with
+// the amount due: the unit price times the quantity.
+type OrderSummary struct {
+	ID      string `json:"id"`
+	UserID  string `json:"userId"`
+	Status  string `json:"status"`
+	Price   int64  `json:"price"`
+	Version int    `json:"version"`
 }
@@ -0,0 +1,127 @@
+package orders_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/catalog"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events/eventstest"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/inbox"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/orders"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/outbox"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/pubsub"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/store"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/tickets"
+)
+
+// TestCreatedTicketIsReplicatedToOrders drives the real tickets and orders
+// repositories through the in-memory bus. It needs a scratch Postgres
+// database in TEST_DATABASE_URL and is skipped otherwise.
+func TestCreatedTicketIsReplicatedToOrders(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := store.NewPostgres(dsn)
+	if err != nil {
+		t.Fatalf("store.NewPostgres: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	bus := pubsub.NewMemory()
+
+	ticketsOutbox := outbox.New(db, tickets.OutboxTable)
+	ticketRepo := tickets.NewRepository(db, ticketsOutbox)
+	orderRepo := orders.NewRepository(db, outbox.New(db, orders.OutboxTable))
+	in := inbox.New(db, orders.InboxTable)
+	for _, ensure := range []func(context.Context) error{catalog.NewRepository(db, ticketsOutbox).EnsureSchema, ticketRepo.EnsureSchema, orderRepo.EnsureSchema, in.EnsureSchema} {
+		if err := ensure(ctx); err != nil {
+			t.Fatalf("EnsureSchema: %v", err)
+		}
+	}
+	if err := orders.RegisterNATSListeners(ctx, bus, orderRepo, in, nil); err != nil {
+		t.Fatalf("RegisterNATSListeners: %v", err)
+	}
+
+	tk, err := tickets.NewService(ticketRepo).Create(ctx, "", "Concert", "", 2000, 1, "seller-1")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	relay := outbox.NewRelay(ticketsOutbox, bus)
+	for {
+		n, err := relay.Flush(ctx)
+		if err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+		if n < relay.Batch {
+			break
+		}
+	}
+
+	var found bool
+	for _, e := range eventstest.Published[events.TicketCreatedData](t, bus, events.SubjectTicketCreated) {
+		found = found || e.Data.ID == tk.ID
+	}
+	if !found {
+		t.Fatalf("ticket:created for %s not published", tk.ID)
+	}
+	replica, err := orderRepo.GetTicket(ctx, tk.ID)
+	if err != nil {
+		t.Fatalf("GetTicket: %v", err)
+	}
+	if replica == nil || replica.Title != "Concert" || replica.Price != 2000 || replica.Version != tk.Version {
+		t.Fatalf("orders_tickets replica = %+v, want copy of %+v", replica, tk)
+	}
+}
+
+// TestGeneralAdmissionIsNotOversold races buyers for the units of a
+// general-admission ticket in the Postgres replica. It needs a scratch
+// Postgres database in TEST_DATABASE_URL and is skipped otherwise.
+func TestGeneralAdmissionIsNotOversold(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := store.NewPostgres(dsn)
+	if err != nil {
+		t.Fatalf("store.NewPostgres: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	repo := orders.NewRepository(db, outbox.New(db, orders.OutboxTable))
+	if err := repo.EnsureSchema(ctx); err != nil {
+		t.Fatalf("EnsureSchema: %v", err)
+	}
+	id := fmt.Sprintf("ga-%d", os.Getpid())
+	if err := repo.UpsertTicket(ctx, &orders.Ticket{ID: id, Title: "Festival", Price: 5000, UserID: "seller", QuantityTotal: 5}); err != nil {
+		t.Fatalf("UpsertTicket: %v", err)
+	}
+	svc := orders.NewService(repo)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sold := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if o, err := svc.CreateOrder(ctx, fmt.Sprintf("buyer-%d", i), id, 1+i%2); err == nil {
+				mu.Lock()
+				sold += o.Quantity
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	got, err := repo.GetTicket(ctx, id)
+	if err != nil {
+		t.Fatalf("GetTicket: %v", err)
+	}
+	if sold > 5 || got.QuantityHeld != sold {
+		t.Fatalf("%d units ordered, replica holds %d of 5", sold, got.QuantityHeld)
+	}
+}
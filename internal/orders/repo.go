@@ -3,31 +3,78 @@ package orders
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/outbox"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/replica"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/store"
 )
 
+// OutboxTable holds order events awaiting publication.
+const OutboxTable = "orders_outbox"
+
+// ErrTicketReserved is returned when a ticket, or the units an order asked
+// for, was reserved concurrently.
+var ErrTicketReserved = errors.New("failed to reserve ticket")
+
 // Repository defines the data layer interface for orders.
+//
+// CreateOrder and CancelOrder update the ticket replica and record
+// order:created / order:cancelled in the outbox within one transaction.
+// All methods join the transaction carried by ctx, if any (see
+// store.ContextWithTx), so listeners can apply them atomically with the inbox.
 type Repository interface {
 	EnsureSchema(ctx context.Context) error
-	CreateOrder(ctx context.Context, userID string, ticketID string, expiresAt time.Time) (*Order, error)
+	// CreateOrder reserves quantity units of ticket for a new order.
+	CreateOrder(ctx context.Context, userID string, ticket *Ticket, quantity int, expiresAt time.Time) (*Order, error)
 	GetOrder(ctx context.Context, id string) (*Order, error)
+	// ListOrdersByUser returns the orders userID placed or holds the units
+	// of, newest first.
 	ListOrdersByUser(ctx context.Context, userID string) ([]*Order, error)
 	CancelOrder(ctx context.Context, id string, version int) error
 	CompleteOrder(ctx context.Context, id string) error
+	// TransferOrder applies ticket:transferred at version: userID now holds
+	// the units of order id. Transfers of an order are applied in version
+	// order, like replica rows.
+	TransferOrder(ctx context.Context, id, userID string, version int) error
+	// Snapshot returns up to limit orders with IDs after the given one, in ID
+	// order; after "" starts from the beginning.
+	Snapshot(ctx context.Context, after string, limit int) ([]*OrderSummary, error)
 
-	// Ticket replica management
-	UpsertTicket(ctx context.Context, id string, title string, price int64, userID string, version int) error
-	UpdateTicketReservation(ctx context.Context, ticketID string, orderID *string, expectedVersion int) error
+	// Ticket replica management. UpsertTicket applies ticket events in
+	// version order (see package replica). Reservations only set order_id and
+	// quantity_held, so the replica's version keeps mirroring the tickets
+	// service.
+	UpsertTicket(ctx context.Context, t *Ticket) error
+	// DeleteTicket applies ticket:deleted at version, in order like
+	// UpsertTicket: the ticket can no longer be ordered, and orders that
+	// reserved it before the withdrawal reached this service are cancelled.
+	DeleteTicket(ctx context.Context, id string, version int) error
+	// SeedTicket stores a ticket from the tickets snapshot unless the
+	// replica already holds that version or a later one.
+	SeedTicket(ctx context.Context, t *Ticket) error
+	// GetTicket and GetSeatTicket return nil for missing and withdrawn
+	// tickets.
 	GetTicket(ctx context.Context, id string) (*Ticket, error)
+	// GetSeatTicket returns the ticket for a seat of an event, or nil.
+	GetSeatTicket(ctx context.Context, eventID string, seat Seat) (*Ticket, error)
 	IsTicketReserved(ctx context.Context, ticketID string) (bool, error)
+
+	// Event replica management. UpsertEvent applies event:created and
+	// event:updated in version order; orders read their ticket's event from
+	// it.
+	UpsertEvent(ctx context.Context, e *Event) error
 }
 
 type repo struct {
-	db *sql.DB
+	db     *sql.DB
+	outbox *outbox.Store
 }
 
-func NewRepository(db *sql.DB) Repository {
-	return &repo{db: db}
+func NewRepository(db *sql.DB, ob *outbox.Store) Repository {
+	return &repo{db: db, outbox: ob}
 }
 
 func (r *repo) EnsureSchema(ctx context.Context) error {
@@ -43,6 +90,11 @@ func (r *repo) EnsureSchema(ctx context.Context) error {
 			version INT NOT NULL DEFAULT 0,
 			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
 		);
+		ALTER TABLE orders ADD COLUMN IF NOT EXISTS quantity INT NOT NULL DEFAULT 1;
+		ALTER TABLE orders ADD COLUMN IF NOT EXISTS price BIGINT NULL;
+		ALTER TABLE orders ADD COLUMN IF NOT EXISTS holder_id TEXT NULL;
+		ALTER TABLE orders ADD COLUMN IF NOT EXISTS holder_version INT NOT NULL DEFAULT 0;
+		CREATE INDEX IF NOT EXISTS idx_orders_holder_id ON orders(holder_id) WHERE holder_id IS NOT NULL;
 		
 		CREATE TABLE IF NOT EXISTS orders_tickets (
 			id TEXT PRIMARY KEY,
@@ -53,43 +105,126 @@ func (r *repo) EnsureSchema(ctx context.Context) error {
 			version INT NOT NULL DEFAULT 0,
 			updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
 		);
+		ALTER TABLE orders_tickets ADD COLUMN IF NOT EXISTS event_id TEXT NULL;
+		ALTER TABLE orders_tickets ADD COLUMN IF NOT EXISTS seat_section TEXT NULL;
+		ALTER TABLE orders_tickets ADD COLUMN IF NOT EXISTS seat_row TEXT NULL;
+		ALTER TABLE orders_tickets ADD COLUMN IF NOT EXISTS seat_number INT NULL;
+		ALTER TABLE orders_tickets ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL;
+		ALTER TABLE orders_tickets ADD COLUMN IF NOT EXISTS quantity_total INT NOT NULL DEFAULT 1;
+		ALTER TABLE orders_tickets ADD COLUMN IF NOT EXISTS quantity_held INT NOT NULL DEFAULT 0;
+		-- Single tickets reserved before quantities hold their one unit.
+		UPDATE orders_tickets SET quantity_held = 1 WHERE order_id IS NOT NULL AND quantity_held = 0;
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_tickets_seat ON orders_tickets(event_id, seat_section, seat_row, seat_number)
+			WHERE seat_number IS NOT NULL;
+
+		CREATE TABLE IF NOT EXISTS orders_events (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			starts_at TIMESTAMPTZ NOT NULL,
+			venue_id TEXT NOT NULL,
+			venue_name TEXT NOT NULL,
+			venue_city TEXT NOT NULL,
+			version INT NOT NULL DEFAULT 0,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
 	`)
-	return err
+	if err != nil {
+		return err
+	}
+	return r.outbox.EnsureSchema(ctx)
 }
 
-func (r *repo) CreateOrder(ctx context.Context, userID string, ticketID string, expiresAt time.Time) (*Order, error) {
-	row := r.db.QueryRowContext(ctx, `
-		INSERT INTO orders (user_id, ticket_id, expires_at, status)
-		VALUES ($1,$2,$3,'created')
-		RETURNING id, user_id, status, expires_at, ticket_id, version, created_at
-	`, userID, ticketID, expiresAt)
-
+func (r *repo) CreateOrder(ctx context.Context, userID string, ticket *Ticket, quantity int, expiresAt time.Time) (*Order, error) {
 	var o Order
-	if err := row.Scan(&o.ID, &o.UserID, &o.Status, &o.ExpiresAt, &o.TicketID, &o.Version, &o.CreatedAt); err != nil {
+	err := store.WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, `
+			INSERT INTO orders (user_id, ticket_id, expires_at, status, quantity, price)
+			VALUES ($1,$2,$3,'created',$4,$5)
+			RETURNING id, user_id, status, expires_at, ticket_id, quantity, price, version, created_at
+		`, userID, ticket.ID, expiresAt, quantity, ticket.Price)
+		if err := row.Scan(&o.ID, &o.UserID, &o.Status, &o.ExpiresAt, &o.TicketID, &o.Quantity, &o.Price, &o.Version, &o.CreatedAt); err != nil {
+			return err
+		}
+		o.HolderID = o.UserID
+
+		// Reserve the units by adding them to the ticket's held quantity
+		if err := reserveTicket(ctx, tx, ticket, o.ID, quantity); err != nil {
+			if err == sql.ErrNoRows {
+				return ErrTicketReserved
+			}
+			return err
+		}
+
+		evt := events.OrderCreatedData{
+			ID:        o.ID,
+			Version:   o.Version,
+			Status:    o.Status,
+			UserID:    o.UserID,
+			ExpiresAt: o.ExpiresAt,
+			Quantity:  o.Quantity,
+			Ticket: events.OrderTicketDetail{
+				ID:    ticket.ID,
+				Price: ticket.Price,
+			},
+		}
+		b, err := events.Marshal(ctx, events.SubjectOrderCreated, evt)
+		if err != nil {
+			return err
+		}
+		return r.outbox.Add(ctx, tx, o.ID, string(events.SubjectOrderCreated), b)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return &o, nil
 }
 
-func (r *repo) GetOrder(ctx context.Context, id string) (*Order, error) {
-	row := r.db.QueryRowContext(ctx, `
-		SELECT id, user_id, status, expires_at, ticket_id, version, created_at FROM orders WHERE id=$1
-	`, id)
+// selectOrders reads orders with the seat and event of their ticket, if
+// replicated.
+const selectOrders = `
+		SELECT o.id, o.user_id, COALESCE(o.holder_id, o.user_id), o.status, o.expires_at, o.ticket_id, o.quantity, COALESCE(o.price, t.price, 0), o.version, o.created_at,
+		       t.seat_section, t.seat_row, t.seat_number,
+		       e.id, e.name, e.starts_at, e.venue_id, e.venue_name, e.venue_city, e.version
+		FROM orders o
+		LEFT JOIN orders_tickets t ON t.id = o.ticket_id
+		LEFT JOIN orders_events e ON e.id = t.event_id
+`
 
+func scanOrder(row interface{ Scan(...any) error }) (*Order, error) {
 	var o Order
-	if err := row.Scan(&o.ID, &o.UserID, &o.Status, &o.ExpiresAt, &o.TicketID, &o.Version, &o.CreatedAt); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
+	var section, seatRow, eventID, name, venueID, venueName, venueCity sql.NullString
+	var startsAt sql.NullTime
+	var number, version sql.NullInt64
+	if err := row.Scan(&o.ID, &o.UserID, &o.HolderID, &o.Status, &o.ExpiresAt, &o.TicketID, &o.Quantity, &o.Price, &o.Version, &o.CreatedAt,
+		&section, &seatRow, &number,
+		&eventID, &name, &startsAt, &venueID, &venueName, &venueCity, &version); err != nil {
 		return nil, err
 	}
+	if number.Valid {
+		o.Seat = &Seat{Section: section.String, Row: seatRow.String, Number: int(number.Int64)}
+	}
+	if eventID.Valid {
+		o.Event = &Event{
+			ID:       eventID.String,
+			Name:     name.String,
+			StartsAt: startsAt.Time,
+			Venue:    EventVenue{ID: venueID.String, Name: venueName.String, City: venueCity.String},
+			Version:  int(version.Int64),
+		}
+	}
 	return &o, nil
 }
 
+func (r *repo) GetOrder(ctx context.Context, id string) (*Order, error) {
+	o, err := scanOrder(store.Conn(ctx, r.db).QueryRowContext(ctx, selectOrders+`WHERE o.id=$1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return o, err
+}
+
 func (r *repo) ListOrdersByUser(ctx context.Context, userID string) ([]*Order, error) {
-	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, user_id, status, expires_at, ticket_id, version, created_at FROM orders WHERE user_id=$1 ORDER BY created_at DESC
-	`, userID)
+	rows, err := store.Conn(ctx, r.db).QueryContext(ctx, selectOrders+`WHERE o.user_id=$1 OR o.holder_id=$1 ORDER BY o.created_at DESC`, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -97,31 +232,55 @@ func (r *repo) ListOrdersByUser(ctx context.Context, userID string) ([]*Order, e
 
 	var out []*Order
 	for rows.Next() {
-		var o Order
-		if err := rows.Scan(&o.ID, &o.UserID, &o.Status, &o.ExpiresAt, &o.TicketID, &o.Version, &o.CreatedAt); err != nil {
+		o, err := scanOrder(rows)
+		if err != nil {
 			return nil, err
 		}
-		out = append(out, &o)
+		out = append(out, o)
 	}
 	return out, nil
 }
 
 func (r *repo) CancelOrder(ctx context.Context, id string, version int) error {
-	res, err := r.db.ExecContext(ctx, `
-		UPDATE orders SET status='cancelled', version=version+1 WHERE id=$1 AND version=$2
-	`, id, version)
-	if err != nil {
-		return err
-	}
-	n, _ := res.RowsAffected()
-	if n == 0 {
-		return sql.ErrNoRows
-	}
-	return nil
+	return store.WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		var ticketID string
+		var quantity, newVersion int
+		err := tx.QueryRowContext(ctx, `
+			UPDATE orders SET status='cancelled', version=version+1 WHERE id=$1 AND version=$2
+			RETURNING ticket_id, quantity, version
+		`, id, version).Scan(&ticketID, &quantity, &newVersion)
+		if err != nil {
+			return err
+		}
+
+		// Release the units. A single ticket is only released if this order
+		// still holds it.
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE orders_tickets SET quantity_held=GREATEST(quantity_held-$3, 0), order_id=NULLIF(order_id, $2), updated_at=$4
+			WHERE id=$1 AND (quantity_total > 1 OR order_id=$2)
+		`, ticketID, id, quantity, time.Now().UTC()); err != nil {
+			return err
+		}
+
+		evt := events.OrderCancelledData{
+			ID:       id,
+			Version:  newVersion,
+			Quantity: quantity,
+			Ticket: events.OrderTicketDetail{
+				ID:    ticketID,
+				Price: 0, // Price not needed for cancellation
+			},
+		}
+		b, err := events.Marshal(ctx, events.SubjectOrderCancelled, evt)
+		if err != nil {
+			return err
+		}
+		return r.outbox.Add(ctx, tx, id, string(events.SubjectOrderCancelled), b)
+	})
 }
 
 func (r *repo) CompleteOrder(ctx context.Context, id string) error {
-	res, err := r.db.ExecContext(ctx, `
+	res, err := store.Conn(ctx, r.db).ExecContext(ctx, `
 		UPDATE orders SET status='complete' WHERE id=$1
 	`, id)
 	if err != nil {
@@ -134,23 +293,183 @@ func (r *repo) CompleteOrder(ctx context.Context, id string) error {
 	return nil
 }
 
-func (r *repo) UpsertTicket(ctx context.Context, id string, title string, price int64, userID string, version int) error {
-	_, err := r.db.ExecContext(ctx, `
-		INSERT INTO orders_tickets (id, title, price, user_id, version, updated_at)
-		VALUES ($1,$2,$3,$4,$5,$6)
-		ON CONFLICT (id) DO UPDATE SET title=EXCLUDED.title, price=EXCLUDED.price, user_id=EXCLUDED.user_id, version=EXCLUDED.version, updated_at=EXCLUDED.updated_at
-	`, id, title, price, userID, version, time.Now().UTC())
-	return err
+func (r *repo) TransferOrder(ctx context.Context, id, userID string, version int) error {
+	return store.WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		var stored int
+		err := tx.QueryRowContext(ctx, `SELECT holder_version FROM orders WHERE id=$1 FOR UPDATE`, id).Scan(&stored)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		// Transfers are numbered from 1; an order's version 0 is its
+		// purchase.
+		apply, err := replica.Check("orders_holders", id, stored, err == nil, version)
+		if !apply {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE orders SET holder_id=$2, holder_version=$3 WHERE id=$1`, id, userID, version)
+		return err
+	})
+}
+
+func (r *repo) Snapshot(ctx context.Context, after string, limit int) ([]*OrderSummary, error) {
+	rows, err := store.Conn(ctx, r.db).QueryContext(ctx, `
+		SELECT o.id, o.user_id, o.status, COALESCE(o.price, t.price, 0) * o.quantity, o.version
+		FROM orders o LEFT JOIN orders_tickets t ON t.id = o.ticket_id
+		WHERE $1::uuid IS NULL OR o.id > $1::uuid
+		ORDER BY o.id
+		LIMIT $2
+	`, sql.NullString{String: after, Valid: after != ""}, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*OrderSummary
+	for rows.Next() {
+		var o OrderSummary
+		if err := rows.Scan(&o.ID, &o.UserID, &o.Status, &o.Price, &o.Version); err != nil {
+			return nil, err
+		}
+		out = append(out, &o)
+	}
+	return out, rows.Err()
 }
 
-func (r *repo) UpdateTicketReservation(ctx context.Context, ticketID string, orderID *string, expectedVersion int) error {
-	var oid interface{}
-	if orderID != nil {
-		oid = *orderID
+// upsertTicket writes every replicated column of a ticket; order_id and
+// quantity_held are owned by this service and kept.
+const upsertTicket = `
+		INSERT INTO orders_tickets (id, title, price, user_id, event_id, seat_section, seat_row, seat_number, quantity_total, deleted_at, version, updated_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
+		ON CONFLICT (id) DO UPDATE SET title=EXCLUDED.title, price=EXCLUDED.price, user_id=EXCLUDED.user_id, event_id=EXCLUDED.event_id,
+			seat_section=EXCLUDED.seat_section, seat_row=EXCLUDED.seat_row, seat_number=EXCLUDED.seat_number,
+			quantity_total=EXCLUDED.quantity_total, deleted_at=EXCLUDED.deleted_at, version=EXCLUDED.version, updated_at=EXCLUDED.updated_at
+`
+
+func upsertTicketArgs(t *Ticket) []any {
+	var section, row, number any
+	if t.Seat != nil {
+		section, row, number = t.Seat.Section, t.Seat.Row, t.Seat.Number
 	}
-	res, err := r.db.ExecContext(ctx, `
-		UPDATE orders_tickets SET order_id=$2, version=version+1, updated_at=$3 WHERE id=$1 AND version=$4
-	`, ticketID, oid, time.Now().UTC(), expectedVersion)
+	return []any{t.ID, t.Title, t.Price, t.UserID, sql.NullString{String: t.EventID, Valid: t.EventID != ""},
+		section, row, number, units(t.QuantityTotal), t.DeletedAt, t.Version, time.Now().UTC()}
+}
+
+// units returns q, or 1 for tickets replicated before they had quantities.
+func units(q int) int {
+	if q < 1 {
+		return 1
+	}
+	return q
+}
+
+// UpsertTicket applies a ticket event at version to the replica. Only the
+// next version is applied: older ones are ignored and newer ones fail with
+// replica.ErrVersionGap so the event is redelivered once the gap fills.
+func (r *repo) UpsertTicket(ctx context.Context, t *Ticket) error {
+	return store.WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		var stored int
+		err := tx.QueryRowContext(ctx, `SELECT version FROM orders_tickets WHERE id=$1 FOR UPDATE`, t.ID).Scan(&stored)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		apply, err := replica.Check("orders_tickets", t.ID, stored, err == nil, t.Version)
+		if !apply {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, upsertTicket, upsertTicketArgs(t)...)
+		return err
+	})
+}
+
+func (r *repo) DeleteTicket(ctx context.Context, id string, version int) error {
+	return store.WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		var stored int
+		err := tx.QueryRowContext(ctx, `SELECT version FROM orders_tickets WHERE id=$1 FOR UPDATE`, id).Scan(&stored)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		apply, err := replica.Check("orders_tickets", id, stored, err == nil, version)
+		if !apply {
+			return err
+		}
+		now := time.Now().UTC()
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE orders_tickets SET deleted_at=$2, version=$3, updated_at=$2 WHERE id=$1
+		`, id, now, version); err != nil {
+			return err
+		}
+
+		// Cancel the orders still waiting for payment on the ticket
+		rows, err := tx.QueryContext(ctx, `SELECT id, version FROM orders WHERE ticket_id=$1 AND status='created'`, id)
+		if err != nil {
+			return err
+		}
+		var open []*Order
+		for rows.Next() {
+			var o Order
+			if err := rows.Scan(&o.ID, &o.Version); err != nil {
+				rows.Close()
+				return err
+			}
+			open = append(open, &o)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		ctx := store.ContextWithTx(ctx, tx)
+		for _, o := range open {
+			if err := r.CancelOrder(ctx, o.ID, o.Version); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UpsertEvent applies an event:created or event:updated at e.Version to the
+// event replica, in version order like UpsertTicket.
+func (r *repo) UpsertEvent(ctx context.Context, e *Event) error {
+	return store.WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		var stored int
+		err := tx.QueryRowContext(ctx, `SELECT version FROM orders_events WHERE id=$1 FOR UPDATE`, e.ID).Scan(&stored)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		apply, err := replica.Check("orders_events", e.ID, stored, err == nil, e.Version)
+		if !apply {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO orders_events (id, name, starts_at, venue_id, venue_name, venue_city, version, updated_at)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+			ON CONFLICT (id) DO UPDATE SET name=EXCLUDED.name, starts_at=EXCLUDED.starts_at, venue_id=EXCLUDED.venue_id,
+				venue_name=EXCLUDED.venue_name, venue_city=EXCLUDED.venue_city, version=EXCLUDED.version, updated_at=EXCLUDED.updated_at
+		`, e.ID, e.Name, e.StartsAt, e.Venue.ID, e.Venue.Name, e.Venue.City, e.Version, time.Now().UTC())
+		return err
+	})
+}
+
+func (r *repo) SeedTicket(ctx context.Context, t *Ticket) error {
+	// Snapshots may skip versions, so unlike UpsertTicket this only requires
+	// the snapshot to be newer. order_id is owned by this service and kept.
+	_, err := store.Conn(ctx, r.db).ExecContext(ctx, upsertTicket+`WHERE orders_tickets.version < EXCLUDED.version`, upsertTicketArgs(t)...)
+	return err
+}
+
+// reserveTicket adds quantity units of ticket to its held quantity unless
+// fewer are free, in one statement so concurrent orders cannot oversell it.
+// A single ticket is also marked with the order holding it. The ticket must
+// not have changed since it was read, except that general-admission tickets
+// only need the same price: each reservation of their units bumps their
+// version in the tickets service.
+func reserveTicket(ctx context.Context, db store.DBTX, ticket *Ticket, orderID string, quantity int) error {
+	res, err := db.ExecContext(ctx, `
+		UPDATE orders_tickets
+		SET quantity_held=quantity_held+$3, order_id=CASE WHEN quantity_total = 1 THEN $2 ELSE order_id END, updated_at=$4
+		WHERE id=$1 AND deleted_at IS NULL AND quantity_held+$3 <= quantity_total
+		  AND (version=$5 OR (quantity_total > 1 AND price=$6))
+	`, ticket.ID, orderID, quantity, time.Now().UTC(), ticket.Version, ticket.Price)
 	if err != nil {
 		return err
 	}
@@ -161,24 +480,39 @@ func (r *repo) UpdateTicketReservation(ctx context.Context, ticketID string, ord
 	return nil
 }
 
-func (r *repo) GetTicket(ctx context.Context, id string) (*Ticket, error) {
-	row := r.db.QueryRowContext(ctx, `
-		SELECT id, title, price, user_id, order_id, version FROM orders_tickets WHERE id=$1
-	`, id)
+// ticketColumns are the orders_tickets columns scanTicket reads, in order.
+const ticketColumns = `id, title, price, user_id, order_id, COALESCE(event_id, ''), seat_section, seat_row, seat_number, quantity_total, quantity_held, version`
 
+func scanTicket(row interface{ Scan(...any) error }) (*Ticket, error) {
 	var t Ticket
-	if err := row.Scan(&t.ID, &t.Title, &t.Price, &t.UserID, &t.OrderID, &t.Version); err != nil {
+	var section, seatRow sql.NullString
+	var number sql.NullInt64
+	if err := row.Scan(&t.ID, &t.Title, &t.Price, &t.UserID, &t.OrderID, &t.EventID, &section, &seatRow, &number, &t.QuantityTotal, &t.QuantityHeld, &t.Version); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
+	if number.Valid {
+		t.Seat = &Seat{Section: section.String, Row: seatRow.String, Number: int(number.Int64)}
+	}
 	return &t, nil
 }
 
+func (r *repo) GetTicket(ctx context.Context, id string) (*Ticket, error) {
+	return scanTicket(store.Conn(ctx, r.db).QueryRowContext(ctx, `SELECT `+ticketColumns+` FROM orders_tickets WHERE id=$1 AND deleted_at IS NULL`, id))
+}
+
+func (r *repo) GetSeatTicket(ctx context.Context, eventID string, seat Seat) (*Ticket, error) {
+	return scanTicket(store.Conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT `+ticketColumns+` FROM orders_tickets
+		WHERE event_id=$1 AND seat_section=$2 AND seat_row=$3 AND seat_number=$4 AND deleted_at IS NULL
+	`, eventID, seat.Section, seat.Row, seat.Number))
+}
+
 func (r *repo) IsTicketReserved(ctx context.Context, ticketID string) (bool, error) {
 	var orderID *string
-	err := r.db.QueryRowContext(ctx, `SELECT order_id FROM orders_tickets WHERE id=$1`, ticketID).Scan(&orderID)
+	err := store.Conn(ctx, r.db).QueryRowContext(ctx, `SELECT order_id FROM orders_tickets WHERE id=$1`, ticketID).Scan(&orderID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return false, nil
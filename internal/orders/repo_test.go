@@ -0,0 +1,242 @@
+package orders_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/orders"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/outbox"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/replica"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/store"
+)
+
+// testRepo returns an orders repository on the scratch Postgres database in
+// TEST_DATABASE_URL, and skips the test when it is not set.
+func testRepo(t *testing.T) orders.Repository {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := store.NewPostgres(dsn)
+	if err != nil {
+		t.Fatalf("store.NewPostgres: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	repo := orders.NewRepository(db, outbox.New(db, orders.OutboxTable))
+	if err := repo.EnsureSchema(context.Background()); err != nil {
+		t.Fatalf("EnsureSchema: %v", err)
+	}
+	return repo
+}
+
+// testTicket stores a ticket with quantity units in the replica of repo,
+// under an ID no other test run uses.
+func testTicket(t *testing.T, repo orders.Repository, quantity int) *orders.Ticket {
+	t.Helper()
+	tk := &orders.Ticket{ID: fmt.Sprintf("%s-%d-%d", t.Name(), os.Getpid(), time.Now().UnixNano()), Title: "Concert", Price: 2000, UserID: "seller", QuantityTotal: quantity}
+	if err := repo.UpsertTicket(context.Background(), tk); err != nil {
+		t.Fatalf("UpsertTicket: %v", err)
+	}
+	return tk
+}
+
+func TestCreatedOrderReadsBack(t *testing.T) {
+	repo := testRepo(t)
+	ctx := context.Background()
+	tk := testTicket(t, repo, 3)
+
+	created, err := repo.CreateOrder(ctx, "buyer", tk, 2, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if created.UserID != "buyer" || created.HolderID != "buyer" || created.Quantity != 2 || created.Price != 2000 {
+		t.Errorf("created order = %+v", created)
+	}
+	got, err := repo.GetOrder(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if got == nil || got.ID != created.ID || got.HolderID != "buyer" || got.Status != "created" || got.TicketID != tk.ID || got.Quantity != 2 {
+		t.Fatalf("GetOrder = %+v, want %+v", got, created)
+	}
+	list, err := repo.ListOrdersByUser(ctx, "buyer")
+	if err != nil {
+		t.Fatalf("ListOrdersByUser: %v", err)
+	}
+	found := false
+	for _, o := range list {
+		found = found || o.ID == created.ID
+	}
+	if !found {
+		t.Errorf("ListOrdersByUser misses order %s", created.ID)
+	}
+	if held, _ := repo.GetTicket(ctx, tk.ID); held == nil || held.QuantityHeld != 2 {
+		t.Errorf("replica after order = %+v, want 2 units held", held)
+	}
+
+	if err := orders.NewService(repo).CancelOrder(ctx, created.ID, "buyer"); err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	if got, _ := repo.GetOrder(ctx, created.ID); got == nil || got.Status != "cancelled" {
+		t.Errorf("order after cancel = %+v", got)
+	}
+	if held, _ := repo.GetTicket(ctx, tk.ID); held == nil || held.QuantityHeld != 0 {
+		t.Errorf("replica after cancel = %+v, want its units back", held)
+	}
+}
+
+func TestTransferredOrderMovesToHolder(t *testing.T) {
+	repo := testRepo(t)
+	ctx := context.Background()
+	tk := testTicket(t, repo, 1)
+	o, err := repo.CreateOrder(ctx, "buyer", tk, 1, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if err := repo.CompleteOrder(ctx, o.ID); err != nil {
+		t.Fatalf("CompleteOrder: %v", err)
+	}
+	friend := fmt.Sprintf("friend-%s", o.ID)
+
+	// Transfer 2 arriving before 1 waits for it; 1 redelivered after 2 is
+	// skipped.
+	if err := repo.TransferOrder(ctx, o.ID, "someone", 2); !errors.Is(err, replica.ErrVersionGap) {
+		t.Errorf("transfer 2 before transfer 1: got %v, want a version gap", err)
+	}
+	for _, step := range []struct {
+		userID  string
+		version int
+	}{{friend, 1}, {"someone", 2}, {friend, 1}} {
+		if err := repo.TransferOrder(ctx, o.ID, step.userID, step.version); err != nil {
+			t.Fatalf("TransferOrder(%s, %d): %v", step.userID, step.version, err)
+		}
+	}
+	got, err := repo.GetOrder(ctx, o.ID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if got.UserID != "buyer" || got.HolderID != "someone" {
+		t.Errorf("order after transfers = %+v, want buyer's order held by someone", got)
+	}
+	list, err := repo.ListOrdersByUser(ctx, friend)
+	if err != nil {
+		t.Fatalf("ListOrdersByUser: %v", err)
+	}
+	if len(list) != 0 {
+		t.Errorf("former holder still lists %+v", list)
+	}
+}
+
+func TestDeletedTicketCancelsOpenOrders(t *testing.T) {
+	repo := testRepo(t)
+	ctx := context.Background()
+	tk := testTicket(t, repo, 3)
+	open, err := repo.CreateOrder(ctx, "buyer", tk, 1, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	paid, err := repo.CreateOrder(ctx, "buyer", tk, 1, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if err := repo.CompleteOrder(ctx, paid.ID); err != nil {
+		t.Fatalf("CompleteOrder: %v", err)
+	}
+
+	// The withdrawal and its redelivery.
+	for i := 0; i < 2; i++ {
+		if err := repo.DeleteTicket(ctx, tk.ID, tk.Version+1); err != nil {
+			t.Fatalf("DeleteTicket: %v", err)
+		}
+	}
+	if got, _ := repo.GetOrder(ctx, open.ID); got == nil || got.Status != "cancelled" {
+		t.Errorf("open order after withdrawal = %+v, want cancelled", got)
+	}
+	if got, _ := repo.GetOrder(ctx, paid.ID); got == nil || got.Status != "complete" {
+		t.Errorf("paid order after withdrawal = %+v, want complete", got)
+	}
+	if got, err := repo.GetTicket(ctx, tk.ID); err != nil || got != nil {
+		t.Errorf("withdrawn ticket still served: %+v, %v", got, err)
+	}
+	if _, err := repo.CreateOrder(ctx, "buyer", tk, 1, time.Now().Add(time.Minute)); err != orders.ErrTicketReserved {
+		t.Errorf("order for a withdrawn ticket: got %v, want %v", err, orders.ErrTicketReserved)
+	}
+}
+
+func TestGeneralAdmissionUnits(t *testing.T) {
+	repo := testRepo(t)
+	ctx := context.Background()
+	tk := testTicket(t, repo, 5)
+	svc := orders.NewService(repo)
+
+	// Buyers racing for 2 units each: only two of them fit.
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var won []*orders.Order
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if o, err := repo.CreateOrder(ctx, fmt.Sprintf("buyer-%d", i), tk, 2, time.Now().Add(time.Minute)); err == nil {
+				mu.Lock()
+				won = append(won, o)
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	if len(won) != 2 {
+		t.Fatalf("%d orders of 2 units out of 5, want 2", len(won))
+	}
+
+	// A ticket update, such as another service's reservation bumping the
+	// version, does not fail orders at the same price; a new price does.
+	bumped := *tk
+	bumped.Version++
+	if err := repo.UpsertTicket(ctx, &bumped); err != nil {
+		t.Fatalf("UpsertTicket: %v", err)
+	}
+	repriced := *tk
+	repriced.Price++
+	if _, err := repo.CreateOrder(ctx, "buyer", &repriced, 1, time.Now().Add(time.Minute)); err != orders.ErrTicketReserved {
+		t.Errorf("order at an outdated price: got %v, want %v", err, orders.ErrTicketReserved)
+	}
+	if _, err := repo.CreateOrder(ctx, "buyer", tk, 2, time.Now().Add(time.Minute)); err != orders.ErrTicketReserved {
+		t.Errorf("order for 2 of 1 left: got %v, want %v", err, orders.ErrTicketReserved)
+	}
+	if _, err := repo.CreateOrder(ctx, "buyer", tk, 1, time.Now().Add(time.Minute)); err != nil {
+		t.Errorf("order for the last unit: %v", err)
+	}
+
+	if err := svc.CancelOrder(ctx, won[0].ID, won[0].UserID); err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	if got, _ := repo.GetTicket(ctx, tk.ID); got == nil || got.QuantityHeld != 3 || got.OrderID != nil {
+		t.Errorf("after cancel ticket = %+v, want 3 units held", got)
+	}
+	var amount int64
+	for after := ""; ; {
+		page, err := repo.Snapshot(ctx, after, 500)
+		if err != nil {
+			t.Fatalf("Snapshot: %v", err)
+		}
+		for _, o := range page {
+			if o.ID == won[0].ID {
+				amount = o.Price
+			}
+		}
+		if len(page) < 500 {
+			break
+		}
+		after = page[len(page)-1].ID
+	}
+	if amount != 2*tk.Price {
+		t.Errorf("snapshot amount = %d, want 2 units at %d", amount, tk.Price)
+	}
+}
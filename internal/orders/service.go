@@ -2,74 +2,66 @@ package orders
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
-
-	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
-	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/pubsub"
 )
 
 const (
 	EXPIRATION_WINDOW_SECONDS = 900 // 15 minutes for orders to expire
+	// MaxOrderQuantity is the most units of a general-admission ticket one
+	// order can hold.
+	MaxOrderQuantity = 10
 )
 
-// Service handles order business logic.
+// Service handles order business logic. Domain events are written to the
+// outbox by the repository and published by an outbox.Relay.
 type Service struct {
 	repo Repository
-	pub  pubsub.Publisher
 }
 
-func NewService(repo Repository, pub pubsub.Publisher) *Service {
-	return &Service{repo: repo, pub: pub}
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
 }
 
-// CreateOrder reserves a ticket and creates an order with expiration.
-func (s *Service) CreateOrder(ctx context.Context, userID string, ticketID string) (*Order, error) {
-	// Check if ticket exists and is not reserved
+// CreateOrder reserves quantity units of a ticket and creates an order with
+// expiration. Only general-admission tickets have more than one unit.
+func (s *Service) CreateOrder(ctx context.Context, userID string, ticketID string, quantity int) (*Order, error) {
+	if quantity < 1 || quantity > MaxOrderQuantity {
+		return nil, fmt.Errorf("quantity must be between 1 and %d", MaxOrderQuantity)
+	}
 	ticket, err := s.repo.GetTicket(ctx, ticketID)
 	if err != nil {
 		return nil, err
 	}
-	if ticket == nil {
-		return nil, errors.New("ticket not found")
-	}
-	if ticket.OrderID != nil {
-		return nil, errors.New("ticket already reserved")
-	}
+	return s.reserve(ctx, userID, ticket, quantity)
+}
 
-	// Create order with expiration
-	expiresAt := time.Now().UTC().Add(time.Duration(EXPIRATION_WINDOW_SECONDS) * time.Second)
-	order, err := s.repo.CreateOrder(ctx, userID, ticketID, expiresAt)
+// CreateSeatOrder reserves the ticket for a seat of an event and creates an
+// order for it. A seat can only be held by one order at a time.
+func (s *Service) CreateSeatOrder(ctx context.Context, userID string, eventID string, seat Seat) (*Order, error) {
+	ticket, err := s.repo.GetSeatTicket(ctx, eventID, seat)
 	if err != nil {
 		return nil, err
 	}
+	return s.reserve(ctx, userID, ticket, 1)
+}
 
-	// Reserve the ticket by setting its orderID
-	if err := s.repo.UpdateTicketReservation(ctx, ticketID, &order.ID, ticket.Version); err != nil {
-		// Rollback: cancel the order if reservation fails
-		_ = s.repo.CancelOrder(ctx, order.ID, order.Version)
-		return nil, errors.New("failed to reserve ticket")
+func (s *Service) reserve(ctx context.Context, userID string, ticket *Ticket, quantity int) (*Order, error) {
+	// Check if ticket exists and enough of it is not reserved
+	if ticket == nil {
+		return nil, errors.New("ticket not found")
 	}
-
-	// Publish order:created event
-	if s.pub != nil {
-		evt := events.OrderCreatedData{
-			ID:        order.ID,
-			Version:   order.Version,
-			Status:    order.Status,
-			UserID:    order.UserID,
-			ExpiresAt: order.ExpiresAt,
-			Ticket: events.OrderTicketDetail{
-				ID:    ticket.ID,
-				Price: ticket.Price,
-			},
+	if free := ticket.free(); free < quantity {
+		if ticket.QuantityTotal <= 1 {
+			return nil, errors.New("ticket already reserved")
 		}
-		b, _ := json.Marshal(evt)
-		_ = s.pub.Publish(ctx, string(events.SubjectOrderCreated), b)
+		return nil, fmt.Errorf("only %d left", free)
 	}
 
-	return order, nil
+	// Create order with expiration and reserve the units atomically
+	expiresAt := time.Now().UTC().Add(time.Duration(EXPIRATION_WINDOW_SECONDS) * time.Second)
+	return s.repo.CreateOrder(ctx, userID, ticket, quantity, expiresAt)
 }
 
 // CancelOrder marks an order as cancelled and releases the ticket reservation.
@@ -88,35 +80,12 @@ func (s *Service) CancelOrder(ctx context.Context, orderID string, userID string
 		return errors.New("cannot cancel order in status: " + order.Status)
 	}
 
-	// Cancel the order
-	if err := s.repo.CancelOrder(ctx, order.ID, order.Version); err != nil {
-		return err
-	}
-
-	// Release ticket reservation
-	ticket, _ := s.repo.GetTicket(ctx, order.TicketID)
-	if ticket != nil && ticket.OrderID != nil && *ticket.OrderID == order.ID {
-		_ = s.repo.UpdateTicketReservation(ctx, ticket.ID, nil, ticket.Version)
-	}
-
-	// Publish order:cancelled event
-	if s.pub != nil {
-		evt := events.OrderCancelledData{
-			ID:      order.ID,
-			Version: order.Version + 1,
-			Ticket: events.OrderTicketDetail{
-				ID:    order.TicketID,
-				Price: 0, // Price not needed for cancellation
-			},
-		}
-		b, _ := json.Marshal(evt)
-		_ = s.pub.Publish(ctx, string(events.SubjectOrderCancelled), b)
-	}
-
-	return nil
+	// Cancel the order and release the ticket reservation
+	return s.repo.CancelOrder(ctx, order.ID, order.Version)
 }
 
-// GetOrder retrieves a single order.
+// GetOrder retrieves a single order for its buyer or the user holding its
+// units.
 func (s *Service) GetOrder(ctx context.Context, orderID string, userID string) (*Order, error) {
 	order, err := s.repo.GetOrder(ctx, orderID)
 	if err != nil {
@@ -125,7 +94,7 @@ func (s *Service) GetOrder(ctx context.Context, orderID string, userID string) (
 	if order == nil {
 		return nil, errors.New("order not found")
 	}
-	if order.UserID != userID {
+	if order.UserID != userID && order.HolderID != userID {
 		return nil, errors.New("not authorized")
 	}
 	return order, nil
@@ -135,3 +104,9 @@ func (s *Service) GetOrder(ctx context.Context, orderID string, userID string) (
 func (s *Service) ListOrders(ctx context.Context, userID string) ([]*Order, error) {
 	return s.repo.ListOrdersByUser(ctx, userID)
 }
+
+// Snapshot returns one page of all orders with their versions, for replicas
+// in other services to bootstrap from.
+func (s *Service) Snapshot(ctx context.Context, after string, limit int) ([]*OrderSummary, error) {
+	return s.repo.Snapshot(ctx, after, limit)
+}
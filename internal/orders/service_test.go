@@ -1,8 +1,331 @@
 package orders
 
-import "testing"
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
 
-func TestPlaceholder(t *testing.T) {
-	// Placeholder test - implement full tests as needed
-	t.Log("Orders service tests placeholder")
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/replica"
+)
+
+// fakeRepo is an in-memory Repository for hermetic tests of the service and
+// listeners; the SQL behind each method is checked in repo_test.go.
+type fakeRepo struct {
+	mu      sync.Mutex
+	orders  map[string]*Order
+	tickets map[string]*Ticket
+	events  map[string]*Event
+	upserts int
+	seq     int
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{orders: map[string]*Order{}, tickets: map[string]*Ticket{}, events: map[string]*Event{}}
+}
+
+func (r *fakeRepo) EnsureSchema(ctx context.Context) error { return nil }
+
+func (r *fakeRepo) CreateOrder(ctx context.Context, userID string, ticket *Ticket, quantity int, expiresAt time.Time) (*Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := r.tickets[ticket.ID]
+	if t == nil || t.DeletedAt != nil || t.free() < quantity {
+		return nil, ErrTicketReserved
+	}
+	r.seq++
+	o := &Order{ID: fmt.Sprintf("order-%d", r.seq), UserID: userID, HolderID: userID, Status: "created", ExpiresAt: expiresAt, TicketID: ticket.ID,
+		Quantity: quantity, Price: ticket.Price}
+	r.orders[o.ID] = o
+	t.QuantityHeld += quantity
+	if t.QuantityTotal == 1 {
+		id := o.ID
+		t.OrderID = &id
+	}
+	cp := *o
+	return &cp, nil
+}
+
+func (r *fakeRepo) GetOrder(ctx context.Context, id string) (*Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if o, ok := r.orders[id]; ok {
+		return r.joined(o), nil
+	}
+	return nil, nil
+}
+
+// joined copies o and joins the seat and event of its ticket, like the
+// SQL repo.
+func (r *fakeRepo) joined(o *Order) *Order {
+	cp := *o
+	if t := r.tickets[o.TicketID]; t != nil {
+		cp.Seat = t.Seat
+		if e := r.events[t.EventID]; e != nil {
+			ev := *e
+			cp.Event = &ev
+		}
+	}
+	return &cp
+}
+
+func (r *fakeRepo) ListOrdersByUser(ctx context.Context, userID string) ([]*Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []*Order
+	for _, o := range r.orders {
+		if o.UserID == userID || o.HolderID == userID {
+			out = append(out, r.joined(o))
+		}
+	}
+	return out, nil
+}
+
+func (r *fakeRepo) CancelOrder(ctx context.Context, id string, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	o := r.orders[id]
+	if o == nil || o.Version != version {
+		return sql.ErrNoRows
+	}
+	o.Status = "cancelled"
+	o.Version++
+	if t := r.tickets[o.TicketID]; t != nil {
+		t.QuantityHeld -= o.Quantity
+		t.OrderID = nil
+	}
+	return nil
+}
+
+func (r *fakeRepo) CompleteOrder(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	o := r.orders[id]
+	if o == nil {
+		return sql.ErrNoRows
+	}
+	o.Status = "complete"
+	return nil
+}
+
+func (r *fakeRepo) UpsertTicket(ctx context.Context, in *Ticket) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := r.tickets[in.ID]
+	stored := 0
+	if t != nil {
+		stored = t.Version
+	}
+	if apply, err := replica.Check("orders_tickets", in.ID, stored, t != nil, in.Version); !apply {
+		return err
+	}
+	r.upserts++
+	cp := *in
+	cp.QuantityTotal, cp.QuantityHeld = units(in.QuantityTotal), 0
+	if t != nil {
+		cp.OrderID, cp.QuantityHeld = t.OrderID, t.QuantityHeld
+	}
+	r.tickets[in.ID] = &cp
+	return nil
+}
+
+// TransferOrder hands order id to userID; the holder versions are checked
+// in the Postgres tests.
+func (r *fakeRepo) TransferOrder(ctx context.Context, id, userID string, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	o := r.orders[id]
+	if o == nil {
+		return sql.ErrNoRows
+	}
+	o.HolderID = userID
+	return nil
+}
+
+// DeleteTicket withdraws ticket id; cancelling its open orders is checked
+// in the Postgres tests.
+func (r *fakeRepo) DeleteTicket(ctx context.Context, id string, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t := r.tickets[id]; t != nil {
+		now := time.Now()
+		t.DeletedAt, t.Version = &now, version
+	}
+	return nil
+}
+
+func (r *fakeRepo) UpsertEvent(ctx context.Context, e *Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cur := r.events[e.ID]
+	stored := 0
+	if cur != nil {
+		stored = cur.Version
+	}
+	if apply, err := replica.Check("orders_events", e.ID, stored, cur != nil, e.Version); !apply {
+		return err
+	}
+	cp := *e
+	r.events[e.ID] = &cp
+	return nil
+}
+
+func (r *fakeRepo) SeedTicket(ctx context.Context, t *Ticket) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cur := r.tickets[t.ID]
+	if cur != nil && cur.Version >= t.Version {
+		return nil
+	}
+	cp := *t
+	cp.OrderID, cp.QuantityTotal, cp.QuantityHeld = nil, units(t.QuantityTotal), 0
+	if cur != nil {
+		cp.OrderID, cp.QuantityHeld = cur.OrderID, cur.QuantityHeld
+	}
+	r.tickets[t.ID] = &cp
+	return nil
+}
+
+func (r *fakeRepo) Snapshot(ctx context.Context, after string, limit int) ([]*OrderSummary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []*OrderSummary
+	for _, o := range r.orders {
+		if o.ID > after {
+			out = append(out, &OrderSummary{ID: o.ID, UserID: o.UserID, Status: o.Status, Price: o.Price * int64(o.Quantity), Version: o.Version})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (r *fakeRepo) GetTicket(ctx context.Context, id string) (*Ticket, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.tickets[id]; ok && t.DeletedAt == nil {
+		cp := *t
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeRepo) GetSeatTicket(ctx context.Context, eventID string, seat Seat) (*Ticket, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range r.tickets {
+		if t.EventID == eventID && t.Seat != nil && *t.Seat == seat && t.DeletedAt == nil {
+			cp := *t
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRepo) IsTicketReserved(ctx context.Context, ticketID string) (bool, error) {
+	t, err := r.GetTicket(ctx, ticketID)
+	return t != nil && t.OrderID != nil, err
+}
+
+func TestCreateOrderReservesTicket(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo()
+	_ = repo.UpsertTicket(ctx, &Ticket{ID: "t1", Title: "Concert", Price: 2000, UserID: "seller"})
+	svc := NewService(repo)
+
+	order, err := svc.CreateOrder(ctx, "buyer", "t1", 1)
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if order.Status != "created" || order.ExpiresAt.Before(time.Now()) {
+		t.Errorf("unexpected order: %+v", order)
+	}
+	if _, err := svc.CreateOrder(ctx, "someone-else", "t1", 1); err == nil {
+		t.Error("second order for a reserved ticket succeeded")
+	}
+	if _, err := svc.CreateOrder(ctx, "buyer", "missing", 1); err == nil {
+		t.Error("order for unknown ticket succeeded")
+	}
+}
+
+func TestSeatIsOrderedOnce(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo()
+	a1 := Seat{Section: "Stalls", Row: "A", Number: 1}
+	a2 := Seat{Section: "Stalls", Row: "A", Number: 2}
+	_ = repo.UpsertTicket(ctx, &Ticket{ID: "t1", Title: "Concert: Stalls, row A, seat 1", Price: 5000, UserID: "seller", EventID: "e1", Seat: &a1})
+	_ = repo.UpsertTicket(ctx, &Ticket{ID: "t2", Title: "Concert: Stalls, row A, seat 2", Price: 5000, UserID: "seller", EventID: "e1", Seat: &a2})
+	svc := NewService(repo)
+
+	// Buyers racing for the same seat: exactly one gets it.
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var won []*Order
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if o, err := svc.CreateSeatOrder(ctx, fmt.Sprintf("buyer-%d", i), "e1", a1); err == nil {
+				mu.Lock()
+				won = append(won, o)
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	if len(won) != 1 {
+		t.Fatalf("%d orders for one seat, want 1", len(won))
+	}
+	if got, _ := repo.GetOrder(ctx, won[0].ID); got.TicketID != "t1" || got.Seat == nil || *got.Seat != a1 {
+		t.Errorf("order = %+v, want seat %+v of t1", got, a1)
+	}
+
+	if _, err := svc.CreateSeatOrder(ctx, "buyer-0", "e1", a2); err != nil {
+		t.Errorf("order for the next seat: %v", err)
+	}
+	if _, err := svc.CreateSeatOrder(ctx, "buyer-0", "e1", Seat{Section: "Stalls", Row: "Z", Number: 1}); err == nil {
+		t.Error("order for a seat without a ticket succeeded")
+	}
+}
+
+func TestOrderQuantityIsChecked(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo()
+	_ = repo.UpsertTicket(ctx, &Ticket{ID: "t1", Title: "Festival", Price: 5000, UserID: "seller", QuantityTotal: 5})
+	svc := NewService(repo)
+
+	if _, err := svc.CreateOrder(ctx, "buyer", "t1", 4); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if _, err := svc.CreateOrder(ctx, "buyer", "t1", 2); err == nil || err.Error() != "only 1 left" {
+		t.Errorf("order for 2 of 1 left: %v", err)
+	}
+	for _, quantity := range []int{0, MaxOrderQuantity + 1} {
+		if _, err := svc.CreateOrder(ctx, "buyer", "t1", quantity); err == nil {
+			t.Errorf("order for %d units succeeded", quantity)
+		}
+	}
+}
+
+func TestCancelOrderReleasesTicket(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo()
+	_ = repo.UpsertTicket(ctx, &Ticket{ID: "t1", Title: "Concert", Price: 2000, UserID: "seller"})
+	svc := NewService(repo)
+	order, _ := svc.CreateOrder(ctx, "buyer", "t1", 1)
+
+	if err := svc.CancelOrder(ctx, order.ID, "intruder"); err == nil {
+		t.Error("another user cancelled the order")
+	}
+	if err := svc.CancelOrder(ctx, order.ID, "buyer"); err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	if reserved, _ := repo.IsTicketReserved(ctx, "t1"); reserved {
+		t.Error("ticket still reserved after cancel")
+	}
 }
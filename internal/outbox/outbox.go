@@ -0,0 +1,52 @@
+// Package outbox implements the transactional outbox pattern: domain events
+// are written to a table in the same transaction as the state change that
+// produced them, and a relay publishes them to the broker afterwards.
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/store"
+)
+
+// Store persists pending events in a per-service outbox table.
+type Store struct {
+	db    *sql.DB
+	table string
+}
+
+// New returns a Store backed by the given table, e.g. "tickets_outbox".
+func New(db *sql.DB, table string) *Store { return &Store{db: db, table: table} }
+
+func (s *Store) EnsureSchema(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %[1]s (
+    id BIGSERIAL PRIMARY KEY,
+    aggregate_id TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    payload BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    sent_at TIMESTAMPTZ NULL
+);
+CREATE INDEX IF NOT EXISTS %[1]s_pending_idx ON %[1]s (id) WHERE sent_at IS NULL;
+`, s.table))
+	return err
+}
+
+// Add records an event for later publication. tx must be the transaction that
+// performs the corresponding state change.
+func (s *Store) Add(ctx context.Context, tx store.DBTX, aggregateID, subject string, payload []byte) error {
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+INSERT INTO %s (aggregate_id, subject, payload) VALUES ($1,$2,$3)
+`, s.table), aggregateID, subject, payload)
+	return err
+}
+
+// Pending returns the number of events not yet published.
+func (s *Store) Pending(ctx context.Context) (int, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT count(*) FROM %s WHERE sent_at IS NULL`, s.table)).Scan(&n)
+	return n, err
+}
@@ -0,0 +1,120 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/pubsub"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/store"
+)
+
+// Relay publishes pending outbox rows and marks them sent. Rows hold JSON
+// envelopes and are published in the process's wire format (see
+// events.SetEncoding).
+//
+// Rows are read in insertion order under a row lock, so concurrent relays in
+// other replicas wait rather than publish the same rows twice. If publishing
+// an event fails, later events for the same aggregate are held back until the
+// next pass so each aggregate's events always leave in order.
+type Relay struct {
+	store    *Store
+	pub      pubsub.Publisher
+	Interval time.Duration
+	Batch    int
+}
+
+// NewRelay returns a relay that drains s into pub.
+func NewRelay(s *Store, pub pubsub.Publisher) *Relay {
+	return &Relay{store: s, pub: pub, Interval: 500 * time.Millisecond, Batch: 100}
+}
+
+// Run polls the outbox until ctx is cancelled.
+func (r *Relay) Run(ctx context.Context) {
+	t := time.NewTicker(r.Interval)
+	defer t.Stop()
+	for {
+		for {
+			n, err := r.Flush(ctx)
+			if err != nil {
+				log.Printf("outbox %s: flush: %v", r.store.table, err)
+			}
+			if err != nil || n < r.Batch {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Flush publishes one batch of pending events and returns how many were sent.
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	published := 0
+	err := store.WithTx(ctx, r.store.db, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf(`
+SELECT id, aggregate_id, subject, payload FROM %s
+WHERE sent_at IS NULL ORDER BY id LIMIT $1 FOR UPDATE
+`, r.store.table), r.Batch)
+		if err != nil {
+			return err
+		}
+		type row struct {
+			id          int64
+			aggregateID string
+			subject     string
+			payload     []byte
+		}
+		var batch []row
+		for rows.Next() {
+			var rw row
+			if err := rows.Scan(&rw.id, &rw.aggregateID, &rw.subject, &rw.payload); err != nil {
+				rows.Close()
+				return err
+			}
+			batch = append(batch, rw)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		var sent []int64
+		blocked := map[string]bool{}
+		for _, rw := range batch {
+			if blocked[rw.aggregateID] {
+				continue
+			}
+			msg, err := events.WireMessage(rw.subject, rw.payload)
+			if err != nil {
+				// Not a contract this build can transcode: send it as stored.
+				log.Printf("outbox %s: encode %s for %s: %v", r.store.table, rw.subject, rw.aggregateID, err)
+				msg = pubsub.Message{Subject: rw.subject, Data: rw.payload, Header: pubsub.Header{events.HeaderContentType: events.ContentTypeJSON}}
+			}
+			if err := r.pub.PublishMsg(ctx, msg); err != nil {
+				log.Printf("outbox %s: publish %s for %s: %v", r.store.table, rw.subject, rw.aggregateID, err)
+				blocked[rw.aggregateID] = true
+				continue
+			}
+			sent = append(sent, rw.id)
+		}
+		if len(sent) == 0 {
+			return nil
+		}
+		published = len(sent)
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET sent_at=now() WHERE id = ANY($1)`, r.store.table), pq.Array(sent))
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return published, nil
+}
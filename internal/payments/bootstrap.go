@@ -0,0 +1,36 @@
+package payments
+
+import (
+	"context"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/snapshot"
+)
+
+// snapshotOrder is an order as served by the orders snapshot endpoint.
+type snapshotOrder struct {
+	ID      string `json:"id"`
+	UserID  string `json:"userId"`
+	Status  string `json:"status"`
+	Price   int64  `json:"price"`
+	Version int    `json:"version"`
+}
+
+// Bootstrap fills the order replica from the orders service snapshot at
+// endpoint (e.g. http://orders:3000/internal/orders/snapshot) and returns how
+// many orders it read. Orders the replica already holds at the same or a
+// later version are left alone, so it is safe to run on every start. Run it
+// before subscribing, so orders placed before this service first consumed
+// order events can be paid.
+func Bootstrap(ctx context.Context, repo Repository, endpoint, token string) (int, error) {
+	n := 0
+	err := snapshot.Fetch(ctx, endpoint, token, func(page []snapshotOrder) error {
+		for _, o := range page {
+			if err := repo.SeedOrder(ctx, o.ID, o.Price, o.Status, o.UserID, o.Version); err != nil {
+				return err
+			}
+		}
+		n += len(page)
+		return nil
+	})
+	return n, err
+}
@@ -2,37 +2,41 @@ package payments
 
 import (
 	"context"
-	"encoding/json"
-	"log"
 
 	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/inbox"
 	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/pubsub"
 )
 
-// RegisterNATSListeners subscribes to order events to maintain local order state.
-func RegisterNATSListeners(ctx context.Context, sub pubsub.Subscriber, repo Repository) error {
-	if err := sub.Subscribe(string(events.SubjectOrderCreated), func(msg []byte) {
-		var d events.OrderCreatedData
-		if err := json.Unmarshal(msg, &d); err != nil {
-			log.Printf("order:created unmarshal: %v", err)
-			return
-		}
-		if err := repo.UpsertOrder(ctx, d.ID, int64(d.Ticket.Price), d.Status, d.UserID, d.Version); err != nil {
-			log.Printf("order:created upsert: %v", err)
-		}
+// QueueGroup is the queue group of the payments replicas; one of them
+// records each order in the payments order replica.
+const QueueGroup = "payments"
+
+// InboxTable records events already processed by the payments service.
+const InboxTable = "payments_inbox"
+
+// RegisterNATSListeners subscribes to order events to maintain local order
+// state. Each handler runs through in, so redelivered events are applied at
+// most once; events that keep failing are handed to dl.
+func RegisterNATSListeners(ctx context.Context, sub pubsub.Subscriber, repo Repository, in inbox.Processor, dl events.DeadLetterSink) error {
+	c := &events.Consumer{Sub: sub, Service: QueueGroup, DeadLetters: dl}
+
+	if err := events.Subscribe(ctx, c, events.SubjectOrderCreated, func(ctx context.Context, e events.Envelope[events.OrderCreatedData]) error {
+		d := e.Data
+		_, err := in.Process(ctx, e.ID, string(e.Subject), func(ctx context.Context) error {
+			return repo.UpsertOrder(ctx, d.ID, d.Amount(), d.Status, d.UserID, d.Version)
+		})
+		return err
 	}); err != nil {
 		return err
 	}
 
-	if err := sub.Subscribe(string(events.SubjectOrderCancelled), func(msg []byte) {
-		var d events.OrderCancelledData
-		if err := json.Unmarshal(msg, &d); err != nil {
-			log.Printf("order:cancelled unmarshal: %v", err)
-			return
-		}
-		if err := repo.CancelOrder(ctx, d.ID, d.Version); err != nil {
-			log.Printf("order:cancelled cancel: %v", err)
-		}
+	if err := events.Subscribe(ctx, c, events.SubjectOrderCancelled, func(ctx context.Context, e events.Envelope[events.OrderCancelledData]) error {
+		d := e.Data
+		_, err := in.Process(ctx, e.ID, string(e.Subject), func(ctx context.Context) error {
+			return repo.CancelOrder(ctx, d.ID, d.Version)
+		})
+		return err
 	}); err != nil {
 		return err
 	}
@@ -3,14 +3,26 @@ package payments
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"time"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/outbox"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/replica"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/store"
 )
 
+// OutboxTable holds payment events awaiting publication.
+const OutboxTable = "payments_outbox"
+
+// Repository is the payments data layer. All methods join the transaction
+// carried by ctx, if any (see store.ContextWithTx).
 type Repository interface {
 	EnsureSchema(ctx context.Context) error
 	UpsertOrder(ctx context.Context, id string, price int64, status string, userID string, version int) error
 	CancelOrder(ctx context.Context, id string, version int) error
+	// SeedOrder stores an order from the orders snapshot unless the replica
+	// already holds that version or a later one.
+	SeedOrder(ctx context.Context, id string, price int64, status string, userID string, version int) error
 	GetOrder(ctx context.Context, id string) (order struct {
 		ID      string
 		Price   int64
@@ -21,9 +33,14 @@ type Repository interface {
 	InsertPayment(ctx context.Context, id string, orderID string, amount int64, currency string, stripeID string) error
 }
 
-type repo struct{ db *sql.DB }
+type repo struct {
+	db     *sql.DB
+	outbox *outbox.Store
+}
 
-func NewRepository(db *sql.DB) Repository { return &repo{db: db} }
+// NewRepository returns a Postgres repository. InsertPayment records
+// payment:created in ob within the same transaction.
+func NewRepository(db *sql.DB, ob *outbox.Store) Repository { return &repo{db: db, outbox: ob} }
 
 func (r *repo) EnsureSchema(ctx context.Context) error {
 	_, err := r.db.ExecContext(ctx, `
@@ -44,30 +61,61 @@ CREATE TABLE IF NOT EXISTS payments_orders (
     updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
 );
 `)
-	return err
+	if err != nil {
+		return err
+	}
+	return r.outbox.EnsureSchema(ctx)
 }
 
+// UpsertOrder records order:created in the replica. Like CancelOrder it
+// applies only the next version of the order (see package replica).
 func (r *repo) UpsertOrder(ctx context.Context, id string, price int64, status string, userID string, version int) error {
-	_, err := r.db.ExecContext(ctx, `
+	return r.applyOrder(ctx, id, version, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
 INSERT INTO payments_orders (id, price, status, user_id, version, updated_at)
 VALUES ($1,$2,$3,$4,$5,$6)
 ON CONFLICT (id) DO UPDATE SET price=EXCLUDED.price, status=EXCLUDED.status, user_id=EXCLUDED.user_id, version=EXCLUDED.version, updated_at=EXCLUDED.updated_at
 `, id, price, status, userID, version, time.Now().UTC())
-	return err
+		return err
+	})
 }
 
+// CancelOrder records order:cancelled at version in the replica.
 func (r *repo) CancelOrder(ctx context.Context, id string, version int) error {
-	res, err := r.db.ExecContext(ctx, `
+	return r.applyOrder(ctx, id, version, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
 UPDATE payments_orders SET status='cancelled', version=$2, updated_at=$3 WHERE id=$1
 `, id, version, time.Now().UTC())
-	if err != nil {
 		return err
-	}
-	n, _ := res.RowsAffected()
-	if n == 0 {
-		return errors.New("order not found")
-	}
-	return nil
+	})
+}
+
+func (r *repo) SeedOrder(ctx context.Context, id string, price int64, status string, userID string, version int) error {
+	// Snapshots may skip versions, so this only requires the snapshot to be newer.
+	_, err := store.Conn(ctx, r.db).ExecContext(ctx, `
+INSERT INTO payments_orders (id, price, status, user_id, version, updated_at)
+VALUES ($1,$2,$3,$4,$5,$6)
+ON CONFLICT (id) DO UPDATE SET price=EXCLUDED.price, status=EXCLUDED.status, user_id=EXCLUDED.user_id, version=EXCLUDED.version, updated_at=EXCLUDED.updated_at
+WHERE payments_orders.version < EXCLUDED.version
+`, id, price, status, userID, version, time.Now().UTC())
+	return err
+}
+
+// applyOrder runs write if version is the next version of order id, with the
+// replica row locked.
+func (r *repo) applyOrder(ctx context.Context, id string, version int, write func(tx *sql.Tx) error) error {
+	return store.WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		var stored int
+		err := tx.QueryRowContext(ctx, `SELECT version FROM payments_orders WHERE id=$1 FOR UPDATE`, id).Scan(&stored)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		apply, err := replica.Check("payments_orders", id, stored, err == nil, version)
+		if !apply {
+			return err
+		}
+		return write(tx)
+	})
 }
 
 func (r *repo) GetOrder(ctx context.Context, id string) (order struct {
@@ -77,14 +125,23 @@ func (r *repo) GetOrder(ctx context.Context, id string) (order struct {
 	UserID  string
 	Version int
 }, err error) {
-	row := r.db.QueryRowContext(ctx, `SELECT id, price, status, user_id, version FROM payments_orders WHERE id=$1`, id)
+	row := store.Conn(ctx, r.db).QueryRowContext(ctx, `SELECT id, price, status, user_id, version FROM payments_orders WHERE id=$1`, id)
 	err = row.Scan(&order.ID, &order.Price, &order.Status, &order.UserID, &order.Version)
 	return
 }
 
 func (r *repo) InsertPayment(ctx context.Context, id string, orderID string, amount int64, currency string, stripeID string) error {
-	_, err := r.db.ExecContext(ctx, `
+	return store.WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `
 INSERT INTO payments (id, order_id, amount, currency, stripe_id) VALUES ($1,$2,$3,$4,$5)
-`, id, orderID, amount, currency, stripeID)
-	return err
+`, id, orderID, amount, currency, stripeID); err != nil {
+			return err
+		}
+		evt := events.PaymentCreatedData{ID: id, OrderID: orderID, StripeID: stripeID}
+		b, err := events.Marshal(ctx, events.SubjectPaymentCreated, evt)
+		if err != nil {
+			return err
+		}
+		return r.outbox.Add(ctx, tx, id, string(events.SubjectPaymentCreated), b)
+	})
 }
@@ -2,12 +2,9 @@ package payments
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
 	"errors"
 	"time"
-
-	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
 )
 
 // Payment is a very small payment record used for responses.
@@ -27,20 +24,15 @@ type StripeClient interface {
 	SetWebhookSecret(secret string)
 }
 
-// Publisher is expected to publish domain events.
-type Publisher interface {
-	Publish(ctx context.Context, subject string, data []byte) error
-}
-
+// Service handles payment logic. payment:created is written to the outbox by
+// the repository and published by an outbox.Relay.
 type Service struct {
-	db     *sql.DB
-	pub    Publisher
 	stripe StripeClient
 	repo   Repository
 }
 
-func NewService(db *sql.DB, pub Publisher, stripe StripeClient) *Service {
-	return &Service{db: db, pub: pub, stripe: stripe, repo: NewRepository(db)}
+func NewService(repo Repository, stripe StripeClient) *Service {
+	return &Service{stripe: stripe, repo: repo}
 }
 
 func (s *Service) CreateCharge(ctx context.Context, orderID string, amount int64, currency string) (*Payment, error) {
@@ -65,7 +57,7 @@ func (s *Service) CreateCharge(ctx context.Context, orderID string, amount int64
 		return nil, err
 	}
 
-	// Persist payment
+	// Persist payment together with its payment:created event
 	now := time.Now().UTC()
 	pay := &Payment{
 		ID:        "pay_" + orderID,
@@ -79,11 +71,6 @@ func (s *Service) CreateCharge(ctx context.Context, orderID string, amount int64
 		return nil, err
 	}
 
-	// Publish payment:created event
-	evt := events.PaymentCreatedData{ID: pay.ID, OrderID: orderID, StripeID: stripeID}
-	b, _ := json.Marshal(evt)
-	_ = s.pub.Publish(ctx, string(events.SubjectPaymentCreated), b)
-
 	return pay, nil
 }
 
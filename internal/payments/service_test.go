@@ -1,8 +1,145 @@
 package payments
 
-import "testing"
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+	"time"
 
-func TestPlaceholder(t *testing.T) {
-	// Placeholder test - implement full tests as needed
-	t.Log("Payments service tests placeholder")
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events/eventstest"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/inbox"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/pubsub"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/replica"
+)
+
+type order = struct {
+	ID      string
+	Price   int64
+	Status  string
+	UserID  string
+	Version int
+}
+
+// fakeRepo is an in-memory Repository for hermetic tests.
+type fakeRepo struct {
+	mu       sync.Mutex
+	orders   map[string]order
+	payments []string
+}
+
+func newFakeRepo() *fakeRepo { return &fakeRepo{orders: map[string]order{}} }
+
+func (r *fakeRepo) EnsureSchema(ctx context.Context) error { return nil }
+
+func (r *fakeRepo) UpsertOrder(ctx context.Context, id string, price int64, status string, userID string, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	o, found := r.orders[id]
+	if apply, err := replica.Check("payments_orders", id, o.Version, found, version); !apply {
+		return err
+	}
+	r.orders[id] = order{ID: id, Price: price, Status: status, UserID: userID, Version: version}
+	return nil
+}
+
+func (r *fakeRepo) CancelOrder(ctx context.Context, id string, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	o, found := r.orders[id]
+	if apply, err := replica.Check("payments_orders", id, o.Version, found, version); !apply {
+		return err
+	}
+	o.Status, o.Version = "cancelled", version
+	r.orders[id] = o
+	return nil
+}
+
+func (r *fakeRepo) SeedOrder(ctx context.Context, id string, price int64, status string, userID string, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if o, found := r.orders[id]; found && o.Version >= version {
+		return nil
+	}
+	r.orders[id] = order{ID: id, Price: price, Status: status, UserID: userID, Version: version}
+	return nil
+}
+
+func (r *fakeRepo) GetOrder(ctx context.Context, id string) (order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	o, ok := r.orders[id]
+	if !ok {
+		return o, sql.ErrNoRows
+	}
+	return o, nil
+}
+
+func (r *fakeRepo) InsertPayment(ctx context.Context, id string, orderID string, amount int64, currency string, stripeID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.payments = append(r.payments, id)
+	return nil
+}
+
+type fakeStripe struct{ intents int }
+
+func (s *fakeStripe) CreatePaymentIntent(amount int64, currency string, metadata map[string]string) (string, error) {
+	s.intents++
+	return "pi_test", nil
+}
+func (s *fakeStripe) VerifyWebhookSignature(payload []byte, sigHeader string) (bool, error) {
+	return true, nil
+}
+func (s *fakeStripe) SetWebhookSecret(secret string) {}
+
+func TestCreateCharge(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo()
+	stripe := &fakeStripe{}
+	svc := NewService(repo, stripe)
+	_ = repo.UpsertOrder(ctx, "o1", 2000, "created", "buyer", 0)
+	_ = repo.UpsertOrder(ctx, "o2", 2000, "created", "buyer", 0)
+	_ = repo.CancelOrder(ctx, "o2", 1)
+
+	if _, err := svc.CreateCharge(ctx, "o1", 1999, "usd"); err == nil {
+		t.Error("charge with wrong amount succeeded")
+	}
+	if _, err := svc.CreateCharge(ctx, "o2", 2000, "usd"); err == nil {
+		t.Error("charge for cancelled order succeeded")
+	}
+	if stripe.intents != 0 {
+		t.Errorf("payment intents created for rejected charges: %d", stripe.intents)
+	}
+
+	pay, err := svc.CreateCharge(ctx, "o1", 2000, "usd")
+	if err != nil {
+		t.Fatalf("CreateCharge: %v", err)
+	}
+	if pay.OrderID != "o1" || len(repo.payments) != 1 {
+		t.Errorf("payment = %+v, stored = %v", pay, repo.payments)
+	}
+}
+
+func TestOrderEventsMaintainReplica(t *testing.T) {
+	ctx := context.Background()
+	bus := pubsub.NewMemory()
+	repo := newFakeRepo()
+	if err := RegisterNATSListeners(ctx, bus, repo, inbox.NewMemory(), nil); err != nil {
+		t.Fatalf("RegisterNATSListeners: %v", err)
+	}
+
+	eventstest.Publish(t, bus, events.SubjectOrderCreated, events.OrderCreatedData{
+		ID: "o1", Status: "created", UserID: "buyer", ExpiresAt: time.Now().Add(time.Minute),
+		Ticket: events.OrderTicketDetail{ID: "t1", Price: 2000},
+	})
+	if o, err := repo.GetOrder(ctx, "o1"); err != nil || o.Price != 2000 || o.Status != "created" {
+		t.Fatalf("replica after order:created = %+v, %v", o, err)
+	}
+
+	eventstest.Publish(t, bus, events.SubjectOrderCancelled, events.OrderCancelledData{ID: "o1", Version: 1, Ticket: events.OrderTicketDetail{ID: "t1"}})
+	if o, _ := repo.GetOrder(ctx, "o1"); o.Status != "cancelled" || o.Version != 1 {
+		t.Fatalf("replica after order:cancelled = %+v", o)
+	}
 }
@@ -0,0 +1,291 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	nats "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// JetStreamConfig configures the durable JetStream client.
+type JetStreamConfig struct {
+	// Durable names the service; it prefixes every durable consumer so a
+	// restarted service resumes where it left off.
+	Durable string
+	// Subjects lists every subject the service publishes or consumes. One
+	// stream is provisioned per subject family ("ticket:created" and
+	// "ticket:updated" share the TICKET stream).
+	Subjects []string
+	// MaxDeliver bounds redelivery attempts per message (default 10), except
+	// for handler errors that wrap ErrRetryLater.
+	MaxDeliver int
+	// BackOff is the redelivery delay for each failed attempt; the last value
+	// repeats for later attempts.
+	BackOff []time.Duration
+	// AckWait is how long the server waits for an ack before redelivering.
+	AckWait time.Duration
+}
+
+var defaultBackOff = []time.Duration{time.Second, 5 * time.Second, 30 * time.Second, 2 * time.Minute}
+
+// JetStreamClient implements Client on top of JetStream streams and durable
+// pull consumers. Messages are acked only after the handler returns.
+type JetStreamClient struct {
+	conn *nats.Conn
+	js   jetstream.JetStream
+	cfg  JetStreamConfig
+
+	ctx    context.Context // cancelled by Close; parent of handler contexts
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	streams map[string]string // subject -> stream name
+	active  []jetstream.ConsumeContext
+}
+
+// NewJetStream connects to NATS and provisions the streams for cfg.Subjects.
+func NewJetStream(url string, cfg JetStreamConfig, opts ...nats.Option) (*JetStreamClient, error) {
+	if cfg.Durable == "" {
+		return nil, fmt.Errorf("jetstream: durable name is required")
+	}
+	if cfg.MaxDeliver <= 0 {
+		cfg.MaxDeliver = 10
+	}
+	if len(cfg.BackOff) == 0 {
+		cfg.BackOff = defaultBackOff
+	}
+	if cfg.AckWait <= 0 {
+		cfg.AckWait = 30 * time.Second
+	}
+
+	base := []nats.Option{
+		nats.Name("ticket-booking-go-app:" + cfg.Durable),
+		nats.ReconnectWait(2 * time.Second),
+		nats.MaxReconnects(-1),
+		nats.Timeout(5 * time.Second),
+	}
+	conn, err := nats.Connect(url, append(base, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	c := &JetStreamClient{conn: conn, js: js, cfg: cfg, streams: map[string]string{}}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := c.ensureStreams(ctx, cfg.Subjects); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	return c, nil
+}
+
+// ensureStreams creates one stream per subject family, or adds subjects to
+// a family stream that already exists. Services declare only the subjects
+// they use, so a stream's subjects are merged, never replaced: replacing
+// them would stop the stream capturing what other services publish.
+func (c *JetStreamClient) ensureStreams(ctx context.Context, subjects []string) error {
+	families := map[string][]string{}
+	var order []string
+	for _, s := range subjects {
+		name := streamName(s)
+		if _, ok := families[name]; !ok {
+			order = append(order, name)
+		}
+		families[name] = append(families[name], s)
+	}
+	for _, name := range order {
+		cfg := jetstream.StreamConfig{
+			Name:      name,
+			Subjects:  families[name],
+			Storage:   jetstream.FileStorage,
+			Retention: jetstream.LimitsPolicy,
+			MaxAge:    7 * 24 * time.Hour,
+		}
+		stream, err := c.js.Stream(ctx, name)
+		switch {
+		case errors.Is(err, jetstream.ErrStreamNotFound):
+			_, err = c.js.CreateStream(ctx, cfg)
+		case err == nil:
+			cfg = stream.CachedInfo().Config
+			if merged, changed := mergeSubjects(cfg.Subjects, families[name]); changed {
+				cfg.Subjects = merged
+				_, err = c.js.UpdateStream(ctx, cfg)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("jetstream: stream %s: %w", name, err)
+		}
+		for _, s := range families[name] {
+			c.streams[s] = name
+		}
+	}
+	return nil
+}
+
+// mergeSubjects appends the subjects of add missing from have, and reports
+// whether there were any.
+func mergeSubjects(have, add []string) ([]string, bool) {
+	out := append([]string(nil), have...)
+	for _, s := range add {
+		found := false
+		for _, h := range have {
+			found = found || h == s
+		}
+		if !found {
+			out = append(out, s)
+		}
+	}
+	return out, len(out) > len(have)
+}
+
+// streamName maps "ticket:created" to the family stream "TICKET".
+func streamName(subject string) string {
+	family := subject
+	if i := strings.IndexAny(subject, ":."); i > 0 {
+		family = subject[:i]
+	}
+	return strings.ToUpper(sanitizeName(family))
+}
+
+// sanitizeName strips characters that are not allowed in stream or consumer names.
+func sanitizeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', '/', '\\', ':', ' ', '\t':
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+func (c *JetStreamClient) Publish(ctx context.Context, subject string, data []byte) error {
+	return c.PublishMsg(ctx, Message{Subject: subject, Data: data})
+}
+
+func (c *JetStreamClient) PublishMsg(ctx context.Context, m Message) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+	msg := nats.NewMsg(m.Subject)
+	msg.Data = m.Data
+	for k, v := range m.Header {
+		msg.Header.Set(k, v)
+	}
+	_, err := c.js.PublishMsg(ctx, msg)
+	return err
+}
+
+// Subscribe attaches a durable consumer named after the service and subject.
+// Durable consumers are shared, so replicas of a service already split the
+// work. A handler that fails is nak'ed and redelivered after the configured
+// backoff; its context expires with the ack wait.
+func (c *JetStreamClient) Subscribe(subject string, h Handler) error {
+	return c.consume(subject, c.cfg.Durable, h)
+}
+
+// QueueSubscribe binds to the durable consumer named after queue, so every
+// client using the same queue competes for the same messages.
+func (c *JetStreamClient) QueueSubscribe(subject, queue string, h Handler) error {
+	return c.consume(subject, queue, h)
+}
+
+func (c *JetStreamClient) consume(subject, durable string, h Handler) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	c.mu.Lock()
+	stream, ok := c.streams[subject]
+	if !ok {
+		if err := c.ensureStreams(ctx, []string{subject}); err != nil {
+			c.mu.Unlock()
+			return err
+		}
+		stream = c.streams[subject]
+	}
+	c.mu.Unlock()
+
+	cons, err := c.js.CreateOrUpdateConsumer(ctx, stream, jetstream.ConsumerConfig{
+		Durable:       sanitizeName(durable + "_" + subject),
+		FilterSubject: subject,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		DeliverPolicy: jetstream.DeliverAllPolicy,
+		AckWait:       c.cfg.AckWait,
+		// handle enforces cfg.MaxDeliver so that it can keep redelivering
+		// messages whose handler returns ErrRetryLater.
+		MaxDeliver: -1,
+	})
+	if err != nil {
+		return fmt.Errorf("jetstream: consumer for %s: %w", subject, err)
+	}
+
+	cc, err := cons.Consume(func(m jetstream.Msg) {
+		c.handle(subject, m, h)
+	})
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.active = append(c.active, cc)
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *JetStreamClient) handle(subject string, m jetstream.Msg, h Handler) {
+	msg := Message{Subject: m.Subject(), Data: m.Data(), Delivery: 1}
+	if md, err := m.Metadata(); err == nil {
+		msg.Delivery = int(md.NumDelivered)
+	}
+	if hdr := m.Headers(); len(hdr) > 0 {
+		msg.Header = Header{}
+		for k := range hdr {
+			msg.Header[k] = hdr.Get(k)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, c.cfg.AckWait)
+	err := invoke(ctx, h, msg)
+	cancel()
+	if err != nil {
+		if msg.Delivery >= c.cfg.MaxDeliver && !errors.Is(err, ErrRetryLater) {
+			log.Printf("jetstream %s: giving up after %d deliveries: %v", subject, msg.Delivery, err)
+			_ = m.Term()
+			return
+		}
+		delay := backoffFor(c.cfg.BackOff, msg.Delivery)
+		log.Printf("jetstream %s: delivery %d failed: %v (redelivering in %s)", subject, msg.Delivery, err, delay)
+		_ = m.NakWithDelay(delay)
+		return
+	}
+	if err := m.Ack(); err != nil {
+		log.Printf("jetstream %s: ack: %v", subject, err)
+	}
+}
+
+func (c *JetStreamClient) Close() error {
+	c.cancel()
+	c.mu.Lock()
+	for _, cc := range c.active {
+		cc.Stop()
+	}
+	c.active = nil
+	c.mu.Unlock()
+	if c.conn != nil && !c.conn.IsClosed() {
+		c.conn.Drain()
+		c.conn.Close()
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+func runJetStreamServer(t *testing.T) *server.Server {
+	t.Helper()
+	s, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		t.Fatalf("nats-server: %v", err)
+	}
+	go s.Start()
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats-server not ready")
+	}
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+func newTestJetStream(t *testing.T, url, durable string) *JetStreamClient {
+	t.Helper()
+	c, err := NewJetStream(url, JetStreamConfig{
+		Durable:  durable,
+		Subjects: []string{"ticket:created", "ticket:updated"},
+		BackOff:  []time.Duration{50 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("NewJetStream: %v", err)
+	}
+	return c
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestJetStreamDeliversEventsPublishedWhileConsumerIsDown(t *testing.T) {
+	s := runJetStreamServer(t)
+
+	first := newTestJetStream(t, s.ClientURL(), "orders")
+	var got atomic.Int32
+	if err := first.Subscribe("ticket:created", func(context.Context, Message) error { got.Add(1); return nil }); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if err := first.Publish(context.Background(), "ticket:created", []byte(`{"id":"1"}`)); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	waitFor(t, func() bool { return got.Load() == 1 })
+	first.Close()
+
+	// Published while the "orders" consumer is offline.
+	pub := newTestJetStream(t, s.ClientURL(), "tickets")
+	defer pub.Close()
+	if err := pub.Publish(context.Background(), "ticket:created", []byte(`{"id":"2"}`)); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	second := newTestJetStream(t, s.ClientURL(), "orders")
+	defer second.Close()
+	var payload atomic.Value
+	if err := second.Subscribe("ticket:created", func(_ context.Context, m Message) error { payload.Store(string(m.Data)); return nil }); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	waitFor(t, func() bool { return payload.Load() != nil })
+	if p := payload.Load().(string); p != `{"id":"2"}` {
+		t.Fatalf("resumed consumer got %s, want only the missed event", p)
+	}
+}
+
+func TestJetStreamRedeliversWhenHandlerFails(t *testing.T) {
+	s := runJetStreamServer(t)
+	c := newTestJetStream(t, s.ClientURL(), "orders")
+	defer c.Close()
+
+	var attempts, lastDelivery atomic.Int32
+	if err := c.Subscribe("ticket:updated", func(ctx context.Context, m Message) error {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("handler context has no deadline")
+		}
+		if m.Header["Content-Type"] != "application/json" {
+			t.Errorf("header = %v", m.Header)
+		}
+		lastDelivery.Store(int32(m.Delivery))
+		switch attempts.Add(1) {
+		case 1:
+			return errors.New("transient failure")
+		case 2:
+			panic("transient failure")
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if err := c.PublishMsg(context.Background(), Message{
+		Subject: "ticket:updated",
+		Data:    []byte(`{}`),
+		Header:  Header{"Content-Type": "application/json"},
+	}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	waitFor(t, func() bool { return attempts.Load() == 3 })
+
+	time.Sleep(200 * time.Millisecond)
+	if n := attempts.Load(); n != 3 {
+		t.Fatalf("handler ran %d times after success, want 3", n)
+	}
+	if d := lastDelivery.Load(); d != 3 {
+		t.Fatalf("last delivery count = %d, want 3", d)
+	}
+}
+
+func TestJetStreamKeepsRedeliveringRetryLaterPastLimit(t *testing.T) {
+	s := runJetStreamServer(t)
+	c := newTestJetStream(t, s.ClientURL(), "orders")
+	defer c.Close()
+	c.cfg.MaxDeliver = 2
+
+	var failed, gapped atomic.Int32
+	if err := c.Subscribe("ticket:created", func(context.Context, Message) error {
+		failed.Add(1)
+		return errors.New("permanent failure")
+	}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if err := c.Subscribe("ticket:updated", func(_ context.Context, m Message) error {
+		gapped.Store(int32(m.Delivery))
+		if m.Delivery < 4 {
+			return fmt.Errorf("version gap: %w", ErrRetryLater)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	for _, subject := range []string{"ticket:created", "ticket:updated"} {
+		if err := c.Publish(context.Background(), subject, []byte(`{}`)); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+	waitFor(t, func() bool { return gapped.Load() == 4 })
+
+	time.Sleep(200 * time.Millisecond)
+	if n := failed.Load(); n != 2 {
+		t.Fatalf("failing handler ran %d times, want MaxDeliver 2", n)
+	}
+}
+
+func TestJetStreamMergesSubjectsOfFamilyStream(t *testing.T) {
+	s := runJetStreamServer(t)
+	orders := newTestJetStream(t, s.ClientURL(), "orders")
+	defer orders.Close()
+	tickets, err := NewJetStream(s.ClientURL(), JetStreamConfig{Durable: "tickets", Subjects: []string{"ticket:deleted"}})
+	if err != nil {
+		t.Fatalf("NewJetStream: %v", err)
+	}
+	defer tickets.Close()
+	// Subscribing to an undeclared subject adds it to the stream as well.
+	if err := tickets.Subscribe("ticket:transferred", func(context.Context, Message) error { return nil }); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	stream, err := orders.js.Stream(context.Background(), "TICKET")
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	got := stream.CachedInfo().Config.Subjects
+	want := []string{"ticket:created", "ticket:updated", "ticket:deleted", "ticket:transferred"}
+	if len(got) != len(want) {
+		t.Fatalf("TICKET subjects = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("TICKET subjects = %v, want %v", got, want)
+		}
+	}
+	if err := orders.Publish(context.Background(), "ticket:created", []byte(`{}`)); err != nil {
+		t.Errorf("publish on the first service's subject: %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory is an in-process Client for hermetic tests. Publish delivers
+// synchronously, before returning, to every plain subscriber and to one member
+// of each queue group (round-robin), in subscription order. A handler that
+// fails is redelivered immediately, up to MaxDeliver deliveries. Every
+// published message is recorded for assertions.
+type Memory struct {
+	// MaxDeliver bounds deliveries per message and subscriber (default 10).
+	MaxDeliver int
+
+	mu        sync.Mutex
+	subs      map[string][]memorySub
+	next      map[string]int // subject+queue -> next member
+	published []Message
+}
+
+type memorySub struct {
+	queue   string
+	handler Handler
+}
+
+// NewMemory returns an empty in-memory broker.
+func NewMemory() *Memory {
+	return &Memory{subs: map[string][]memorySub{}, next: map[string]int{}}
+}
+
+func (m *Memory) Publish(ctx context.Context, subject string, data []byte) error {
+	return m.PublishMsg(ctx, Message{Subject: subject, Data: data})
+}
+
+func (m *Memory) PublishMsg(ctx context.Context, msg Message) error {
+	subject := msg.Subject
+	msg.Data = append([]byte(nil), msg.Data...)
+	if msg.Header != nil {
+		h := Header{}
+		for k, v := range msg.Header {
+			h[k] = v
+		}
+		msg.Header = h
+	}
+	msg.Delivery = 0
+
+	m.mu.Lock()
+	m.published = append(m.published, msg)
+	var targets []Handler
+	seen := map[string]bool{}
+	subs := m.subs[subject]
+	for _, s := range subs {
+		if s.queue == "" {
+			targets = append(targets, s.handler)
+			continue
+		}
+		if seen[s.queue] {
+			continue
+		}
+		seen[s.queue] = true
+		var members []memorySub
+		for _, o := range subs {
+			if o.queue == s.queue {
+				members = append(members, o)
+			}
+		}
+		key := subject + "\x00" + s.queue
+		targets = append(targets, members[m.next[key]%len(members)].handler)
+		m.next[key]++
+	}
+	m.mu.Unlock()
+
+	// Deliver without holding the lock so handlers may publish in turn.
+	for _, h := range targets {
+		m.deliver(h, msg)
+	}
+	return nil
+}
+
+func (m *Memory) deliver(h Handler, msg Message) {
+	limit := m.MaxDeliver
+	if limit <= 0 {
+		limit = 10
+	}
+	for msg.Delivery = 1; msg.Delivery <= limit; msg.Delivery++ {
+		if invoke(context.Background(), h, msg) == nil {
+			return
+		}
+	}
+}
+
+func (m *Memory) Subscribe(subject string, h Handler) error {
+	return m.QueueSubscribe(subject, "", h)
+}
+
+func (m *Memory) QueueSubscribe(subject, queue string, h Handler) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subs[subject] = append(m.subs[subject], memorySub{queue: queue, handler: h})
+	return nil
+}
+
+func (m *Memory) Close() error { return nil }
+
+// Messages returns every message published so far, in order.
+func (m *Memory) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.published...)
+}
+
+// Published returns the payloads published on subject, in order.
+func (m *Memory) Published(subject string) [][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out [][]byte
+	for _, msg := range m.published {
+		if msg.Subject == subject {
+			out = append(out, msg.Data)
+		}
+	}
+	return out
+}
+
+// Reset forgets recorded messages but keeps subscriptions.
+func (m *Memory) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.published = nil
+}
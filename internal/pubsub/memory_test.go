@@ -0,0 +1,66 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMemoryDeliversSynchronouslyOncePerQueueGroup(t *testing.T) {
+	m := NewMemory()
+	var fanout, replicaA, replicaB int
+	_ = m.Subscribe("order:created", func(context.Context, Message) error { fanout++; return nil })
+	_ = m.QueueSubscribe("order:created", "expiration", func(context.Context, Message) error { replicaA++; return nil })
+	_ = m.QueueSubscribe("order:created", "expiration", func(context.Context, Message) error { replicaB++; return nil })
+
+	for i := 0; i < 4; i++ {
+		if err := m.Publish(context.Background(), "order:created", []byte(`{}`)); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+
+	if fanout != 4 {
+		t.Errorf("plain subscriber got %d, want 4", fanout)
+	}
+	if replicaA != 2 || replicaB != 2 {
+		t.Errorf("queue members got %d and %d, want 2 each", replicaA, replicaB)
+	}
+	if n := len(m.Published("order:created")); n != 4 {
+		t.Errorf("recorded %d messages, want 4", n)
+	}
+}
+
+func TestMemoryHandlersMayPublish(t *testing.T) {
+	m := NewMemory()
+	_ = m.Subscribe("payment:created", func(ctx context.Context, _ Message) error {
+		return m.Publish(ctx, "order:completed", []byte(`{}`))
+	})
+	_ = m.Publish(context.Background(), "payment:created", []byte(`{}`))
+
+	msgs := m.Messages()
+	if len(msgs) != 2 || msgs[1].Subject != "order:completed" {
+		t.Fatalf("messages = %+v, want nested publish recorded", msgs)
+	}
+}
+
+func TestMemoryRedeliversFailedMessages(t *testing.T) {
+	m := NewMemory()
+	m.MaxDeliver = 3
+	var deliveries []int
+	_ = m.Subscribe("ticket:updated", func(_ context.Context, msg Message) error {
+		deliveries = append(deliveries, msg.Delivery)
+		if msg.Header["Content-Type"] != "application/json" {
+			t.Errorf("header = %v", msg.Header)
+		}
+		return errors.New("not yet")
+	})
+	_ = m.PublishMsg(context.Background(), Message{
+		Subject: "ticket:updated",
+		Data:    []byte(`{}`),
+		Header:  Header{"Content-Type": "application/json"},
+	})
+
+	if len(deliveries) != 3 || deliveries[0] != 1 || deliveries[2] != 3 {
+		t.Fatalf("deliveries = %v, want 1..3", deliveries)
+	}
+}
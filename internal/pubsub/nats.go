@@ -2,16 +2,34 @@ package pubsub
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
 	"time"
 
 	nats "github.com/nats-io/nats.go"
 )
 
 // NATSClient is a thin wrapper around nats.Conn implementing Publisher and Subscriber.
+//
+// Core NATS has no acknowledgements, so a failed handler is redelivered from
+// memory after a backoff, up to ten deliveries. Redeliveries pending when the
+// process exits are lost; use JetStream where that matters.
 type NATSClient struct {
 	conn *nats.Conn
+
+	maxDeliver int
+	backOff    []time.Duration
+	timeout    time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
+// ErrNotConnected is returned by NATSClient.PublishMsg while the connection
+// to the server is down.
+var ErrNotConnected = errors.New("not connected")
+
 // NewNATS connects to a NATS server at the given URL and returns a Client.
 // Example URL: nats://localhost:4222
 func NewNATS(url string, opts ...nats.Option) (Client, error) {
@@ -29,31 +47,89 @@ func NewNATS(url string, opts ...nats.Option) (Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &NATSClient{conn: conn}, nil
+	c := &NATSClient{conn: conn, maxDeliver: 10, backOff: defaultBackOff, timeout: 30 * time.Second}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	return c, nil
 }
 
 func (c *NATSClient) Publish(ctx context.Context, subject string, data []byte) error {
-	// Publish with context-aware deadline if present
+	return c.PublishMsg(ctx, Message{Subject: subject, Data: data})
+}
+
+// PublishMsg returns once the server has the message: it flushes within
+// ctx's deadline, or 5s without one. While the connection is down it fails
+// at once instead of queueing the message in the client's reconnect buffer,
+// which is lost if the process exits, so callers such as Spool can keep it.
+func (c *NATSClient) PublishMsg(ctx context.Context, m Message) error {
+	if status := c.conn.Status(); status != nats.CONNECTED {
+		return fmt.Errorf("nats publish %s: %w (%s)", m.Subject, ErrNotConnected, status)
+	}
+	msg := nats.NewMsg(m.Subject)
+	msg.Data = m.Data
+	for k, v := range m.Header {
+		msg.Header.Set(k, v)
+	}
+	if err := c.conn.PublishMsg(msg); err != nil {
+		return err
+	}
+	t := 5 * time.Second
 	if deadline, ok := ctx.Deadline(); ok {
-		// Use RequestWithContext style semantics by using Publish and Flush within deadline
-		c.conn.Publish(subject, data)
-		t := time.Until(deadline)
+		t = time.Until(deadline)
 		if t <= 0 {
 			t = time.Millisecond
 		}
-		return c.conn.FlushTimeout(t)
 	}
-	return c.conn.Publish(subject, data)
+	return c.conn.FlushTimeout(t)
 }
 
-func (c *NATSClient) Subscribe(subject string, handler func(msg []byte)) error {
+func (c *NATSClient) Subscribe(subject string, h Handler) error {
 	_, err := c.conn.Subscribe(subject, func(m *nats.Msg) {
-		handler(m.Data)
+		c.deliver(h, natsMessage(m))
 	})
 	return err
 }
 
+func (c *NATSClient) QueueSubscribe(subject, queue string, h Handler) error {
+	_, err := c.conn.QueueSubscribe(subject, queue, func(m *nats.Msg) {
+		c.deliver(h, natsMessage(m))
+	})
+	return err
+}
+
+func natsMessage(m *nats.Msg) Message {
+	msg := Message{Subject: m.Subject, Data: m.Data, Delivery: 1}
+	if len(m.Header) > 0 {
+		msg.Header = Header{}
+		for k := range m.Header {
+			msg.Header[k] = m.Header.Get(k)
+		}
+	}
+	return msg
+}
+
+// deliver runs h and schedules a redelivery if it fails.
+func (c *NATSClient) deliver(h Handler, m Message) {
+	if c.ctx.Err() != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.ctx, c.timeout)
+	err := invoke(ctx, h, m)
+	cancel()
+	if err == nil {
+		return
+	}
+	if m.Delivery >= c.maxDeliver && !errors.Is(err, ErrRetryLater) {
+		log.Printf("nats %s: giving up after %d deliveries: %v", m.Subject, m.Delivery, err)
+		return
+	}
+	delay := backoffFor(c.backOff, m.Delivery)
+	log.Printf("nats %s: delivery %d failed: %v (redelivering in %s)", m.Subject, m.Delivery, err, delay)
+	m.Delivery++
+	time.AfterFunc(delay, func() { c.deliver(h, m) })
+}
+
 func (c *NATSClient) Close() error {
+	c.cancel()
 	if c.conn != nil && !c.conn.IsClosed() {
 		c.conn.Drain()
 		c.conn.Close()
@@ -0,0 +1,150 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	nats "github.com/nats-io/nats.go"
+)
+
+func TestNATSQueueSubscribeDeliversOncePerGroup(t *testing.T) {
+	s := runJetStreamServer(t)
+
+	var handled atomic.Int32
+	for i := 0; i < 3; i++ {
+		c, err := NewNATS(s.ClientURL())
+		if err != nil {
+			t.Fatalf("NewNATS: %v", err)
+		}
+		defer c.Close()
+		if err := c.QueueSubscribe("payment:created", "orders", func(context.Context, Message) error { handled.Add(1); return nil }); err != nil {
+			t.Fatalf("QueueSubscribe: %v", err)
+		}
+		c.(*NATSClient).conn.Flush()
+	}
+
+	pub, err := NewNATS(s.ClientURL())
+	if err != nil {
+		t.Fatalf("NewNATS: %v", err)
+	}
+	defer pub.Close()
+	for i := 0; i < 10; i++ {
+		if err := pub.Publish(context.Background(), "payment:created", []byte(`{}`)); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+
+	waitFor(t, func() bool { return handled.Load() >= 10 })
+	time.Sleep(100 * time.Millisecond)
+	if n := handled.Load(); n != 10 {
+		t.Fatalf("handled %d messages across 3 replicas, want 10", n)
+	}
+}
+
+func TestNATSRedeliversWhenHandlerFails(t *testing.T) {
+	s := runJetStreamServer(t)
+	c, err := NewNATS(s.ClientURL())
+	if err != nil {
+		t.Fatalf("NewNATS: %v", err)
+	}
+	defer c.Close()
+	c.(*NATSClient).backOff = []time.Duration{10 * time.Millisecond}
+
+	var last atomic.Int32
+	if err := c.Subscribe("ticket:updated", func(ctx context.Context, m Message) error {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("handler context has no deadline")
+		}
+		last.Store(int32(m.Delivery))
+		if m.Delivery < 3 {
+			return errors.New("transient failure")
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	c.(*NATSClient).conn.Flush()
+	if err := c.Publish(context.Background(), "ticket:updated", []byte(`{}`)); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	waitFor(t, func() bool { return last.Load() == 3 })
+
+	time.Sleep(100 * time.Millisecond)
+	if n := last.Load(); n != 3 {
+		t.Fatalf("delivered %d times, want 3", n)
+	}
+}
+
+func TestNATSKeepsRedeliveringRetryLaterPastLimit(t *testing.T) {
+	s := runJetStreamServer(t)
+	c, err := NewNATS(s.ClientURL())
+	if err != nil {
+		t.Fatalf("NewNATS: %v", err)
+	}
+	defer c.Close()
+	c.(*NATSClient).backOff = []time.Duration{10 * time.Millisecond}
+	c.(*NATSClient).maxDeliver = 2
+
+	var last atomic.Int32
+	if err := c.Subscribe("ticket:updated", func(ctx context.Context, m Message) error {
+		last.Store(int32(m.Delivery))
+		if m.Delivery < 5 {
+			return fmt.Errorf("version gap: %w", ErrRetryLater)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	c.(*NATSClient).conn.Flush()
+	if err := c.Publish(context.Background(), "ticket:updated", []byte(`{}`)); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	waitFor(t, func() bool { return last.Load() == 5 })
+}
+
+func TestNATSSpoolKeepsMessagesPublishedDuringOutage(t *testing.T) {
+	s := runJetStreamServer(t)
+	port := s.Addr().(*net.TCPAddr).Port
+	c, err := NewNATS(s.ClientURL(), nats.ReconnectWait(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewNATS: %v", err)
+	}
+	spool, err := NewSpool(SpoolConfig{
+		Name:          "nats-outage",
+		Dir:           t.TempDir(),
+		Connect:       func() (Publisher, error) { return c, nil },
+		RetryInterval: 20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spool.Close()
+
+	// Once the server is gone the client only reconnects, and a publish
+	// must go to the spool rather than the client's reconnect buffer.
+	s.Shutdown()
+	waitFor(t, func() bool { return c.(*NATSClient).conn.Status() != nats.CONNECTED })
+	if err := spool.Publish(context.Background(), "ticket:updated", []byte(`{"id":"1"}`)); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if d := spool.Depth(); d != 1 {
+		t.Fatalf("spool depth during outage = %d, want 1", d)
+	}
+
+	back, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: port, NoLog: true, NoSigs: true})
+	if err != nil {
+		t.Fatalf("nats-server: %v", err)
+	}
+	go back.Start()
+	t.Cleanup(back.Shutdown)
+	if !back.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats-server not ready")
+	}
+	waitFor(t, func() bool { return spool.Depth() == 0 })
+}
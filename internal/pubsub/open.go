@@ -0,0 +1,49 @@
+package pubsub
+
+import "fmt"
+
+// Supported values for the PUBSUB_DRIVER setting.
+const (
+	DriverNATS      = "nats"
+	DriverJetStream = "jetstream"
+	DriverRedis     = "redis"
+)
+
+// Config selects and configures a broker implementation.
+type Config struct {
+	Driver   string   // "nats" (default), "jetstream" or "redis"
+	URL      string   // broker URL, e.g. nats://localhost:4222 or redis://localhost:6379
+	Service  string   // service name, used for durable consumers
+	Subjects []string // subjects to provision on durable brokers
+}
+
+// Open connects to the broker selected by cfg.Driver.
+func Open(cfg Config) (Client, error) {
+	switch cfg.Driver {
+	case "", DriverNATS:
+		return NewNATS(cfg.URL)
+	case DriverJetStream:
+		c, err := NewJetStream(cfg.URL, JetStreamConfig{Durable: cfg.Service, Subjects: cfg.Subjects})
+		if err != nil {
+			return nil, err // not a typed nil Client
+		}
+		return c, nil
+	case DriverRedis:
+		c, err := NewRedis(cfg.URL, RedisConfig{Group: cfg.Service})
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	default:
+		return nil, fmt.Errorf("pubsub: unknown driver %q", cfg.Driver)
+	}
+}
+
+// URLEnv names the environment variable holding the broker address for
+// driver: REDIS_URL for the Redis driver, NATS_URL otherwise.
+func URLEnv(driver string) string {
+	if driver == DriverRedis {
+		return "REDIS_URL"
+	}
+	return "NATS_URL"
+}
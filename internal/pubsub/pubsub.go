@@ -1,30 +1,97 @@
 package pubsub
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Header carries message metadata alongside the payload.
+type Header map[string]string
+
+// Message is a message as published or delivered.
+type Message struct {
+	Subject string
+	Data    []byte
+	Header  Header
+	// Delivery counts deliveries of this message, starting at 1; it is
+	// higher when the broker redelivers after a failed attempt.
+	Delivery int
+}
+
+// Handler processes one delivered message. ctx expires when the broker
+// would consider the message lost and redeliver it anyway. Returning an
+// error (or panicking) asks the broker to redeliver the message after a
+// backoff, up to its delivery limit.
+type Handler func(ctx context.Context, m Message) error
+
+// ErrRetryLater marks a handler error for a message that cannot be processed
+// until other messages have been, such as an event that arrived ahead of its
+// predecessors. Such a message is redelivered with the longest backoff for as
+// long as it keeps failing this way, past the delivery limit; only Memory,
+// which redelivers immediately, still stops at its limit.
+var ErrRetryLater = errors.New("pubsub: retry later")
 
 // Publisher is a minimal pub/sub publisher interface used by services.
 type Publisher interface {
 	Publish(ctx context.Context, subject string, data []byte) error
+	// PublishMsg publishes m.Data on m.Subject together with m.Header.
+	PublishMsg(ctx context.Context, m Message) error
 	Close() error
 }
 
 // Subscriber is a minimal subscriber interface.
 type Subscriber interface {
-	Subscribe(subject string, handler func(msg []byte)) error
+	Subscribe(subject string, h Handler) error
+	// QueueSubscribe delivers each message to only one member of the named
+	// queue group, so replicas of a service share the work instead of each
+	// processing every event. Use the service name as the queue.
+	QueueSubscribe(subject, queue string, h Handler) error
 	Close() error
 }
 
 // NewNoopPublisher returns a publisher that does nothing (useful for dev).
 func NewNoopPublisher() Publisher { return noop{} }
 
+// NewNoopClient returns a client that drops publishes and never delivers.
+func NewNoopClient() Client { return noop{} }
+
 type noop struct{}
 
 func (n noop) Publish(ctx context.Context, subject string, data []byte) error { return nil }
+func (n noop) PublishMsg(ctx context.Context, m Message) error                { return nil }
 func (n noop) Close() error                                                   { return nil }
-func (n noop) Subscribe(subject string, handler func(msg []byte)) error       { return nil }
+func (n noop) Subscribe(subject string, h Handler) error                      { return nil }
+func (n noop) QueueSubscribe(subject, queue string, h Handler) error          { return nil }
 
 // Client is a combined Publisher+Subscriber, useful when a service both publishes and consumes.
 type Client interface {
 	Publisher
 	Subscriber
 }
+
+// invoke runs h, turning a panic into an error so it is redelivered like one.
+func invoke(ctx context.Context, h Handler, m Message) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("handler panic: %v", rec)
+		}
+	}()
+	return h(ctx, m)
+}
+
+// backoffFor returns the redelivery delay after failed attempt n; the last
+// value in b repeats for later attempts.
+func backoffFor(b []time.Duration, attempt int) time.Duration {
+	if len(b) == 0 {
+		return 0
+	}
+	if attempt > len(b) {
+		attempt = len(b)
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+	return b[attempt-1]
+}
@@ -0,0 +1,348 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisConfig configures the Redis Streams client.
+type RedisConfig struct {
+	// Group names the service; it is the consumer group for Subscribe so a
+	// restarted service resumes where it left off.
+	Group string
+	// Consumer names this process within its groups (default
+	// group-hostname-random).
+	Consumer string
+	// MaxDeliver bounds delivery attempts per message (default 10), except
+	// for handler errors that wrap ErrRetryLater.
+	MaxDeliver int
+	// BackOff is the retry delay for each failed attempt; the last value
+	// repeats for later attempts.
+	BackOff []time.Duration
+	// AckWait is how long an entry may stay pending before another consumer
+	// reclaims it, e.g. after a crash (default 30s).
+	AckWait time.Duration
+	// MaxAge trims entries older than this when publishing (default 7 days).
+	MaxAge time.Duration
+	// Block is how long each XREADGROUP waits for new entries (default 2s).
+	Block time.Duration
+}
+
+// streamKeyPrefix keeps event streams apart from asynq's keys when both
+// share a Redis instance.
+const streamKeyPrefix = "events:"
+
+// RedisClient implements Client on top of Redis Streams: one stream per
+// subject, one consumer group per service (or queue), explicit XACK after the
+// handler returns, and reclaim of entries left pending by dead consumers.
+type RedisClient struct {
+	rdb *redis.Client
+	cfg RedisConfig
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewRedis connects to Redis at addr, given as host:port or a redis:// URL.
+func NewRedis(addr string, cfg RedisConfig) (*RedisClient, error) {
+	if cfg.Group == "" {
+		return nil, fmt.Errorf("redis: group name is required")
+	}
+	if cfg.Consumer == "" {
+		host, _ := os.Hostname()
+		cfg.Consumer = fmt.Sprintf("%s-%s-%s", cfg.Group, host, uuid.NewString()[:8])
+	}
+	if cfg.MaxDeliver <= 0 {
+		cfg.MaxDeliver = 10
+	}
+	if len(cfg.BackOff) == 0 {
+		cfg.BackOff = defaultBackOff
+	}
+	if cfg.AckWait <= 0 {
+		cfg.AckWait = 30 * time.Second
+	}
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = 7 * 24 * time.Hour
+	}
+	if cfg.Block <= 0 {
+		cfg.Block = 2 * time.Second
+	}
+
+	opts := &redis.Options{Addr: addr}
+	if strings.Contains(addr, "://") {
+		var err error
+		if opts, err = redis.ParseURL(addr); err != nil {
+			return nil, err
+		}
+	}
+	rdb := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		rdb.Close()
+		return nil, err
+	}
+
+	c := &RedisClient{rdb: rdb, cfg: cfg}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	return c, nil
+}
+
+// headerFieldPrefix marks entry fields that carry message headers.
+const headerFieldPrefix = "h:"
+
+func (c *RedisClient) Publish(ctx context.Context, subject string, data []byte) error {
+	return c.PublishMsg(ctx, Message{Subject: subject, Data: data})
+}
+
+func (c *RedisClient) PublishMsg(ctx context.Context, m Message) error {
+	values := map[string]any{"data": m.Data}
+	for k, v := range m.Header {
+		values[headerFieldPrefix+k] = v
+	}
+	minID := strconv.FormatInt(time.Now().Add(-c.cfg.MaxAge).UnixMilli(), 10)
+	return c.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: streamKeyPrefix + m.Subject,
+		MinID:  minID,
+		Approx: true,
+		Values: values,
+	}).Err()
+}
+
+// Subscribe consumes subject in the consumer group named after the service.
+// Replicas of a service share the group and so already split the work. A
+// handler that fails is retried after the configured backoff; its context
+// expires with the ack wait, after which another consumer may reclaim it.
+func (c *RedisClient) Subscribe(subject string, h Handler) error {
+	return c.consume(subject, c.cfg.Group, h)
+}
+
+// QueueSubscribe consumes subject in the consumer group named after queue,
+// so every client using the same queue competes for the same messages.
+func (c *RedisClient) QueueSubscribe(subject, queue string, h Handler) error {
+	return c.consume(subject, queue, h)
+}
+
+func (c *RedisClient) consume(subject, group string, h Handler) error {
+	s := &redisSub{
+		c:       c,
+		subject: subject,
+		stream:  streamKeyPrefix + subject,
+		group:   group,
+		handler: h,
+		retries: map[string]time.Time{},
+	}
+	ctx, cancel := context.WithTimeout(c.ctx, 5*time.Second)
+	defer cancel()
+	// Like the JetStream consumers, a new group starts from the beginning of
+	// the retained stream.
+	err := c.rdb.XGroupCreateMkStream(ctx, s.stream, group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("redis: group %s on %s: %w", group, subject, err)
+	}
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		s.run(c.ctx)
+	}()
+	return nil
+}
+
+// redisSub is one subject consumed in one group. Its loop is the only
+// goroutine touching retries.
+type redisSub struct {
+	c       *RedisClient
+	subject string
+	stream  string
+	group   string
+	handler Handler
+	retries map[string]time.Time // entry ID -> when to retry
+}
+
+func (s *redisSub) run(ctx context.Context) {
+	for ctx.Err() == nil {
+		s.retryDue(ctx)
+		s.reclaim(ctx)
+
+		res, err := s.c.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    s.group,
+			Consumer: s.c.cfg.Consumer,
+			Streams:  []string{s.stream, ">"},
+			Count:    16,
+			Block:    s.blockFor(),
+		}).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) || ctx.Err() != nil {
+				continue
+			}
+			log.Printf("redis %s: read: %v", s.subject, err)
+			sleep(ctx, time.Second)
+			continue
+		}
+		for _, st := range res {
+			for _, m := range st.Messages {
+				s.handle(ctx, m, 1)
+			}
+		}
+	}
+}
+
+// blockFor bounds the XREADGROUP wait so due retries are not held up.
+func (s *redisSub) blockFor() time.Duration {
+	d := s.c.cfg.Block
+	for _, at := range s.retries {
+		if until := time.Until(at); until < d {
+			d = until
+		}
+	}
+	if d < time.Millisecond {
+		d = time.Millisecond // BLOCK 0 would wait forever
+	}
+	return d
+}
+
+// retryDue redelivers this consumer's failed entries whose backoff elapsed.
+func (s *redisSub) retryDue(ctx context.Context) {
+	now := time.Now()
+	var ids []string
+	for id, at := range s.retries {
+		if !at.After(now) {
+			ids = append(ids, id)
+		}
+	}
+	for _, id := range ids {
+		delete(s.retries, id)
+		s.claim(ctx, 0, id)
+	}
+}
+
+// reclaim takes over entries that have been pending longer than AckWait, in
+// any consumer of the group; typically their consumer died mid-handler.
+func (s *redisSub) reclaim(ctx context.Context) {
+	pending, err := s.c.rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: s.stream,
+		Group:  s.group,
+		Idle:   s.c.cfg.AckWait,
+		Start:  "-",
+		End:    "+",
+		Count:  16,
+	}).Result()
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("redis %s: pending: %v", s.subject, err)
+		}
+		return
+	}
+	for _, p := range pending {
+		if _, scheduled := s.retries[p.ID]; scheduled {
+			continue
+		}
+		if int(p.RetryCount) >= s.c.cfg.MaxDeliver {
+			log.Printf("redis %s: giving up on %s after %d deliveries", s.subject, p.ID, p.RetryCount)
+			s.ack(ctx, p.ID)
+			continue
+		}
+		s.claim(ctx, s.c.cfg.AckWait, p.ID)
+	}
+}
+
+// claim moves id to this consumer and handles it. minIdle guards against
+// taking an entry another consumer has just picked up.
+func (s *redisSub) claim(ctx context.Context, minIdle time.Duration, id string) {
+	msgs, err := s.c.rdb.XClaim(ctx, &redis.XClaimArgs{
+		Stream:   s.stream,
+		Group:    s.group,
+		Consumer: s.c.cfg.Consumer,
+		MinIdle:  minIdle,
+		Messages: []string{id},
+	}).Result()
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("redis %s: claim %s: %v", s.subject, id, err)
+		}
+		return
+	}
+	for _, m := range msgs {
+		s.handle(ctx, m, s.deliveries(ctx, m.ID))
+	}
+}
+
+// deliveries returns how many times id has been delivered, this time included.
+func (s *redisSub) deliveries(ctx context.Context, id string) int {
+	p, err := s.c.rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: s.stream,
+		Group:  s.group,
+		Start:  id,
+		End:    id,
+		Count:  1,
+	}).Result()
+	if err != nil || len(p) == 0 {
+		return 1
+	}
+	return int(p[0].RetryCount)
+}
+
+func (s *redisSub) handle(ctx context.Context, m redis.XMessage, attempt int) {
+	msg := Message{Subject: s.subject, Delivery: attempt}
+	for k, v := range m.Values {
+		str, _ := v.(string)
+		switch {
+		case k == "data":
+			msg.Data = []byte(str)
+		case strings.HasPrefix(k, headerFieldPrefix):
+			if msg.Header == nil {
+				msg.Header = Header{}
+			}
+			msg.Header[strings.TrimPrefix(k, headerFieldPrefix)] = str
+		}
+	}
+
+	hctx, cancel := context.WithTimeout(ctx, s.c.cfg.AckWait)
+	err := invoke(hctx, s.handler, msg)
+	cancel()
+	if err == nil {
+		s.ack(ctx, m.ID)
+		return
+	}
+	if attempt >= s.c.cfg.MaxDeliver && !errors.Is(err, ErrRetryLater) {
+		log.Printf("redis %s: giving up on %s after %d deliveries: %v", s.subject, m.ID, attempt, err)
+		s.ack(ctx, m.ID)
+		return
+	}
+	delay := backoffFor(s.c.cfg.BackOff, attempt)
+	log.Printf("redis %s: delivery %d of %s failed: %v (redelivering in %s)", s.subject, attempt, m.ID, err, delay)
+	s.retries[m.ID] = time.Now().Add(delay)
+}
+
+func (s *redisSub) ack(ctx context.Context, id string) {
+	if err := s.c.rdb.XAck(ctx, s.stream, s.group, id).Err(); err != nil && ctx.Err() == nil {
+		log.Printf("redis %s: ack %s: %v", s.subject, id, err)
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
+
+func (c *RedisClient) Close() error {
+	c.cancel()
+	c.wg.Wait()
+	return c.rdb.Close()
+}
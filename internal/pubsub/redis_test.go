@@ -0,0 +1,138 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newTestRedis(t *testing.T, addr, group string, cfg RedisConfig) *RedisClient {
+	t.Helper()
+	cfg.Group = group
+	if cfg.BackOff == nil {
+		cfg.BackOff = []time.Duration{50 * time.Millisecond}
+	}
+	cfg.Block = 50 * time.Millisecond
+	c, err := NewRedis(addr, cfg)
+	if err != nil {
+		t.Fatalf("NewRedis: %v", err)
+	}
+	return c
+}
+
+func TestRedisDeliversEventsPublishedWhileConsumerIsDown(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	first := newTestRedis(t, mr.Addr(), "orders", RedisConfig{})
+	var got atomic.Int32
+	if err := first.Subscribe("ticket:created", func(context.Context, Message) error { got.Add(1); return nil }); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if err := first.Publish(context.Background(), "ticket:created", []byte(`{"id":"1"}`)); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	waitFor(t, func() bool { return got.Load() == 1 })
+	first.Close()
+
+	// Published while the "orders" group has no consumers.
+	pub := newTestRedis(t, mr.Addr(), "tickets", RedisConfig{})
+	defer pub.Close()
+	if err := pub.Publish(context.Background(), "ticket:created", []byte(`{"id":"2"}`)); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	second := newTestRedis(t, mr.Addr(), "orders", RedisConfig{})
+	defer second.Close()
+	var payload atomic.Value
+	if err := second.Subscribe("ticket:created", func(_ context.Context, m Message) error { payload.Store(string(m.Data)); return nil }); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	waitFor(t, func() bool { return payload.Load() != nil })
+	if p := payload.Load().(string); p != `{"id":"2"}` {
+		t.Fatalf("resumed consumer got %s, want only the missed event", p)
+	}
+}
+
+func TestRedisRedeliversWhenHandlerFails(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newTestRedis(t, mr.Addr(), "orders", RedisConfig{})
+	defer c.Close()
+
+	var attempts, lastDelivery atomic.Int32
+	if err := c.Subscribe("ticket:updated", func(ctx context.Context, m Message) error {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("handler context has no deadline")
+		}
+		if m.Header["Content-Type"] != "application/json" {
+			t.Errorf("header = %v", m.Header)
+		}
+		lastDelivery.Store(int32(m.Delivery))
+		switch attempts.Add(1) {
+		case 1:
+			return errors.New("transient failure")
+		case 2:
+			panic("transient failure")
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if err := c.PublishMsg(context.Background(), Message{
+		Subject: "ticket:updated",
+		Data:    []byte(`{}`),
+		Header:  Header{"Content-Type": "application/json"},
+	}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	waitFor(t, func() bool { return attempts.Load() == 3 })
+
+	time.Sleep(200 * time.Millisecond)
+	if n := attempts.Load(); n != 3 {
+		t.Fatalf("handler ran %d times after success, want 3", n)
+	}
+	if d := lastDelivery.Load(); d != 3 {
+		t.Fatalf("last delivery count = %d, want 3", d)
+	}
+}
+
+func TestRedisReclaimsEntriesFromDeadConsumer(t *testing.T) {
+	mr := miniredis.RunT(t)
+	ctx := context.Background()
+
+	// The first consumer reads the entry and dies before acking it.
+	dead := newTestRedis(t, mr.Addr(), "orders", RedisConfig{Consumer: "dead"})
+	var read atomic.Int32
+	if err := dead.QueueSubscribe("order:created", "orders", func(context.Context, Message) error {
+		read.Add(1)
+		select {} // never returns
+	}); err != nil {
+		t.Fatalf("QueueSubscribe: %v", err)
+	}
+	if err := dead.Publish(ctx, "order:created", []byte(`{"id":"o1"}`)); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	waitFor(t, func() bool { return read.Load() == 1 })
+
+	live := newTestRedis(t, mr.Addr(), "orders", RedisConfig{Consumer: "live", AckWait: 100 * time.Millisecond})
+	defer live.Close()
+	var payload atomic.Value
+	if err := live.QueueSubscribe("order:created", "orders", func(_ context.Context, m Message) error { payload.Store(string(m.Data)); return nil }); err != nil {
+		t.Fatalf("QueueSubscribe: %v", err)
+	}
+	waitFor(t, func() bool { return payload.Load() != nil })
+	if p := payload.Load().(string); p != `{"id":"o1"}` {
+		t.Fatalf("reclaimed %s", p)
+	}
+
+	pending, err := live.rdb.XPending(ctx, streamKeyPrefix+"order:created", "orders").Result()
+	if err != nil {
+		t.Fatalf("XPending: %v", err)
+	}
+	if pending.Count != 0 {
+		t.Fatalf("%d entries still pending after reclaim", pending.Count)
+	}
+}
@@ -0,0 +1,323 @@
+package pubsub
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SpoolDepth counts the messages waiting in each spool, by spool name. It is
+// published at /debug/vars as pubsub_spool_depth; a depth that keeps rising
+// means the broker has been unreachable for a while.
+var SpoolDepth = expvar.NewMap("pubsub_spool_depth")
+
+// ErrSpoolFull is returned by a Spool that cannot buffer another message.
+var ErrSpoolFull = errors.New("pubsub: spool full")
+
+// SpoolConfig configures a Spool.
+type SpoolConfig struct {
+	// Name identifies the spool in SpoolDepth, usually the service name.
+	Name string
+	// Dir holds the spool files; it is created if missing.
+	Dir string
+	// MaxBytes bounds the spool file (default 64 MiB).
+	MaxBytes int64
+	// Connect dials the broker. It is retried every RetryInterval (default
+	// 2s) until it succeeds.
+	Connect       func() (Publisher, error)
+	RetryInterval time.Duration
+}
+
+// Spool is a Publisher that buffers messages on disk while the broker is
+// unreachable and publishes them in order once it is back.
+//
+// A message is published directly while the spool is empty. When the broker
+// is not connected yet or a publish fails, the message is appended to the
+// spool file instead, and so is every later message until a background loop
+// has drained the spool. Messages therefore leave in publish order, and a
+// spool left behind by a crash is drained after the next start. A message
+// may be published twice if the process dies between publishing it and
+// recording that, so consumers must be idempotent as with any redelivery.
+type Spool struct {
+	cfg SpoolConfig
+
+	mu     sync.Mutex
+	pub    Publisher // nil until connected
+	f      *os.File
+	size   int64 // bytes in the spool file
+	offset int64 // start of the first unsent record
+	depth  int
+	metric *expvar.Int
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// spooled is one line of the spool file.
+type spooled struct {
+	Subject string `json:"subject"`
+	Header  Header `json:"header,omitempty"`
+	Data    []byte `json:"data"`
+}
+
+// NewSpool opens the spool in cfg.Dir, connects to the broker if it can and
+// starts draining the spool in the background.
+func NewSpool(cfg SpoolConfig) (*Spool, error) {
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = 64 << 20
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = 2 * time.Second
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(cfg.Dir, "spool.jsonl"), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s := &Spool{cfg: cfg, f: f, metric: new(expvar.Int), done: make(chan struct{})}
+	SpoolDepth.Set(cfg.Name, s.metric)
+	if err := s.load(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("pubsub: load spool %s: %w", cfg.Dir, err)
+	}
+	if s.depth > 0 {
+		log.Printf("pubsub spool %s: %d message(s) left from a previous run", cfg.Name, s.depth)
+	}
+
+	if p, err := cfg.Connect(); err == nil {
+		s.pub = p
+	} else {
+		log.Printf("warn: pubsub spool %s: broker unavailable (%v), spooling to %s", cfg.Name, err, cfg.Dir)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	go s.run(ctx)
+	return s, nil
+}
+
+// load reads the saved offset and counts the unsent records, dropping a
+// partial record written by a crash.
+func (s *Spool) load() error {
+	if b, err := os.ReadFile(s.offsetPath()); err == nil {
+		s.offset, _ = strconv.ParseInt(string(b), 10, 64)
+	}
+	info, err := s.f.Stat()
+	if err != nil {
+		return err
+	}
+	if s.offset > info.Size() {
+		s.offset = 0
+	}
+	r := bufio.NewReader(io.NewSectionReader(s.f, s.offset, info.Size()-s.offset))
+	end := s.offset
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			break // io.EOF, possibly after a partial record
+		}
+		end += int64(len(line))
+		s.depth++
+	}
+	if end != info.Size() {
+		if err := s.f.Truncate(end); err != nil {
+			return err
+		}
+	}
+	s.size = end
+	s.metric.Set(int64(s.depth))
+	return nil
+}
+
+func (s *Spool) offsetPath() string { return filepath.Join(s.cfg.Dir, "spool.offset") }
+
+// Depth returns the number of messages waiting in the spool.
+func (s *Spool) Depth() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.depth
+}
+
+func (s *Spool) Publish(ctx context.Context, subject string, data []byte) error {
+	return s.PublishMsg(ctx, Message{Subject: subject, Data: data})
+}
+
+// PublishMsg publishes m, or spools it when the broker is unavailable or
+// earlier messages are still spooled. It fails only if the spool is full or
+// cannot be written.
+func (s *Spool) PublishMsg(ctx context.Context, m Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.depth == 0 && s.pub != nil {
+		err := s.pub.PublishMsg(ctx, m)
+		if err == nil {
+			return nil
+		}
+		log.Printf("warn: pubsub spool %s: publish %s failed (%v), spooling", s.cfg.Name, m.Subject, err)
+	}
+	return s.append(m)
+}
+
+func (s *Spool) append(m Message) error {
+	line, err := json.Marshal(spooled{Subject: m.Subject, Header: m.Header, Data: m.Data})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if s.size+int64(len(line)) > s.cfg.MaxBytes && s.offset > 0 {
+		if err := s.compact(); err != nil {
+			return err
+		}
+	}
+	if s.size+int64(len(line)) > s.cfg.MaxBytes {
+		return fmt.Errorf("%w: %s holds %d message(s)", ErrSpoolFull, s.cfg.Name, s.depth)
+	}
+	if _, err := s.f.WriteAt(line, s.size); err != nil {
+		return err
+	}
+	if err := s.f.Sync(); err != nil {
+		return err
+	}
+	s.size += int64(len(line))
+	s.depth++
+	s.metric.Set(int64(s.depth))
+	return nil
+}
+
+// compact drops the records already sent from the front of the file.
+func (s *Spool) compact() error {
+	rest := make([]byte, s.size-s.offset)
+	if _, err := s.f.ReadAt(rest, s.offset); err != nil {
+		return err
+	}
+	if _, err := s.f.WriteAt(rest, 0); err != nil {
+		return err
+	}
+	if err := s.f.Truncate(int64(len(rest))); err != nil {
+		return err
+	}
+	s.size, s.offset = int64(len(rest)), 0
+	return s.saveOffset()
+}
+
+func (s *Spool) saveOffset() error {
+	return os.WriteFile(s.offsetPath(), []byte(strconv.FormatInt(s.offset, 10)), 0o644)
+}
+
+func (s *Spool) run(ctx context.Context) {
+	defer close(s.done)
+	t := time.NewTicker(s.cfg.RetryInterval)
+	defer t.Stop()
+	for {
+		if err := s.connect(); err == nil {
+			if err := s.drain(ctx); err != nil {
+				log.Printf("warn: pubsub spool %s: %v; %d message(s) spooled", s.cfg.Name, err, s.Depth())
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// connect dials the broker if not connected yet, without holding the lock
+// so publishes keep spooling meanwhile.
+func (s *Spool) connect() error {
+	s.mu.Lock()
+	connected := s.pub != nil
+	s.mu.Unlock()
+	if connected {
+		return nil
+	}
+	p, err := s.cfg.Connect()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.pub = p
+	s.mu.Unlock()
+	log.Printf("pubsub spool %s: broker connected", s.cfg.Name)
+	return nil
+}
+
+// drain publishes spooled messages in order until the spool is empty or a
+// publish fails.
+func (s *Spool) drain(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+		if s.depth == 0 {
+			err := s.reset()
+			s.mu.Unlock()
+			return err
+		}
+		err := s.sendNext(ctx)
+		s.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+func (s *Spool) sendNext(ctx context.Context) error {
+	r := bufio.NewReader(io.NewSectionReader(s.f, s.offset, s.size-s.offset))
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return fmt.Errorf("read spool: %w", err)
+	}
+	var m spooled
+	if err := json.Unmarshal(line, &m); err != nil {
+		log.Printf("pubsub spool %s: dropping unreadable record: %v", s.cfg.Name, err)
+	} else if err := s.pub.PublishMsg(ctx, Message{Subject: m.Subject, Header: m.Header, Data: m.Data}); err != nil {
+		return fmt.Errorf("publish %s: %w", m.Subject, err)
+	}
+	s.offset += int64(len(line))
+	s.depth--
+	s.metric.Set(int64(s.depth))
+	return s.saveOffset()
+}
+
+// reset empties a fully drained spool file.
+func (s *Spool) reset() error {
+	if s.size == 0 {
+		return nil
+	}
+	if err := s.f.Truncate(0); err != nil {
+		return err
+	}
+	s.size, s.offset = 0, 0
+	return s.saveOffset()
+}
+
+// Close stops draining and closes the broker connection. Messages still
+// spooled are published after the next start.
+func (s *Spool) Close() error {
+	s.cancel()
+	<-s.done
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var err error
+	if s.pub != nil {
+		err = s.pub.Close()
+	}
+	if cerr := s.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,139 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// broker is a Memory that can be taken down.
+type broker struct {
+	*Memory
+	mu   sync.Mutex
+	down bool
+}
+
+func (b *broker) setDown(down bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.down = down
+}
+
+func (b *broker) connect() (Publisher, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.down {
+		return nil, errors.New("connection refused")
+	}
+	return b, nil
+}
+
+func (b *broker) PublishMsg(ctx context.Context, m Message) error {
+	b.mu.Lock()
+	down := b.down
+	b.mu.Unlock()
+	if down {
+		return errors.New("broker unavailable")
+	}
+	return b.Memory.PublishMsg(ctx, m)
+}
+
+func (b *broker) Close() error { return nil }
+
+func published(b *broker) string {
+	var out []string
+	for _, m := range b.Messages() {
+		out = append(out, string(m.Data))
+	}
+	return strings.Join(out, ",")
+}
+
+func TestSpoolBuffersUntilBrokerIsBackAndKeepsOrder(t *testing.T) {
+	ctx := context.Background()
+	b := &broker{Memory: NewMemory(), down: true}
+	s, err := NewSpool(SpoolConfig{Name: "test", Dir: t.TempDir(), Connect: b.connect, RetryInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for i := 1; i <= 3; i++ {
+		if err := s.PublishMsg(ctx, Message{Subject: "user:created", Data: []byte(fmt.Sprint(i)), Header: Header{"Content-Type": "application/json"}}); err != nil {
+			t.Fatalf("publish %d: %v", i, err)
+		}
+	}
+	if s.Depth() != 3 || SpoolDepth.Get("test").String() != "3" {
+		t.Fatalf("depth = %d (expvar %s), want 3", s.Depth(), SpoolDepth.Get("test"))
+	}
+
+	b.setDown(false)
+	waitFor(t, func() bool { return s.Depth() == 0 })
+	if got := published(b); got != "1,2,3" {
+		t.Fatalf("published %q, want 1,2,3", got)
+	}
+	if h := b.Messages()[0].Header["Content-Type"]; h != "application/json" {
+		t.Errorf("header lost: %q", h)
+	}
+
+	// A failed publish spools that message and everything after it until
+	// the spool is drained again.
+	b.setDown(true)
+	_ = s.Publish(ctx, "user:created", []byte("4"))
+	b.setDown(false)
+	_ = s.Publish(ctx, "user:created", []byte("5"))
+	waitFor(t, func() bool { return s.Depth() == 0 })
+	_ = s.Publish(ctx, "user:created", []byte("6"))
+	if got := published(b); got != "1,2,3,4,5,6" {
+		t.Fatalf("published %q, want 1..6 in order", got)
+	}
+}
+
+func TestSpoolSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	b := &broker{Memory: NewMemory(), down: true}
+	cfg := SpoolConfig{Name: "restart", Dir: dir, Connect: b.connect, RetryInterval: 10 * time.Millisecond}
+
+	s, err := NewSpool(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Publish(ctx, "user:created", []byte("a"))
+	_ = s.Publish(ctx, "user:created", []byte("b"))
+	_ = s.Close()
+
+	b.setDown(false)
+	s, err = NewSpool(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	waitFor(t, func() bool { return s.Depth() == 0 })
+	if got := published(b); got != "a,b" {
+		t.Fatalf("published %q after restart, want a,b", got)
+	}
+}
+
+func TestSpoolIsBounded(t *testing.T) {
+	b := &broker{Memory: NewMemory(), down: true}
+	s, err := NewSpool(SpoolConfig{Name: "bounded", Dir: t.TempDir(), MaxBytes: 200, Connect: b.connect, RetryInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var err2 error
+	for i := 0; i < 10 && err2 == nil; i++ {
+		err2 = s.Publish(context.Background(), "user:created", []byte("0123456789"))
+	}
+	if !errors.Is(err2, ErrSpoolFull) {
+		t.Fatalf("err = %v, want ErrSpoolFull", err2)
+	}
+	if d := s.Depth(); d == 0 || d >= 10 {
+		t.Errorf("depth = %d", d)
+	}
+}
@@ -0,0 +1,44 @@
+// Package replica decides whether a versioned event may be applied to a
+// local read model of another service's aggregate.
+//
+// Producers bump an aggregate's version by one per event, so a replica at
+// version n can only apply version n+1. Older versions are duplicates or
+// stale and are dropped; newer ones mean an event is missing or still in
+// flight, so the delivery fails and the broker redelivers it later.
+package replica
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+)
+
+// ErrVersionGap is returned for an event that arrived ahead of its
+// predecessors.
+var ErrVersionGap = errors.New("replica: version gap")
+
+// Gaps counts version gaps per replica table. It is published with the other
+// expvars at /debug/vars as replica_version_gaps; a count that keeps rising
+// means events are being lost rather than reordered.
+var Gaps = expvar.NewMap("replica_version_gaps")
+
+// Check reports whether the event with version incoming applies to row id of
+// table, whose stored version is stored (found is false if the row does not
+// exist yet). A missing row only accepts the aggregate's first version, 0.
+func Check(table, id string, stored int, found bool, incoming int) (bool, error) {
+	next := 0
+	if found {
+		next = stored + 1
+	}
+	switch {
+	case incoming == next:
+		return true, nil
+	case incoming < next:
+		return false, nil
+	default:
+		Gaps.Add(table, 1)
+		log.Printf("%s %s: version %d arrived before %d", table, id, incoming, next)
+		return false, fmt.Errorf("%w: %s %s expects version %d, got %d", ErrVersionGap, table, id, next, incoming)
+	}
+}
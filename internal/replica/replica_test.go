@@ -0,0 +1,42 @@
+package replica
+
+import (
+	"errors"
+	"expvar"
+	"testing"
+)
+
+func gaps(table string) int64 {
+	if v, ok := Gaps.Get(table).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func TestCheck(t *testing.T) {
+	cases := []struct {
+		name     string
+		stored   int
+		found    bool
+		incoming int
+		apply    bool
+		gap      bool
+	}{
+		{"first version of new row", 0, false, 0, true, false},
+		{"later version of missing row", 0, false, 2, false, true},
+		{"next version", 3, true, 4, true, false},
+		{"duplicate", 3, true, 3, false, false},
+		{"stale", 3, true, 1, false, false},
+		{"skips a version", 3, true, 5, false, true},
+	}
+	for _, c := range cases {
+		before := gaps("test_replica")
+		apply, err := Check("test_replica", "id", c.stored, c.found, c.incoming)
+		if apply != c.apply || errors.Is(err, ErrVersionGap) != c.gap {
+			t.Errorf("%s: apply=%v err=%v", c.name, apply, err)
+		}
+		if c.gap && gaps("test_replica") != before+1 {
+			t.Errorf("%s: gap not counted", c.name)
+		}
+	}
+}
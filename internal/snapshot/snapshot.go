@@ -0,0 +1,108 @@
+// Package snapshot serves and reads paginated snapshots of a service's
+// aggregates. Other services use them to bootstrap their replicas before
+// they start consuming events.
+//
+// Pages are keyset-paginated: each page carries the cursor of the next one,
+// and a page without a cursor is the last.
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Page sizes accepted by Params.
+const (
+	DefaultLimit = 500
+	MaxLimit     = 1000
+)
+
+// Page is one page of a snapshot.
+type Page[T any] struct {
+	Items []T `json:"items"`
+	// Next is the cursor for the following page; empty on the last page.
+	Next string `json:"next,omitempty"`
+}
+
+// Params reads the "after" cursor and "limit" page size from r's query.
+func Params(r *http.Request) (after string, limit int) {
+	q := r.URL.Query()
+	limit, _ = strconv.Atoi(q.Get("limit"))
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	return q.Get("after"), limit
+}
+
+// NewPage wraps items fetched with limit. A full page gets the key of its
+// last item as the cursor for the next.
+func NewPage[T any](items []T, limit int, key func(T) string) Page[T] {
+	p := Page[T]{Items: items}
+	if p.Items == nil {
+		p.Items = []T{}
+	}
+	if len(items) == limit && limit > 0 {
+		p.Next = key(items[len(items)-1])
+	}
+	return p
+}
+
+// Write encodes p as the response.
+func Write[T any](w http.ResponseWriter, p Page[T]) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// Fetch reads every page of the snapshot at endpoint, passing each page's
+// items to fn in order. token is sent as a bearer token when set.
+func Fetch[T any](ctx context.Context, endpoint, token string, fn func(items []T) error) error {
+	after := ""
+	for {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return err
+		}
+		q := u.Query()
+		if after != "" {
+			q.Set("after", after)
+		}
+		u.RawQuery = q.Encode()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return err
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		var page Page[T]
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return fmt.Errorf("snapshot %s: %s", endpoint, resp.Status)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("snapshot %s: %w", endpoint, err)
+		}
+
+		if err := fn(page.Items); err != nil {
+			return err
+		}
+		if page.Next == "" {
+			return nil
+		}
+		after = page.Next
+	}
+}
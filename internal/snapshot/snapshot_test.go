@@ -0,0 +1,51 @@
+package snapshot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	cmw "github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/middleware"
+)
+
+type item struct {
+	ID string `json:"id"`
+}
+
+func TestFetchWalksEveryPage(t *testing.T) {
+	ids := []string{"a", "b", "c", "d", "e"}
+	srv := httptest.NewServer(cmw.RequireInternalToken("secret")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		after, _ := Params(r)
+		i := sort.SearchStrings(ids, after)
+		if i < len(ids) && ids[i] == after {
+			i++
+		}
+		var items []item
+		for ; i < len(ids) && len(items) < 2; i++ {
+			items = append(items, item{ID: ids[i]})
+		}
+		Write(w, NewPage(items, 2, func(it item) string { return it.ID }))
+	})))
+	defer srv.Close()
+
+	var got []string
+	err := Fetch(context.Background(), srv.URL, "secret", func(items []item) error {
+		for _, it := range items {
+			got = append(got, it.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if strings.Join(got, "") != "abcde" {
+		t.Fatalf("fetched %v", got)
+	}
+
+	if err := Fetch(context.Background(), srv.URL, "wrong", func([]item) error { return nil }); err == nil {
+		t.Fatal("fetch with wrong token succeeded")
+	}
+}
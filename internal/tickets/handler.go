@@ -2,9 +2,16 @@ package tickets
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 
+	"github.com/google/uuid"
+
+	apperr "github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/errors"
 	cmw "github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/middleware"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/snapshot"
 )
 
 type HTTPHandler struct{ svc *Service }
@@ -12,13 +19,19 @@ type HTTPHandler struct{ svc *Service }
 func NewHTTPHandler(s *Service) *HTTPHandler { return &HTTPHandler{svc: s} }
 
 type createReq struct {
-	Title string `json:"title"`
-	Price int64  `json:"price"`
+	EventID     string `json:"eventId"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Price       int64  `json:"price"`
+	// Quantity is the number of identical units for general admission;
+	// 0 lists a single ticket.
+	Quantity int `json:"quantity"`
 }
 type updateReq struct {
-	Title   string `json:"title"`
-	Price   int64  `json:"price"`
-	Version int    `json:"version"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Price       int64  `json:"price"`
+	Version     int    `json:"version"`
 }
 
 func (h *HTTPHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -27,12 +40,33 @@ func (h *HTTPHandler) Create(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid payload", http.StatusBadRequest)
 		return
 	}
+	bad := map[string]string{}
+	// eventId is optional; tickets without one belong to no event.
+	if req.EventID != "" {
+		if _, err := uuid.Parse(req.EventID); err != nil {
+			bad["eventId"] = "must be the ID of an event"
+		}
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+	if req.Quantity < 1 || req.Quantity > MaxQuantity {
+		bad["quantity"] = fmt.Sprintf("must be between 1 and %d", MaxQuantity)
+	}
+	if len(bad) > 0 {
+		cmw.JSONError(w, apperr.NewValidation("invalid payload", bad))
+		return
+	}
 	cu := cmw.GetCurrentUser(r.Context())
 	if cu == nil {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
-	t, err := h.svc.Create(r.Context(), req.Title, req.Price, cu.ID)
+	t, err := h.svc.Create(r.Context(), req.EventID, req.Title, req.Description, req.Price, req.Quantity, cu.ID)
+	if errors.Is(err, ErrUnknownEvent) {
+		cmw.JSONError(w, apperr.NewValidation("invalid payload", map[string]string{"eventId": err.Error()}))
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -57,15 +91,60 @@ func (h *HTTPHandler) Update(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
-	t, err := h.svc.Update(r.Context(), id, req.Version, req.Title, req.Price, cu.ID)
+	t, err := h.svc.Update(r.Context(), id, req.Version, req.Title, req.Description, req.Price, cu.ID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		cmw.JSONError(w, updateError(err))
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(t)
 }
 
+// Delete withdraws the ticket given by id. Only its seller can, and only
+// while no order holds or has bought it.
+func (h *HTTPHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "missing id", http.StatusBadRequest)
+		return
+	}
+	cu := cmw.GetCurrentUser(r.Context())
+	if cu == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := h.svc.Delete(r.Context(), id, cu.ID); err != nil {
+		cmw.JSONError(w, updateError(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Codes of the 409 responses to a rejected update or withdrawal.
+const (
+	CodeVersionConflict = "version_conflict"
+	CodeTicketReserved  = "ticket_reserved"
+	CodeTicketSold      = "ticket_sold"
+)
+
+// updateError maps a repository error from Update or Delete to its HTTP
+// response.
+func updateError(err error) error {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return apperr.NewNotFound(err.Error())
+	case errors.Is(err, ErrNotOwner):
+		return apperr.NewForbidden(err.Error())
+	case errors.Is(err, ErrVersionConflict):
+		return apperr.WithCode(apperr.NewConflict(err.Error()), CodeVersionConflict)
+	case errors.Is(err, ErrTicketReserved):
+		return apperr.WithCode(apperr.NewConflict(err.Error()), CodeTicketReserved)
+	case errors.Is(err, ErrTicketSold):
+		return apperr.WithCode(apperr.NewConflict(err.Error()), CodeTicketSold)
+	}
+	return err
+}
+
 func (h *HTTPHandler) Show(w http.ResponseWriter, r *http.Request) {
 	id := r.URL.Query().Get("id")
 	if id == "" {
@@ -85,15 +164,365 @@ func (h *HTTPHandler) Show(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(t)
 }
 
+// Index serves one page of tickets, filtered and sorted by the query
+// parameters read by ParseListQuery, as {"items": [...], "nextCursor": "..."}.
+// Pass nextCursor back as ?cursor= with the same filters and sort for the
+// next page.
 func (h *HTTPHandler) Index(w http.ResponseWriter, r *http.Request) {
-	pageSize := int64(50)
-	_ = pageSize // placeholder for future pagination
-	list, err := h.svc.List(r.Context())
+	q, bad := ParseListQuery(r.URL.Query())
+	if bad != nil {
+		cmw.JSONError(w, apperr.NewValidation("invalid query parameters", bad))
+		return
+	}
+	page, err := h.svc.List(r.Context(), q)
+	if err != nil {
+		cmw.JSONError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(page)
+}
+
+// Search serves the tickets matching ?q= (see ParseSearchQuery), best match
+// first, as {"items": [...]}. Each item is a ticket with its rank and HTML
+// highlights.
+func (h *HTTPHandler) Search(w http.ResponseWriter, r *http.Request) {
+	q, bad := ParseSearchQuery(r.URL.Query())
+	if bad != nil {
+		cmw.JSONError(w, apperr.NewValidation("invalid query parameters", bad))
+		return
+	}
+	results, err := h.svc.Search(r.Context(), q)
+	if err != nil {
+		cmw.JSONError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct {
+		Items []*SearchResult `json:"items"`
+	}{results})
+}
+
+// CreateSeats lists one ticket per seat of an event's venue for the event's
+// owner and answers the new tickets as {"items": [...]}.
+func (h *HTTPHandler) CreateSeats(w http.ResponseWriter, r *http.Request) {
+	var in SeatTicketsInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	if bad := in.Validate(); bad != nil {
+		cmw.JSONError(w, apperr.NewValidation("invalid payload", bad))
+		return
+	}
+	cu := cmw.GetCurrentUser(r.Context())
+	if cu == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	list, err := h.svc.CreateSeatTickets(r.Context(), in, cu.ID)
+	switch {
+	case errors.Is(err, ErrUnknownEvent), errors.Is(err, ErrNoSeatMap):
+		err = apperr.NewValidation("invalid payload", map[string]string{"eventId": err.Error()})
+	case errors.Is(err, ErrNotEventOwner):
+		err = apperr.NewForbidden(err.Error())
+	}
+	if err != nil {
+		cmw.JSONError(w, err)
+		return
+	}
+	if list == nil {
+		list = []*Ticket{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(struct {
+		Items []*Ticket `json:"items"`
+	}{list})
+}
+
+// Seats serves the seat map of ?eventId= with each seat free, held or sold.
+func (h *HTTPHandler) Seats(w http.ResponseWriter, r *http.Request) {
+	eventID := r.URL.Query().Get("eventId")
+	if _, err := uuid.Parse(eventID); err != nil {
+		cmw.JSONError(w, apperr.NewValidation("invalid query parameters", map[string]string{"eventId": "must be the ID of an event"}))
+		return
+	}
+	seats, err := h.svc.Seats(r.Context(), eventID)
+	if err != nil {
+		cmw.JSONError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(seats)
+}
+
+// Snapshot serves one page of every ticket with its version, for replicas in
+// other services (see package snapshot).
+func (h *HTTPHandler) Snapshot(w http.ResponseWriter, r *http.Request) {
+	after, limit := snapshot.Params(r)
+	list, err := h.svc.Snapshot(r.Context(), after, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	snapshot.Write(w, snapshot.NewPage(list, limit, func(t *Ticket) string { return t.ID }))
+}
+
+// ImageHandler serves image uploads for ticket sellers.
+type ImageHandler struct{ images *Images }
+
+func NewImageHandler(images *Images) *ImageHandler { return &ImageHandler{images: images} }
+
+// CodeTooManyImages is the code of the 409 response to an upload for a
+// ticket that already has MaxImages.
+const CodeTooManyImages = "too_many_images"
+
+// Upload adds the image in the multipart field "image" to the ticket given
+// by id and answers the new image with its URLs.
+func (h *ImageHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "missing id", http.StatusBadRequest)
+		return
+	}
+	cu := cmw.GetCurrentUser(r.Context())
+	if cu == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	// Leave room for the multipart headers around the file.
+	r.Body = http.MaxBytesReader(w, r.Body, MaxImageSize+64<<10)
+	f, _, err := r.FormFile("image")
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		cmw.JSONError(w, imageError(ErrImageTooLarge))
+		return
+	case err != nil:
+		cmw.JSONError(w, apperr.NewValidation("invalid payload", map[string]string{"image": "must be a file upload"}))
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, MaxImageSize+1))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	// ensure consistent numeric encoding
+	img, err := h.images.Add(r.Context(), id, cu.ID, data)
+	if err != nil {
+		cmw.JSONError(w, imageError(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(img)
+}
+
+// Delete removes image imageId from the ticket given by id.
+func (h *ImageHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, imageID := r.URL.Query().Get("id"), r.URL.Query().Get("imageId")
+	if id == "" || imageID == "" {
+		http.Error(w, "missing id or imageId", http.StatusBadRequest)
+		return
+	}
+	cu := cmw.GetCurrentUser(r.Context())
+	if cu == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := h.images.Remove(r.Context(), id, imageID, cu.ID); err != nil {
+		cmw.JSONError(w, imageError(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// imageError maps an error from Images to its HTTP response.
+func imageError(err error) error {
+	switch {
+	case errors.Is(err, ErrImageTooLarge):
+		return apperr.NewValidation("invalid payload", map[string]string{
+			"image": fmt.Sprintf("must be at most %d MB and %d megapixels", MaxImageSize>>20, MaxImagePixels/1_000_000)})
+	case errors.Is(err, ErrUnsupportedImage):
+		return apperr.NewValidation("invalid payload", map[string]string{"image": err.Error()})
+	case errors.Is(err, ErrTooManyImages):
+		return apperr.WithCode(apperr.NewConflict(err.Error()), CodeTooManyImages)
+	}
+	return updateError(err)
+}
+
+// Codes of the 409 responses to a rejected transfer.
+const (
+	CodeTransferPending = "transfer_pending"
+	CodeTransferClosed  = "transfer_closed"
+)
+
+// transferError maps an error from a transfer or holding to its HTTP
+// response.
+func transferError(err error) error {
+	switch {
+	case errors.Is(err, ErrHoldingNotFound), errors.Is(err, ErrTransferNotFound):
+		return apperr.NewNotFound(err.Error())
+	case errors.Is(err, ErrNotHolder), errors.Is(err, ErrNotRecipient):
+		return apperr.NewForbidden(err.Error())
+	case errors.Is(err, ErrTransferPending):
+		return apperr.WithCode(apperr.NewConflict(err.Error()), CodeTransferPending)
+	case errors.Is(err, ErrTransferClosed):
+		return apperr.WithCode(apperr.NewConflict(err.Error()), CodeTransferClosed)
+	}
+	return err
+}
+
+// StartTransfer offers the units of the paid order in the body to an email
+// address and answers the pending transfer.
+func (h *HTTPHandler) StartTransfer(w http.ResponseWriter, r *http.Request) {
+	var in TransferInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	cu := cmw.GetCurrentUser(r.Context())
+	if cu == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if bad := in.Validate(cu.Email); bad != nil {
+		cmw.JSONError(w, apperr.NewValidation("invalid payload", bad))
+		return
+	}
+	t, err := h.svc.StartTransfer(r.Context(), in, cu.ID)
+	if err != nil {
+		cmw.JSONError(w, transferError(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(t)
+}
+
+// AcceptTransfer hands the units of transfer id to the current user.
+func (h *HTTPHandler) AcceptTransfer(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "missing id", http.StatusBadRequest)
+		return
+	}
+	cu := cmw.GetCurrentUser(r.Context())
+	if cu == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	t, err := h.svc.AcceptTransfer(r.Context(), id, cu.ID, cu.Email)
+	if err != nil {
+		cmw.JSONError(w, transferError(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(t)
+}
+
+// CancelTransfer withdraws transfer id, which the current user sent.
+func (h *HTTPHandler) CancelTransfer(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "missing id", http.StatusBadRequest)
+		return
+	}
+	cu := cmw.GetCurrentUser(r.Context())
+	if cu == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := h.svc.CancelTransfer(r.Context(), id, cu.ID); err != nil {
+		cmw.JSONError(w, transferError(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Transfers serves the pending transfers the current user sent or received
+// as {"items": [...]}.
+func (h *HTTPHandler) Transfers(w http.ResponseWriter, r *http.Request) {
+	cu := cmw.GetCurrentUser(r.Context())
+	if cu == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	list, err := h.svc.Transfers(r.Context(), cu.ID, cu.Email)
+	if err != nil {
+		cmw.JSONError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct {
+		Items []*Transfer `json:"items"`
+	}{list})
+}
+
+// Holding serves the current user's units of ?orderId= with their
+// credential.
+func (h *HTTPHandler) Holding(w http.ResponseWriter, r *http.Request) {
+	orderID := r.URL.Query().Get("orderId")
+	if orderID == "" {
+		http.Error(w, "missing orderId", http.StatusBadRequest)
+		return
+	}
+	cu := cmw.GetCurrentUser(r.Context())
+	if cu == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	hold, err := h.svc.Holding(r.Context(), orderID, cu.ID)
+	if err != nil {
+		cmw.JSONError(w, transferError(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(hold)
+}
+
+// Owners serves the ownership history of ?orderId=, buyer first, as
+// {"items": [...]}, to anyone who held its units.
+func (h *HTTPHandler) Owners(w http.ResponseWriter, r *http.Request) {
+	orderID := r.URL.Query().Get("orderId")
+	if orderID == "" {
+		http.Error(w, "missing orderId", http.StatusBadRequest)
+		return
+	}
+	cu := cmw.GetCurrentUser(r.Context())
+	if cu == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	owners, err := h.svc.Owners(r.Context(), orderID, cu.ID)
+	if err != nil {
+		cmw.JSONError(w, transferError(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct {
+		Items []Owner `json:"items"`
+	}{owners})
+}
+
+// CheckCredential serves the units admitted by the credential ?code=, for
+// entry scanning. Replaced credentials are 404s like unknown ones.
+func (h *HTTPHandler) CheckCredential(w http.ResponseWriter, r *http.Request) {
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		http.Error(w, "missing code", http.StatusBadRequest)
+		return
+	}
+	hold, err := h.svc.CheckCredential(r.Context(), code)
+	if err != nil {
+		cmw.JSONError(w, err)
+		return
+	}
+	if hold == nil {
+		cmw.JSONError(w, apperr.NewNotFound("credential is not valid"))
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(list)
+	_ = json.NewEncoder(w).Encode(hold)
 }
@@ -0,0 +1,76 @@
+package tickets
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	apperr "github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/errors"
+	cmw "github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/middleware"
+)
+
+func TestUpdateErrorStatus(t *testing.T) {
+	for _, tc := range []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{ErrNotFound, http.StatusNotFound, ""},
+		{ErrNotOwner, http.StatusForbidden, ""},
+		{ErrVersionConflict, http.StatusConflict, CodeVersionConflict},
+		{ErrTicketReserved, http.StatusConflict, CodeTicketReserved},
+		{ErrTicketSold, http.StatusConflict, CodeTicketSold},
+	} {
+		rec := httptest.NewRecorder()
+		cmw.JSONError(rec, updateError(tc.err))
+		var resp apperr.ErrorResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("%v: decode error response: %v", tc.err, err)
+		}
+		if rec.Code != tc.status || resp.Code != tc.code || resp.Message == "" {
+			t.Errorf("%v: status %d, response %+v; want %d with code %q", tc.err, rec.Code, resp, tc.status, tc.code)
+		}
+	}
+}
+
+func TestCreateTakesOptionalEventID(t *testing.T) {
+	t.Setenv("JWT_KEY", "test-key")
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &cmw.UserClaims{ID: "seller"}).SignedString([]byte("test-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := cmw.CurrentUser(http.HandlerFunc(NewHTTPHandler(NewService(newFakeRepo())).Create))
+
+	for _, tc := range []struct {
+		body   string
+		status int
+	}{
+		{`{"title":"Concert","price":2500}`, http.StatusOK},
+		{`{"title":"Concert","price":2500,"eventId":""}`, http.StatusOK},
+		{`{"title":"Concert","price":2500,"eventId":"not-an-id"}`, http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/tickets", strings.NewReader(tc.body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Errorf("create %s: status %d, want %d (%s)", tc.body, rec.Code, tc.status, rec.Body)
+			continue
+		}
+		if tc.status == http.StatusOK {
+			var tk Ticket
+			if err := json.NewDecoder(rec.Body).Decode(&tk); err != nil || tk.EventID != "" || tk.UserID != "seller" {
+				t.Errorf("create %s: %v %+v", tc.body, err, tk)
+			}
+			continue
+		}
+		var resp apperr.ErrorResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.FieldErrors["eventId"] == "" {
+			t.Errorf("create %s: %v %+v, want an eventId field error", tc.body, err, resp)
+		}
+	}
+}
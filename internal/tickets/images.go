@@ -0,0 +1,320 @@
+package tickets
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif" // register the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/blob"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/store"
+)
+
+// Limits on ticket images.
+const (
+	MaxImageSize   = 5 << 20    // bytes per upload
+	MaxImagePixels = 25_000_000 // width × height, checked before decoding
+	MaxImages      = 10         // per ticket
+	ThumbnailSize  = 320        // longest side of a thumbnail, in pixels
+)
+
+// ImagePath is where the tickets service serves image blobs. Image URLs are
+// ImagePath followed by the blob key.
+const ImagePath = "/api/tickets/images/"
+
+// Image is a picture of a ticket listing with a thumbnail of it.
+type Image struct {
+	ID           string `json:"id"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnailUrl"`
+	ContentType  string `json:"contentType"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	// Blob keys of the image and its thumbnail.
+	Key          string `json:"-"`
+	ThumbnailKey string `json:"-"`
+}
+
+func (img *Image) setURLs() {
+	img.URL, img.ThumbnailURL = ImagePath+img.Key, ImagePath+img.ThumbnailKey
+}
+
+// Errors returned for image uploads.
+var (
+	ErrImageTooLarge    = errors.New("image is too large")
+	ErrUnsupportedImage = errors.New("image must be a JPEG, PNG or GIF")
+	ErrTooManyImages    = errors.New("ticket has the maximum number of images")
+)
+
+// imageTypes maps the content types accepted for uploads to the extension of
+// their blob keys.
+var imageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// upload is an image checked and ready to store, with its thumbnail.
+type upload struct {
+	contentType   string
+	width, height int
+	data          []byte
+	thumbType     string
+	thumb         []byte
+}
+
+// processImage checks an uploaded image and makes its thumbnail. The content
+// type is sniffed from the data; what the client claims is ignored.
+// Thumbnails of JPEGs are JPEGs and all others are PNGs.
+func processImage(data []byte) (*upload, error) {
+	if len(data) > MaxImageSize {
+		return nil, ErrImageTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	if imageTypes[contentType] == "" {
+		return nil, ErrUnsupportedImage
+	}
+	// Read the size first so a small file cannot claim a huge canvas.
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if cfg.Width*cfg.Height > MaxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+
+	u := &upload{contentType: contentType, width: cfg.Width, height: cfg.Height, data: data}
+	var buf bytes.Buffer
+	if contentType == "image/jpeg" {
+		u.thumbType = "image/jpeg"
+		err = jpeg.Encode(&buf, thumbnail(src, ThumbnailSize), &jpeg.Options{Quality: 80})
+	} else {
+		u.thumbType = "image/png"
+		err = png.Encode(&buf, thumbnail(src, ThumbnailSize))
+	}
+	if err != nil {
+		return nil, err
+	}
+	u.thumb = buf.Bytes()
+	return u, nil
+}
+
+// thumbnail scales src down to fit in a size × size square, keeping its
+// aspect ratio, by averaging the source pixels behind each thumbnail pixel.
+// Images that already fit are copied at their size.
+func thumbnail(src image.Image, size int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	tw, th := w, h
+	switch {
+	case w > size && w >= h:
+		tw, th = size, h*size/w
+	case h > size:
+		tw, th = w*size/h, size
+	}
+	if tw < 1 {
+		tw = 1
+	}
+	if th < 1 {
+		th = 1
+	}
+
+	dst := image.NewRGBA64(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := b.Min.Y+y*h/th, b.Min.Y+(y+1)*h/th
+		if y1 == y0 {
+			y1++
+		}
+		for x := 0; x < tw; x++ {
+			x0, x1 := b.Min.X+x*w/tw, b.Min.X+(x+1)*w/tw
+			if x1 == x0 {
+				x1++
+			}
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a, n = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
+
+// Images stores ticket images in a blob store and records them on their
+// tickets. Only a ticket's seller can add or remove its images.
+type Images struct {
+	repo  Repository
+	store blob.Store
+}
+
+func NewImages(repo Repository, store blob.Store) *Images {
+	return &Images{repo: repo, store: store}
+}
+
+// Add stores data as a new image of ticket id with its thumbnail.
+func (s *Images) Add(ctx context.Context, id, userID string, data []byte) (*Image, error) {
+	// Check the ticket before storing anything; AddImage checks again.
+	t, err := s.repo.Get(ctx, id)
+	switch {
+	case err != nil:
+		return nil, err
+	case t == nil:
+		return nil, ErrNotFound
+	case t.UserID != userID:
+		return nil, ErrNotOwner
+	case len(t.Images) >= MaxImages:
+		return nil, ErrTooManyImages
+	}
+	u, err := processImage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	imageID := uuid.NewString()
+	img := &Image{
+		ID:           imageID,
+		ContentType:  u.contentType,
+		Width:        u.width,
+		Height:       u.height,
+		Key:          "tickets/" + id + "/" + imageID + imageTypes[u.contentType],
+		ThumbnailKey: "tickets/" + id + "/" + imageID + "-thumb" + imageTypes[u.thumbType],
+	}
+	if err := s.store.Put(ctx, img.Key, u.contentType, u.data); err != nil {
+		return nil, err
+	}
+	if err := s.store.Put(ctx, img.ThumbnailKey, u.thumbType, u.thumb); err != nil {
+		s.discard(img)
+		return nil, err
+	}
+	if err := s.repo.AddImage(ctx, id, userID, img); err != nil {
+		s.discard(img)
+		return nil, err
+	}
+	img.setURLs()
+	return img, nil
+}
+
+// Remove deletes image imageID of ticket id.
+func (s *Images) Remove(ctx context.Context, id, imageID, userID string) error {
+	img, err := s.repo.RemoveImage(ctx, id, imageID, userID)
+	if err != nil {
+		return err
+	}
+	s.discard(img)
+	return nil
+}
+
+// discard deletes the blobs of img. Failures only leave unreferenced blobs
+// behind, so they are logged rather than returned.
+func (s *Images) discard(img *Image) {
+	for _, key := range []string{img.Key, img.ThumbnailKey} {
+		if err := s.store.Delete(context.Background(), key); err != nil {
+			log.Printf("delete image blob %s: %v", key, err)
+		}
+	}
+}
+
+func (r *repo) AddImage(ctx context.Context, id, userID string, img *Image) error {
+	return store.WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		// Lock the ticket so concurrent uploads cannot pass MaxImages.
+		var owner string
+		err := tx.QueryRowContext(ctx, `SELECT user_id FROM tickets WHERE id=$1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&owner)
+		switch {
+		case err == sql.ErrNoRows:
+			return ErrNotFound
+		case err != nil:
+			return err
+		case owner != userID:
+			return ErrNotOwner
+		}
+		var n int
+		if err := tx.QueryRowContext(ctx, `SELECT count(*) FROM ticket_images WHERE ticket_id=$1`, id).Scan(&n); err != nil {
+			return err
+		}
+		if n >= MaxImages {
+			return ErrTooManyImages
+		}
+		_, err = tx.ExecContext(ctx, `
+INSERT INTO ticket_images (id, ticket_id, key, thumbnail_key, content_type, width, height)
+VALUES ($1,$2,$3,$4,$5,$6,$7)`, img.ID, id, img.Key, img.ThumbnailKey, img.ContentType, img.Width, img.Height)
+		return err
+	})
+}
+
+func (r *repo) RemoveImage(ctx context.Context, id, imageID, userID string) (*Image, error) {
+	img := Image{ID: imageID}
+	err := r.db.QueryRowContext(ctx, `
+DELETE FROM ticket_images i USING tickets t
+WHERE i.id=$1 AND i.ticket_id=$2 AND t.id = i.ticket_id AND t.deleted_at IS NULL
+  AND t.user_id=$3
+RETURNING i.key, i.thumbnail_key, i.content_type, i.width, i.height`, imageID, id, userID).Scan(
+		&img.Key, &img.ThumbnailKey, &img.ContentType, &img.Width, &img.Height)
+	if err == sql.ErrNoRows {
+		// Tell a missing image from one on somebody else's ticket.
+		var found bool
+		if err := r.db.QueryRowContext(ctx, `
+SELECT EXISTS (SELECT 1 FROM ticket_images i JOIN tickets t ON t.id = i.ticket_id
+               WHERE i.id=$1 AND i.ticket_id=$2 AND t.deleted_at IS NULL)`, imageID, id).Scan(&found); err != nil {
+			return nil, err
+		}
+		if found {
+			return nil, ErrNotOwner
+		}
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	img.setURLs()
+	return &img, nil
+}
+
+// attachImages sets the Images of tickets, oldest image first.
+func (r *repo) attachImages(ctx context.Context, tickets []*Ticket) error {
+	if len(tickets) == 0 {
+		return nil
+	}
+	byID := make(map[string]*Ticket, len(tickets))
+	ids := make([]string, len(tickets))
+	for i, t := range tickets {
+		byID[t.ID] = t
+		ids[i] = t.ID
+	}
+	rows, err := r.db.QueryContext(ctx, `
+SELECT ticket_id, id, key, thumbnail_key, content_type, width, height FROM ticket_images
+WHERE ticket_id = ANY($1::uuid[])
+ORDER BY created_at, id`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var ticketID string
+		var img Image
+		if err := rows.Scan(&ticketID, &img.ID, &img.Key, &img.ThumbnailKey, &img.ContentType, &img.Width, &img.Height); err != nil {
+			return err
+		}
+		img.setURLs()
+		if t := byID[ticketID]; t != nil {
+			t.Images = append(t.Images, img)
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,226 @@
+package tickets
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/blob"
+	apperr "github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/errors"
+	cmw "github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/middleware"
+)
+
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestThumbnailFitsAndKeepsAspectRatio(t *testing.T) {
+	for _, tc := range []struct{ w, h, tw, th int }{
+		{1280, 640, 320, 160},
+		{400, 1000, 128, 320},
+		{200, 100, 200, 100}, // never scaled up
+		{3000, 2, 320, 1},
+	} {
+		b := thumbnail(testImage(tc.w, tc.h), ThumbnailSize).Bounds()
+		if b.Dx() != tc.tw || b.Dy() != tc.th {
+			t.Errorf("thumbnail of %dx%d is %dx%d, want %dx%d", tc.w, tc.h, b.Dx(), b.Dy(), tc.tw, tc.th)
+		}
+	}
+
+	// Averaging a two-color image gives the color in between.
+	src := image.NewGray(image.Rect(0, 0, 640, 640))
+	for x := 0; x < 640; x += 2 {
+		for y := 0; y < 640; y++ {
+			src.SetGray(x, y, color.Gray{Y: 200})
+		}
+	}
+	if r, _, _, _ := thumbnail(src, ThumbnailSize).At(10, 10).RGBA(); r>>8 != 100 {
+		t.Errorf("thumbnail pixel = %d, want the average 100", r>>8)
+	}
+}
+
+func TestProcessImageSniffsContent(t *testing.T) {
+	var jpg bytes.Buffer
+	if err := jpeg.Encode(&jpg, testImage(800, 600), nil); err != nil {
+		t.Fatal(err)
+	}
+	u, err := processImage(jpg.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.contentType != "image/jpeg" || u.thumbType != "image/jpeg" || u.width != 800 || u.height != 600 {
+		t.Errorf("processed JPEG = %s %dx%d, thumbnail %s", u.contentType, u.width, u.height, u.thumbType)
+	}
+	thumb, format, err := image.Decode(bytes.NewReader(u.thumb))
+	if err != nil || format != "jpeg" || thumb.Bounds().Dx() != ThumbnailSize || thumb.Bounds().Dy() != 240 {
+		t.Errorf("thumbnail: %v %s %v", err, format, thumb)
+	}
+
+	if _, err := processImage([]byte("<svg xmlns='http://www.w3.org/2000/svg'></svg>")); err != ErrUnsupportedImage {
+		t.Errorf("SVG: got %v, want ErrUnsupportedImage", err)
+	}
+	// Sniffed as PNG, but not a decodable one.
+	if _, err := processImage(append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 32)...)); err != ErrUnsupportedImage {
+		t.Errorf("corrupt PNG: got %v, want ErrUnsupportedImage", err)
+	}
+	if _, err := processImage(make([]byte, MaxImageSize+1)); err != ErrImageTooLarge {
+		t.Errorf("oversized upload: got %v, want ErrImageTooLarge", err)
+	}
+	// A tiny file whose header claims a canvas beyond MaxImagePixels.
+	huge := encodePNG(t, testImage(1, 1))
+	copy(huge[16:24], []byte{0, 0, 0x27, 0x10, 0, 0, 0x27, 0x10}) // 10000x10000 in IHDR
+	binary.BigEndian.PutUint32(huge[29:33], crc32.ChecksumIEEE(huge[12:29]))
+	if _, err := processImage(huge); err != ErrImageTooLarge {
+		t.Errorf("huge canvas: got %v, want ErrImageTooLarge", err)
+	}
+}
+
+func TestUploadStoresImageAndThumbnail(t *testing.T) {
+	t.Setenv("JWT_KEY", "test-key")
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &cmw.UserClaims{ID: "seller"}).SignedString([]byte("test-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := blob.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := newFakeRepo(
+		&Ticket{ID: "mine", UserID: "seller", Status: StatusAvailable},
+		&Ticket{ID: "theirs", UserID: "someone else", Status: StatusAvailable},
+	)
+	h := NewImageHandler(NewImages(repo, store))
+	upload := cmw.CurrentUser(http.HandlerFunc(h.Upload))
+	files := http.StripPrefix(ImagePath, blob.Handler(store))
+
+	post := func(id string, data []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		// The claimed name and type are ignored; the content decides.
+		part, err := mw.CreateFormFile("image", "photo.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(data)
+		mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/api/tickets/images?id="+id, &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		upload.ServeHTTP(rec, req)
+		return rec
+	}
+
+	pic := encodePNG(t, testImage(640, 480))
+	rec := post("mine", pic)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("upload: status %d (%s)", rec.Code, rec.Body)
+	}
+	var img Image
+	if err := json.NewDecoder(rec.Body).Decode(&img); err != nil {
+		t.Fatal(err)
+	}
+	if img.ContentType != "image/png" || img.Width != 640 || img.Height != 480 ||
+		!strings.HasPrefix(img.URL, ImagePath+"tickets/mine/") || !strings.HasSuffix(img.ThumbnailURL, "-thumb.png") {
+		t.Errorf("uploaded image = %+v", img)
+	}
+
+	for _, tc := range []struct {
+		url         string
+		contentType string
+		check       func([]byte)
+	}{
+		{img.URL, "image/png", func(b []byte) {
+			if !bytes.Equal(b, pic) {
+				t.Error("served image differs from the upload")
+			}
+		}},
+		{img.ThumbnailURL, "image/png", func(b []byte) {
+			if cfg, err := png.DecodeConfig(bytes.NewReader(b)); err != nil || cfg.Width != 320 || cfg.Height != 240 {
+				t.Errorf("served thumbnail: %v %+v", err, cfg)
+			}
+		}},
+	} {
+		rec := httptest.NewRecorder()
+		files.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.url, nil))
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != tc.contentType {
+			t.Fatalf("GET %s: status %d, type %q", tc.url, rec.Code, rec.Header().Get("Content-Type"))
+		}
+		tc.check(rec.Body.Bytes())
+	}
+
+	ticket, _ := repo.Get(context.Background(), "mine")
+	if len(ticket.Images) != 1 || ticket.Images[0].URL != img.URL {
+		t.Errorf("ticket images = %+v, want the upload", ticket.Images)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		id     string
+		data   []byte
+		status int
+		field  bool
+	}{
+		{"text", "mine", []byte("not an image at all"), http.StatusBadRequest, true},
+		{"oversized", "mine", make([]byte, MaxImageSize+1), http.StatusBadRequest, true},
+		{"not the seller", "theirs", pic, http.StatusForbidden, false},
+		{"missing ticket", "missing", pic, http.StatusNotFound, false},
+	} {
+		rec := post(tc.id, tc.data)
+		if rec.Code != tc.status {
+			t.Errorf("%s: status %d, want %d (%s)", tc.name, rec.Code, tc.status, rec.Body)
+			continue
+		}
+		var resp apperr.ErrorResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("%s: decode error response: %v", tc.name, err)
+		}
+		if tc.field && resp.FieldErrors["image"] == "" {
+			t.Errorf("%s: response %+v, want an image field error", tc.name, resp)
+		}
+	}
+
+	// Removing the image deletes its blobs.
+	del := cmw.CurrentUser(http.HandlerFunc(h.Delete))
+	req := httptest.NewRequest(http.MethodDelete, "/api/tickets/images?id=mine&imageId="+img.ID, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec = httptest.NewRecorder()
+	del.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: status %d (%s)", rec.Code, rec.Body)
+	}
+	if _, _, err := store.Get(context.Background(), strings.TrimPrefix(img.URL, ImagePath)); err != blob.ErrNotFound {
+		t.Errorf("image blob after delete: %v, want ErrNotFound", err)
+	}
+	if ticket, _ := repo.Get(context.Background(), "mine"); len(ticket.Images) != 0 {
+		t.Errorf("ticket images after delete = %+v", ticket.Images)
+	}
+}
@@ -0,0 +1,72 @@
+package tickets
+
+import (
+	"context"
+	"log"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/inbox"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/pubsub"
+)
+
+// QueueGroup is the queue group of the tickets replicas; one of them moves
+// each order's units between held, released and sold.
+const QueueGroup = "tickets"
+
+// RegisterNATSListeners subscribes to order and payment events to track the
+// units of each ticket held by orders and sold. Each handler runs through in,
+// so redelivered events are applied at most once; events that keep failing
+// are handed to dl.
+func RegisterNATSListeners(ctx context.Context, sub pubsub.Subscriber, repo Repository, in inbox.Processor, dl events.DeadLetterSink) error {
+	c := &events.Consumer{Sub: sub, Service: QueueGroup, DeadLetters: dl}
+
+	// Listen for order:created to mark the ticket reserved
+	if err := events.Subscribe(ctx, c, events.SubjectOrderCreated, func(ctx context.Context, e events.Envelope[events.OrderCreatedData]) error {
+		d := e.Data
+		_, err := in.Process(ctx, e.ID, string(e.Subject), func(ctx context.Context) error {
+			t, err := repo.Reserve(ctx, d.Ticket.ID, d.ID, d.UserID, d.Units())
+			if err != nil {
+				return err
+			}
+			if t == nil {
+				log.Printf("order:created: ticket %s not found, already held for %s or without %d free units", d.Ticket.ID, d.ID, d.Units())
+			}
+			return nil
+		})
+		return err
+	}); err != nil {
+		return err
+	}
+
+	// Listen for order:cancelled to release the ticket
+	if err := events.Subscribe(ctx, c, events.SubjectOrderCancelled, func(ctx context.Context, e events.Envelope[events.OrderCancelledData]) error {
+		d := e.Data
+		_, err := in.Process(ctx, e.ID, string(e.Subject), func(ctx context.Context) error {
+			_, err := repo.Release(ctx, d.Ticket.ID, d.ID)
+			return err
+		})
+		return err
+	}); err != nil {
+		return err
+	}
+
+	// Listen for payment:created to mark the ticket sold
+	if err := events.Subscribe(ctx, c, events.SubjectPaymentCreated, func(ctx context.Context, e events.Envelope[events.PaymentCreatedData]) error {
+		d := e.Data
+		_, err := in.Process(ctx, e.ID, string(e.Subject), func(ctx context.Context) error {
+			t, err := repo.MarkSold(ctx, d.OrderID)
+			if err != nil {
+				return err
+			}
+			if t == nil {
+				log.Printf("payment:created: no ticket reserved for order %s, nothing marked sold", d.OrderID)
+			}
+			return nil
+		})
+		return err
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
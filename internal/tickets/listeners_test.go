@@ -0,0 +1,195 @@
+package tickets
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events/eventstest"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/inbox"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/pubsub"
+)
+
+// fakeRepo keeps tickets in memory and records the order and payment calls
+// the listeners make; the SQL behind them is checked in repo_test.go.
+type fakeRepo struct {
+	mu      sync.Mutex
+	tickets map[string]*Ticket
+	calls   []string
+}
+
+func newFakeRepo(ts ...*Ticket) *fakeRepo {
+	r := &fakeRepo{tickets: map[string]*Ticket{}}
+	for _, t := range ts {
+		r.tickets[t.ID] = t
+	}
+	return r
+}
+
+func (r *fakeRepo) EnsureSchema(context.Context) error { return nil }
+
+func (r *fakeRepo) Create(_ context.Context, eventID, title, description string, price int64, quantity int, userID string) (*Ticket, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := &Ticket{ID: fmt.Sprintf("new-%d", len(r.tickets)+1), EventID: eventID, Title: title, Description: description, Price: price,
+		UserID: userID, Status: StatusAvailable, QuantityTotal: quantity}
+	r.tickets[t.ID] = t
+	cp := *t
+	return &cp, nil
+}
+
+func (r *fakeRepo) Get(_ context.Context, id string) (*Ticket, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.tickets[id]; ok {
+		cp := *t
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeRepo) List(context.Context, ListQuery) (*TicketPage, error) { panic("not used") }
+
+func (r *fakeRepo) Search(context.Context, SearchQuery) ([]*SearchResult, error) {
+	panic("not used")
+}
+
+func (r *fakeRepo) Snapshot(context.Context, string, int) ([]*Ticket, error) { panic("not used") }
+
+func (r *fakeRepo) CreateSeatTickets(context.Context, SeatTicketsInput, string) ([]*Ticket, error) {
+	panic("not used")
+}
+
+// SeatTickets returns the event's seat tickets by ID, so tests give seat
+// tickets IDs in seat map order.
+func (r *fakeRepo) SeatTickets(_ context.Context, eventID string) ([]*Ticket, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := []*Ticket{}
+	for _, t := range r.tickets {
+		if t.EventID == eventID && t.Seat != nil {
+			cp := *t
+			out = append(out, &cp)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (r *fakeRepo) UpdateWithVersion(context.Context, string, int, string, string, int64, string) (*Ticket, error) {
+	panic("not used")
+}
+
+func (r *fakeRepo) Delete(context.Context, string, string) error { panic("not used") }
+
+func (r *fakeRepo) AddImage(_ context.Context, id, userID string, img *Image) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.tickets[id]
+	switch {
+	case !ok:
+		return ErrNotFound
+	case t.UserID != userID:
+		return ErrNotOwner
+	case len(t.Images) >= MaxImages:
+		return ErrTooManyImages
+	}
+	cp := *img
+	cp.setURLs()
+	t.Images = append(t.Images, cp)
+	return nil
+}
+
+func (r *fakeRepo) RemoveImage(_ context.Context, id, imageID, userID string) (*Image, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.tickets[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	for i, img := range t.Images {
+		if img.ID != imageID {
+			continue
+		}
+		if t.UserID != userID {
+			return nil, ErrNotOwner
+		}
+		t.Images = append(t.Images[:i:i], t.Images[i+1:]...)
+		return &img, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (r *fakeRepo) Reserve(_ context.Context, id, orderID, userID string, quantity int) (*Ticket, error) {
+	r.record("reserve %s for %s of %s: %d", id, orderID, userID, quantity)
+	return nil, nil
+}
+
+func (r *fakeRepo) Release(_ context.Context, id, orderID string) (*Ticket, error) {
+	r.record("release %s from %s", id, orderID)
+	return nil, nil
+}
+
+func (r *fakeRepo) MarkSold(_ context.Context, orderID string) (*Ticket, error) {
+	r.record("sell %s", orderID)
+	return nil, nil
+}
+
+func (r *fakeRepo) record(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, fmt.Sprintf(format, args...))
+}
+
+func (r *fakeRepo) StartTransfer(context.Context, string, string, string) (*Transfer, error) {
+	panic("not used")
+}
+
+func (r *fakeRepo) AcceptTransfer(context.Context, string, string, string) (*Transfer, error) {
+	panic("not used")
+}
+
+func (r *fakeRepo) CancelTransfer(context.Context, string, string) error { panic("not used") }
+
+func (r *fakeRepo) Transfers(context.Context, string, string) ([]*Transfer, error) {
+	panic("not used")
+}
+
+func (r *fakeRepo) Holding(context.Context, string, string) (*Holding, error) { panic("not used") }
+
+func (r *fakeRepo) HoldingByCredential(context.Context, string) (*Holding, error) {
+	panic("not used")
+}
+
+func (r *fakeRepo) Owners(context.Context, string, string) ([]Owner, error) { panic("not used") }
+
+func TestOrderAndPaymentEventsReachRepository(t *testing.T) {
+	ctx := context.Background()
+	bus := pubsub.NewMemory()
+	repo := newFakeRepo()
+	if err := RegisterNATSListeners(ctx, bus, repo, inbox.NewMemory(), nil); err != nil {
+		t.Fatalf("RegisterNATSListeners: %v", err)
+	}
+	ticket := events.OrderTicketDetail{ID: "t1", Price: 2000}
+	expires := time.Now().Add(15 * time.Minute)
+
+	eventstest.Publish(t, bus, events.SubjectOrderCreated, events.OrderCreatedData{ID: "o1", Status: "created", UserID: "buyer", ExpiresAt: expires, Ticket: ticket})
+	eventstest.Publish(t, bus, events.SubjectOrderCreated, events.OrderCreatedData{ID: "o2", Status: "created", UserID: "buyer", ExpiresAt: expires, Ticket: ticket, Quantity: 3})
+	eventstest.Publish(t, bus, events.SubjectOrderCancelled, events.OrderCancelledData{ID: "o2", Version: 1, Ticket: ticket, Quantity: 3})
+	eventstest.Publish(t, bus, events.SubjectPaymentCreated, events.PaymentCreatedData{ID: "p1", OrderID: "o1", StripeID: "ch_1"})
+
+	want := []string{
+		"reserve t1 for o1 of buyer: 1",
+		"reserve t1 for o2 of buyer: 3",
+		"release t1 from o2",
+		"sell o1",
+	}
+	if !reflect.DeepEqual(repo.calls, want) {
+		t.Errorf("repository calls = %q, want %q", repo.calls, want)
+	}
+}
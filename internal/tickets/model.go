@@ -2,12 +2,64 @@ package tickets
 
 import "time"
 
+// Ticket states. A ticket is reserved while an order holds it and sold once
+// that order is paid; only available tickets can be edited. A
+// general-admission ticket with several units stays available while any
+// unit is free, is reserved while the rest are held and sold once all are.
+const (
+	StatusAvailable = "available"
+	StatusReserved  = "reserved"
+	StatusSold      = "sold"
+)
+
 type Ticket struct {
-	ID        string    `json:"id"`
-	Title     string    `json:"title"`
-	Price     int64     `json:"price"`
-	UserID    string    `json:"userId"`
-	OrderID   *string   `json:"orderId,omitempty"`
-	Version   int       `json:"version"`
-	CreatedAt time.Time `json:"createdAt"`
+	ID          string  `json:"id"`
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	Price       int64   `json:"price"`
+	UserID      string  `json:"userId"`
+	OrderID     *string `json:"orderId,omitempty"`
+	Status      string  `json:"status"`
+	EventID     string  `json:"eventId,omitempty"`
+	// Seat is set for tickets generated from the seat map of the event's
+	// venue.
+	Seat *Seat `json:"seat,omitempty"`
+	// Units of a general-admission ticket: QuantityHeld are held by unpaid
+	// orders and QuantitySold are paid. Single tickets have one unit.
+	QuantityTotal int       `json:"quantityTotal"`
+	QuantityHeld  int       `json:"quantityHeld"`
+	QuantitySold  int       `json:"quantitySold"`
+	Version       int       `json:"version"`
+	CreatedAt     time.Time `json:"createdAt"`
+	// DeletedAt is set once the seller withdraws the ticket. Only Snapshot
+	// serves withdrawn tickets, so replicas catching up learn of them.
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	// Images are the seller's pictures of the listing, oldest first.
+	Images []Image `json:"images,omitempty"`
+}
+
+// MaxQuantity is the most units one ticket can have.
+const MaxQuantity = 100000
+
+// stock is the units of a ticket.
+type stock struct{ total, held, sold int }
+
+func (s stock) free() int { return s.total - s.held - s.sold }
+
+// status is the ticket status for the units in s.
+func (s stock) status() string {
+	switch {
+	case s.free() > 0:
+		return StatusAvailable
+	case s.held > 0:
+		return StatusReserved
+	}
+	return StatusSold
+}
+
+// Seat is a seat of a venue's seat map, copied onto its ticket.
+type Seat struct {
+	Section string `json:"section"`
+	Row     string `json:"row"`
+	Number  int    `json:"number"`
 }
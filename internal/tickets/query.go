@@ -0,0 +1,250 @@
+package tickets
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Sort orders for List. The leading "-" means descending; ties are broken by
+// ID in the same direction so every ticket has a stable position.
+const (
+	SortNewest    = "-createdAt"
+	SortOldest    = "createdAt"
+	SortPriceAsc  = "price"
+	SortPriceDesc = "-price"
+)
+
+// Page sizes for List.
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+// ListQuery selects one page of tickets. Zero fields do not filter.
+type ListQuery struct {
+	Limit  int
+	Cursor string // nextCursor of the previous page
+	Sort   string // one of the Sort constants; SortNewest when empty
+
+	MinPrice, MaxPrice *int64
+	UserID             string // seller
+	EventID            string
+	// Available keeps only available tickets when true and only reserved or
+	// sold ones when false.
+	Available     *bool
+	CreatedAfter  *time.Time // inclusive
+	CreatedBefore *time.Time // exclusive
+}
+
+// TicketPage is one page of List. NextCursor is empty on the last page.
+type TicketPage struct {
+	Items      []*Ticket `json:"items"`
+	NextCursor string    `json:"nextCursor,omitempty"`
+}
+
+// ErrInvalidCursor is returned for a cursor that was not issued for the
+// query's sort order.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ParseListQuery reads a ListQuery from URL parameters: limit, cursor, sort,
+// minPrice, maxPrice, userId, eventId, available, createdAfter and
+// createdBefore.
+// Dates are RFC 3339 timestamps or YYYY-MM-DD. Invalid parameters are
+// reported by name.
+func ParseListQuery(v url.Values) (ListQuery, map[string]string) {
+	q := ListQuery{Limit: DefaultListLimit, Cursor: v.Get("cursor"), Sort: v.Get("sort"), UserID: v.Get("userId"), EventID: v.Get("eventId")}
+	bad := map[string]string{}
+
+	if s := v.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > MaxListLimit {
+			bad["limit"] = fmt.Sprintf("must be between 1 and %d", MaxListLimit)
+		}
+		q.Limit = n
+	}
+	switch q.Sort {
+	case "":
+		q.Sort = SortNewest
+	case SortNewest, SortOldest, SortPriceAsc, SortPriceDesc:
+	default:
+		bad["sort"] = fmt.Sprintf("must be one of %s, %s, %s, %s", SortNewest, SortOldest, SortPriceAsc, SortPriceDesc)
+	}
+	for name, dst := range map[string]**int64{"minPrice": &q.MinPrice, "maxPrice": &q.MaxPrice} {
+		if s := v.Get(name); s != "" {
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || n < 0 {
+				bad[name] = "must be a non-negative integer"
+				continue
+			}
+			*dst = &n
+		}
+	}
+	if q.EventID != "" {
+		if _, err := uuid.Parse(q.EventID); err != nil {
+			bad["eventId"] = "must be the ID of an event"
+		}
+	}
+	if s := v.Get("available"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			bad["available"] = "must be true or false"
+		}
+		q.Available = &b
+	}
+	for name, dst := range map[string]**time.Time{"createdAfter": &q.CreatedAfter, "createdBefore": &q.CreatedBefore} {
+		if s := v.Get(name); s != "" {
+			t, err := parseDate(s)
+			if err != nil {
+				bad[name] = "must be an RFC 3339 timestamp or YYYY-MM-DD"
+				continue
+			}
+			*dst = &t
+		}
+	}
+	if q.Cursor != "" && bad["sort"] == "" {
+		if _, err := decodeCursor(q.Cursor, q.Sort); err != nil {
+			bad["cursor"] = "does not belong to this sort order"
+		}
+	}
+	if len(bad) > 0 {
+		return q, bad
+	}
+	return q, nil
+}
+
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+// cursor is the position of the last ticket of a page in its sort order.
+type cursor struct {
+	Sort      string    `json:"s"`
+	CreatedAt time.Time `json:"c,omitempty"`
+	Price     int64     `json:"p,omitempty"`
+	ID        string    `json:"i"`
+}
+
+func encodeCursor(sort string, t *Ticket) string {
+	c := cursor{Sort: sort, ID: t.ID}
+	if sortColumn(sort) == "price" {
+		c.Price = t.Price
+	} else {
+		c.CreatedAt = t.CreatedAt
+	}
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s, sort string) (*cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.Sort != sort || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+func sortColumn(sort string) string {
+	if strings.TrimPrefix(sort, "-") == SortPriceAsc {
+		return "price"
+	}
+	return "created_at"
+}
+
+// withDefaults fills in the default limit and sort order.
+func (q ListQuery) withDefaults() ListQuery {
+	if q.Limit <= 0 || q.Limit > MaxListLimit {
+		q.Limit = DefaultListLimit
+	}
+	if q.Sort == "" {
+		q.Sort = SortNewest
+	}
+	return q
+}
+
+// listSQL builds the WHERE and ORDER BY clauses and arguments for q, reading
+// one row more than the limit to tell whether another page follows.
+func listSQL(q ListQuery) (string, []any, error) {
+	q = q.withDefaults()
+	where := []string{"deleted_at IS NULL"}
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if q.MinPrice != nil {
+		where = append(where, "price >= "+arg(*q.MinPrice))
+	}
+	if q.MaxPrice != nil {
+		where = append(where, "price <= "+arg(*q.MaxPrice))
+	}
+	if q.UserID != "" {
+		where = append(where, "user_id = "+arg(q.UserID))
+	}
+	if q.EventID != "" {
+		where = append(where, "event_id = "+arg(q.EventID)+"::uuid")
+	}
+	if q.Available != nil {
+		op := "="
+		if !*q.Available {
+			op = "<>"
+		}
+		where = append(where, "status "+op+" "+arg(StatusAvailable))
+	}
+	if q.CreatedAfter != nil {
+		where = append(where, "created_at >= "+arg(*q.CreatedAfter))
+	}
+	if q.CreatedBefore != nil {
+		where = append(where, "created_at < "+arg(*q.CreatedBefore))
+	}
+
+	col, dir, cmp := sortColumn(q.Sort), "ASC", ">"
+	if strings.HasPrefix(q.Sort, "-") {
+		dir, cmp = "DESC", "<"
+	}
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor, q.Sort)
+		if err != nil {
+			return "", nil, err
+		}
+		var v any = c.CreatedAt
+		if col == "price" {
+			v = c.Price
+		}
+		where = append(where, fmt.Sprintf("(%s, id) %s (%s, %s::uuid)", col, cmp, arg(v), arg(c.ID)))
+	}
+
+	var sb strings.Builder
+	sb.WriteString("WHERE " + strings.Join(where, " AND ") + "\n")
+	fmt.Fprintf(&sb, "ORDER BY %s %s, id %s\nLIMIT %s", col, dir, dir, arg(q.Limit+1))
+	return sb.String(), args, nil
+}
+
+// newPage trims the extra row read by listSQL and sets the next cursor.
+func newPage(rows []*Ticket, q ListQuery) *TicketPage {
+	q = q.withDefaults()
+	p := &TicketPage{Items: rows}
+	if p.Items == nil {
+		p.Items = []*Ticket{}
+	}
+	if len(rows) > q.Limit {
+		p.Items = rows[:q.Limit]
+		p.NextCursor = encodeCursor(q.Sort, p.Items[q.Limit-1])
+	}
+	return p
+}
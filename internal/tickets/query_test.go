@@ -0,0 +1,87 @@
+package tickets
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseListQuery(t *testing.T) {
+	v, _ := url.ParseQuery("limit=5&sort=price&minPrice=100&maxPrice=900&userId=u1&available=true&createdAfter=2024-01-02&createdBefore=2024-02-01T00:00:00Z")
+	q, bad := ParseListQuery(v)
+	if bad != nil {
+		t.Fatalf("ParseListQuery: %v", bad)
+	}
+	after := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	if q.Limit != 5 || q.Sort != SortPriceAsc || *q.MinPrice != 100 || *q.MaxPrice != 900 || q.UserID != "u1" ||
+		!*q.Available || !q.CreatedAfter.Equal(after) || q.CreatedBefore.Month() != time.February {
+		t.Errorf("ParseListQuery = %+v", q)
+	}
+
+	if q, bad := ParseListQuery(url.Values{}); bad != nil || q.Limit != DefaultListLimit || q.Sort != SortNewest {
+		t.Errorf("defaults: %+v, %v", q, bad)
+	}
+
+	v, _ = url.ParseQuery("limit=500&sort=title&minPrice=-1&available=maybe&createdAfter=yesterday")
+	_, bad = ParseListQuery(v)
+	for _, name := range []string{"limit", "sort", "minPrice", "available", "createdAfter"} {
+		if bad[name] == "" {
+			t.Errorf("%s not reported invalid: %v", name, bad)
+		}
+	}
+}
+
+func TestListSQL(t *testing.T) {
+	minPrice, available := int64(100), false
+	clauses, args, err := listSQL(ListQuery{MinPrice: &minPrice, Available: &available, Sort: SortPriceDesc, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "WHERE deleted_at IS NULL AND price >= $1 AND status <> $2\nORDER BY price DESC, id DESC\nLIMIT $3"
+	if clauses != want || !reflect.DeepEqual(args, []any{int64(100), StatusAvailable, 11}) {
+		t.Errorf("listSQL = %q %v, want %q", clauses, args, want)
+	}
+
+	if clauses, _, _ := listSQL(ListQuery{}); clauses != "WHERE deleted_at IS NULL\nORDER BY created_at DESC, id DESC\nLIMIT $1" {
+		t.Errorf("default listSQL = %q", clauses)
+	}
+}
+
+func TestPagesContinueAfterCursor(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := []*Ticket{
+		{ID: "00000000-0000-0000-0000-000000000003", CreatedAt: base.Add(2 * time.Hour)},
+		{ID: "00000000-0000-0000-0000-000000000002", CreatedAt: base.Add(time.Hour)},
+		{ID: "00000000-0000-0000-0000-000000000001", CreatedAt: base.Add(time.Hour)},
+	}
+	q := ListQuery{Limit: 2}
+	p := newPage(rows, q)
+	if len(p.Items) != 2 || p.NextCursor == "" {
+		t.Fatalf("first page = %+v", p)
+	}
+	if last := newPage(rows[:2], q); last.NextCursor != "" {
+		t.Errorf("last page has a cursor: %+v", last)
+	}
+
+	q.Cursor = p.NextCursor
+	clauses, args, err := listSQL(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "WHERE deleted_at IS NULL AND (created_at, id) < ($1, $2::uuid)\nORDER BY created_at DESC, id DESC\nLIMIT $3"; clauses != want {
+		t.Errorf("listSQL = %q, want %q", clauses, want)
+	}
+	if !args[0].(time.Time).Equal(rows[1].CreatedAt) || args[1] != rows[1].ID {
+		t.Errorf("cursor args = %v", args)
+	}
+
+	// A cursor only continues the sort order it was issued for.
+	q.Sort = SortPriceAsc
+	if _, _, err := listSQL(q); err != ErrInvalidCursor {
+		t.Errorf("cursor with another sort: err = %v", err)
+	}
+	if _, bad := ParseListQuery(url.Values{"cursor": {p.NextCursor}, "sort": {SortPriceAsc}}); bad["cursor"] == "" {
+		t.Errorf("cursor with another sort accepted: %v", bad)
+	}
+}
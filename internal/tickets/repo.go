@@ -4,19 +4,157 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/outbox"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/store"
+)
+
+// OutboxTable holds ticket events awaiting publication.
+const OutboxTable = "tickets_outbox"
+
+// InboxTable records order events already processed by the tickets service.
+const InboxTable = "tickets_inbox"
+
+// ErrUnknownEvent is returned by Create and CreateSeatTickets for an event ID
+// that does not exist.
+var ErrUnknownEvent = errors.New("event does not exist")
+
+// Errors returned by CreateSeatTickets.
+var (
+	ErrNotEventOwner = errors.New("event belongs to another user")
+	ErrNoSeatMap     = errors.New("venue of the event has no seat map")
+)
+
+// Errors returned by UpdateWithVersion and Delete.
+var (
+	ErrNotFound        = errors.New("ticket not found")
+	ErrNotOwner        = errors.New("ticket belongs to another user")
+	ErrVersionConflict = errors.New("ticket was changed by another request")
+	ErrTicketReserved  = errors.New("ticket is reserved by an order")
+	ErrTicketSold      = errors.New("ticket is sold")
 )
 
 type Repository interface {
 	EnsureSchema(ctx context.Context) error
-	Create(ctx context.Context, title string, price int64, userID string) (*Ticket, error)
+	// Create lists a ticket with quantity units for the event eventID, which
+	// must exist; "" lists it without an event.
+	Create(ctx context.Context, eventID, title, description string, price int64, quantity int, userID string) (*Ticket, error)
 	Get(ctx context.Context, id string) (*Ticket, error)
-	List(ctx context.Context) ([]*Ticket, error)
-	UpdateWithVersion(ctx context.Context, id string, expectedVersion int, title string, price int64, userID string) (*Ticket, error)
+	// List returns one page of tickets matching q, with the cursor of the
+	// next page.
+	List(ctx context.Context, q ListQuery) (*TicketPage, error)
+	// Snapshot returns up to limit tickets with IDs after the given one, in
+	// ID order; after "" starts from the beginning.
+	Snapshot(ctx context.Context, after string, limit int) ([]*Ticket, error)
+	// UpdateWithVersion changes a ticket owned by userID at expectedVersion
+	// that no order holds or has bought, failing with ErrTicketReserved and
+	// ErrTicketSold otherwise, so it also fails for a general-admission
+	// ticket with some units still free.
+	UpdateWithVersion(ctx context.Context, id string, expectedVersion int, title, description string, price int64, userID string) (*Ticket, error)
+	// Delete withdraws a ticket owned by userID that no order holds or has
+	// bought, failing with ErrTicketReserved and ErrTicketSold otherwise, and
+	// records ticket:deleted. Withdrawn tickets are left out of every read
+	// but Snapshot and can no longer be changed or reserved.
+	Delete(ctx context.Context, id, userID string) error
+	// Reserve holds quantity units of a ticket for orderID, placed by
+	// userID, if that many are free, and Release frees the units orderID
+	// still holds. MarkSold marks the units held by orderID as sold and
+	// gives them a credential. All three bump the version and record
+	// ticket:updated; they return nil when there was nothing to change,
+	// including when orderID already holds units.
+	Reserve(ctx context.Context, id, orderID, userID string, quantity int) (*Ticket, error)
+	Release(ctx context.Context, id, orderID string) (*Ticket, error)
+	MarkSold(ctx context.Context, orderID string) (*Ticket, error)
+	// Search returns tickets matching q by title and description, best
+	// match first.
+	Search(ctx context.Context, q SearchQuery) ([]*SearchResult, error)
+	// CreateSeatTickets lists one ticket per seat of the event's venue for
+	// userID, who must own the event. Seats that already have a ticket are
+	// skipped, so only the new tickets are returned.
+	CreateSeatTickets(ctx context.Context, in SeatTicketsInput, userID string) ([]*Ticket, error)
+	// SeatTickets returns the seat tickets of an event in seat map order.
+	SeatTickets(ctx context.Context, eventID string) ([]*Ticket, error)
+	// AddImage records img on a ticket owned by userID, failing with
+	// ErrTooManyImages once it has MaxImages. Images do not change the
+	// ticket's version or emit events; other services have no use for them.
+	AddImage(ctx context.Context, id, userID string, img *Image) error
+	// RemoveImage deletes image imageID of a ticket owned by userID and
+	// returns it so its blobs can be deleted too.
+	RemoveImage(ctx context.Context, id, imageID, userID string) (*Image, error)
+
+	// Transfers of sold units. StartTransfer offers the units of a paid
+	// order held by userID to email, one pending transfer at a time.
+	// AcceptTransfer hands them to userID, signed in with the transfer's
+	// email, replaces their credential and records ticket:transferred;
+	// CancelTransfer lets the sender withdraw a pending transfer.
+	StartTransfer(ctx context.Context, orderID, userID, email string) (*Transfer, error)
+	AcceptTransfer(ctx context.Context, transferID, userID, email string) (*Transfer, error)
+	CancelTransfer(ctx context.Context, transferID, userID string) error
+	// Transfers returns the pending transfers userID sent or that are
+	// addressed to email, newest first.
+	Transfers(ctx context.Context, userID, email string) ([]*Transfer, error)
+	// Holding returns the units of a paid order held by userID with their
+	// credential.
+	Holding(ctx context.Context, orderID, userID string) (*Holding, error)
+	// HoldingByCredential returns the units a credential admits, or nil if
+	// it is unknown or was replaced by a transfer.
+	HoldingByCredential(ctx context.Context, credential string) (*Holding, error)
+	// Owners returns everyone who held the units of a paid order, buyer
+	// first, to a user who is or was one of them.
+	Owners(ctx context.Context, orderID, userID string) ([]Owner, error)
 }
 
-type repo struct{ db *sql.DB }
+// ticketColumns are the columns scanTicket reads, in order.
+const ticketColumns = `id, title, description, price, user_id, order_id, status, COALESCE(event_id::text, ''),
+       seat_section, seat_row, seat_number, quantity_total, quantity_held, quantity_sold, version, created_at, deleted_at`
 
-func NewRepository(db *sql.DB) Repository { return &repo{db: db} }
+// scanTicket reads ticketColumns into t, followed by any extra columns.
+func scanTicket(row interface{ Scan(...any) error }, t *Ticket, extra ...any) error {
+	var section, seatRow sql.NullString
+	var number sql.NullInt64
+	dest := append([]any{&t.ID, &t.Title, &t.Description, &t.Price, &t.UserID, &t.OrderID, &t.Status, &t.EventID,
+		&section, &seatRow, &number, &t.QuantityTotal, &t.QuantityHeld, &t.QuantitySold, &t.Version, &t.CreatedAt, &t.DeletedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	t.Seat = nil
+	if number.Valid {
+		t.Seat = &Seat{Section: section.String, Row: seatRow.String, Number: int(number.Int64)}
+	}
+	return nil
+}
+
+// createdData and updatedData are the ticket:created and ticket:updated
+// payloads for t.
+func createdData(t *Ticket) events.TicketCreatedData {
+	return events.TicketCreatedData{ID: t.ID, Title: t.Title, Price: t.Price, UserID: t.UserID, Version: t.Version, EventRef: t.EventID, Seat: eventSeat(t.Seat),
+		QuantityTotal: t.QuantityTotal}
+}
+
+func updatedData(t *Ticket) events.TicketUpdatedData {
+	return events.TicketUpdatedData{ID: t.ID, Title: t.Title, Price: t.Price, UserID: t.UserID, OrderID: t.OrderID, Status: t.Status, Version: t.Version, EventRef: t.EventID, Seat: eventSeat(t.Seat),
+		QuantityTotal: t.QuantityTotal, QuantityHeld: t.QuantityHeld, QuantitySold: t.QuantitySold}
+}
+
+func eventSeat(s *Seat) *events.TicketSeat {
+	if s == nil {
+		return nil
+	}
+	return &events.TicketSeat{Section: s.Section, Row: s.Row, Number: s.Number}
+}
+
+type repo struct {
+	db     *sql.DB
+	outbox *outbox.Store
+}
+
+// NewRepository returns a Postgres repository. Create, UpdateWithVersion,
+// Reserve, Release and MarkSold record ticket:created / ticket:updated, and
+// Delete ticket:deleted, in ob within the same transaction. Tickets
+// reference the events table of package catalog, whose schema must be
+// ensured first.
+func NewRepository(db *sql.DB, ob *outbox.Store) Repository { return &repo{db: db, outbox: ob} }
 
 func (r *repo) EnsureSchema(ctx context.Context) error {
 	_, err := r.db.ExecContext(ctx, `
@@ -29,38 +167,132 @@ CREATE TABLE IF NOT EXISTS tickets (
     version INT NOT NULL DEFAULT 0,
     created_at TIMESTAMPTZ NOT NULL DEFAULT now()
 );
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'available';
+UPDATE tickets SET status='reserved' WHERE order_id IS NOT NULL AND status='available';
+CREATE INDEX IF NOT EXISTS idx_tickets_order_id ON tickets(order_id) WHERE order_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_tickets_created_at_id ON tickets(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_tickets_price_id ON tickets(price, id);
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS search TSVECTOR;
+UPDATE tickets SET search = `+searchDocument("title", "description")+` WHERE search IS NULL;
+CREATE INDEX IF NOT EXISTS idx_tickets_search ON tickets USING GIN (search);
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS event_id UUID NULL;
+CREATE INDEX IF NOT EXISTS idx_tickets_event_id ON tickets(event_id) WHERE event_id IS NOT NULL;
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS seat_id UUID NULL REFERENCES venue_seats(id);
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS seat_section TEXT NULL;
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS seat_row TEXT NULL;
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS seat_number INT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tickets_event_id_seat_id ON tickets(event_id, seat_id) WHERE seat_id IS NOT NULL;
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS quantity_total INT NOT NULL DEFAULT 1;
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS quantity_held INT NOT NULL DEFAULT 0;
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS quantity_sold INT NOT NULL DEFAULT 0;
+CREATE TABLE IF NOT EXISTS ticket_holds (
+    order_id TEXT PRIMARY KEY,
+    ticket_id UUID NOT NULL REFERENCES tickets(id),
+    quantity INT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'held',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_ticket_holds_ticket_id ON ticket_holds(ticket_id);
+INSERT INTO ticket_holds (order_id, ticket_id, quantity, status)
+SELECT order_id, id, 1, CASE status WHEN 'sold' THEN 'sold' ELSE 'held' END FROM tickets
+WHERE order_id IS NOT NULL AND quantity_held + quantity_sold = 0
+ON CONFLICT (order_id) DO NOTHING;
+UPDATE tickets SET quantity_held = 1 WHERE status = 'reserved' AND quantity_held + quantity_sold = 0;
+UPDATE tickets SET quantity_sold = 1 WHERE status = 'sold' AND quantity_held + quantity_sold = 0;
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL;
+CREATE TABLE IF NOT EXISTS ticket_images (
+    id UUID PRIMARY KEY,
+    ticket_id UUID NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    thumbnail_key TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_ticket_images_ticket_id ON ticket_images(ticket_id, created_at);
+ALTER TABLE ticket_holds ADD COLUMN IF NOT EXISTS user_id TEXT NULL;
+ALTER TABLE ticket_holds ADD COLUMN IF NOT EXISTS credential TEXT NULL;
+ALTER TABLE ticket_holds ADD COLUMN IF NOT EXISTS transfers INT NOT NULL DEFAULT 0;
 CREATE EXTENSION IF NOT EXISTS pgcrypto;
+UPDATE ticket_holds SET credential = `+newCredential+` WHERE status = 'sold' AND credential IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ticket_holds_credential ON ticket_holds(credential) WHERE credential IS NOT NULL;
+CREATE TABLE IF NOT EXISTS ticket_transfers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id TEXT NOT NULL REFERENCES ticket_holds(order_id),
+    ticket_id UUID NOT NULL REFERENCES tickets(id),
+    quantity INT NOT NULL,
+    from_user_id TEXT NOT NULL,
+    to_email TEXT NOT NULL,
+    to_user_id TEXT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    accepted_at TIMESTAMPTZ NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ticket_transfers_pending ON ticket_transfers(order_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_ticket_transfers_to_email ON ticket_transfers(lower(to_email)) WHERE status = 'pending';
 `)
-	return err
+	if err != nil {
+		return err
+	}
+	return r.outbox.EnsureSchema(ctx)
 }
 
-func (r *repo) Create(ctx context.Context, title string, price int64, userID string) (*Ticket, error) {
-	row := r.db.QueryRowContext(ctx, `
-INSERT INTO tickets (title, price, user_id)
-VALUES ($1,$2,$3)
-RETURNING id, title, price, user_id, order_id, version, created_at
-`, title, price, userID)
+func (r *repo) Create(ctx context.Context, eventID, title, description string, price int64, quantity int, userID string) (*Ticket, error) {
 	var t Ticket
-	if err := row.Scan(&t.ID, &t.Title, &t.Price, &t.UserID, &t.OrderID, &t.Version, &t.CreatedAt); err != nil {
+	err := store.WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		if eventID != "" {
+			// Keep the event from being deleted until the ticket is in.
+			var found int
+			err := tx.QueryRowContext(ctx, `SELECT 1 FROM events WHERE id=$1 FOR SHARE`, eventID).Scan(&found)
+			if err == sql.ErrNoRows {
+				return ErrUnknownEvent
+			}
+			if err != nil {
+				return err
+			}
+		}
+		row := tx.QueryRowContext(ctx, `
+INSERT INTO tickets (title, description, price, user_id, event_id, quantity_total, search)
+VALUES ($1,$2,$3,$4,$5,$6,`+searchDocument("$1", "$2")+`)
+RETURNING `+ticketColumns, title, description, price, userID, sql.NullString{String: eventID, Valid: eventID != ""}, quantity)
+		if err := scanTicket(row, &t); err != nil {
+			return err
+		}
+		b, err := events.Marshal(ctx, events.SubjectTicketCreated, createdData(&t))
+		if err != nil {
+			return err
+		}
+		return r.outbox.Add(ctx, tx, t.ID, string(events.SubjectTicketCreated), b)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return &t, nil
 }
 
 func (r *repo) Get(ctx context.Context, id string) (*Ticket, error) {
-	row := r.db.QueryRowContext(ctx, `SELECT id, title, price, user_id, order_id, version, created_at FROM tickets WHERE id=$1`, id)
+	row := r.db.QueryRowContext(ctx, `SELECT `+ticketColumns+` FROM tickets WHERE id=$1 AND deleted_at IS NULL`, id)
 	var t Ticket
-	if err := row.Scan(&t.ID, &t.Title, &t.Price, &t.UserID, &t.OrderID, &t.Version, &t.CreatedAt); err != nil {
+	if err := scanTicket(row, &t); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
+	if err := r.attachImages(ctx, []*Ticket{&t}); err != nil {
+		return nil, err
+	}
 	return &t, nil
 }
 
-func (r *repo) List(ctx context.Context) ([]*Ticket, error) {
-	rows, err := r.db.QueryContext(ctx, `SELECT id, title, price, user_id, order_id, version, created_at FROM tickets ORDER BY created_at DESC`)
+func (r *repo) List(ctx context.Context, q ListQuery) (*TicketPage, error) {
+	clauses, args, err := listSQL(q)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, "SELECT "+ticketColumns+" FROM tickets\n"+clauses, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -68,27 +300,226 @@ func (r *repo) List(ctx context.Context) ([]*Ticket, error) {
 	var out []*Ticket
 	for rows.Next() {
 		var t Ticket
-		if err := rows.Scan(&t.ID, &t.Title, &t.Price, &t.UserID, &t.OrderID, &t.Version, &t.CreatedAt); err != nil {
+		if err := scanTicket(rows, &t); err != nil {
 			return nil, err
 		}
 		out = append(out, &t)
 	}
-	return out, nil
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.attachImages(ctx, out); err != nil {
+		return nil, err
+	}
+	return newPage(out, q), nil
 }
 
-func (r *repo) UpdateWithVersion(ctx context.Context, id string, expectedVersion int, title string, price int64, userID string) (*Ticket, error) {
-	// OCC: update only if current version matches expected, then bump version
-	res, err := r.db.ExecContext(ctx, `
-UPDATE tickets SET title=$3, price=$4, version=version+1
-WHERE id=$1 AND user_id=$2 AND version=$5
-`, id, userID, title, price, expectedVersion)
+func (r *repo) Snapshot(ctx context.Context, after string, limit int) ([]*Ticket, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT `+ticketColumns+` FROM tickets
+WHERE $1::uuid IS NULL OR id > $1::uuid
+ORDER BY id
+LIMIT $2
+`, sql.NullString{String: after, Valid: after != ""}, limit)
 	if err != nil {
 		return nil, err
 	}
-	n, _ := res.RowsAffected()
-	if n == 0 {
-		return nil, errors.New("conflict or not found")
+	defer rows.Close()
+	var out []*Ticket
+	for rows.Next() {
+		var t Ticket
+		if err := scanTicket(rows, &t); err != nil {
+			return nil, err
+		}
+		out = append(out, &t)
 	}
+	return out, rows.Err()
+}
+
+func (r *repo) UpdateWithVersion(ctx context.Context, id string, expectedVersion int, title, description string, price int64, userID string) (*Ticket, error) {
+	// OCC: update only if current version matches expected, then bump version.
+	// The row is locked first so a rejected update can say why.
+	var t Ticket
+	err := store.WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		var owner string
+		var s stock
+		var version int
+		err := tx.QueryRowContext(ctx, `
+SELECT user_id, quantity_total, quantity_held, quantity_sold, version FROM tickets WHERE id=$1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&owner, &s.total, &s.held, &s.sold, &version)
+		switch {
+		case err == sql.ErrNoRows:
+			return ErrNotFound
+		case err != nil:
+			return err
+		case owner != userID:
+			return ErrNotOwner
+		// Units of a general-admission ticket can be held or sold while
+		// others are still free: buyers keep the title and price they paid
+		// or are paying for.
+		case s.sold > 0:
+			return ErrTicketSold
+		case s.held > 0:
+			return ErrTicketReserved
+		case version != expectedVersion:
+			return ErrVersionConflict
+		}
 
-	return r.Get(ctx, id)
+		row := tx.QueryRowContext(ctx, `
+UPDATE tickets SET title=$2, description=$3, price=$4, search=`+searchDocument("$2", "$3")+`, version=version+1
+WHERE id=$1
+RETURNING `+ticketColumns, id, title, description, price)
+		if err := scanTicket(row, &t); err != nil {
+			return err
+		}
+		b, err := events.Marshal(ctx, events.SubjectTicketUpdated, updatedData(&t))
+		if err != nil {
+			return err
+		}
+		return r.outbox.Add(ctx, tx, t.ID, string(events.SubjectTicketUpdated), b)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *repo) Delete(ctx context.Context, id, userID string) error {
+	return store.WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		var owner string
+		var s stock
+		err := tx.QueryRowContext(ctx, `
+SELECT user_id, quantity_total, quantity_held, quantity_sold FROM tickets WHERE id=$1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&owner, &s.total, &s.held, &s.sold)
+		switch {
+		case err == sql.ErrNoRows:
+			return ErrNotFound
+		case err != nil:
+			return err
+		case owner != userID:
+			return ErrNotOwner
+		case s.sold > 0:
+			return ErrTicketSold
+		case s.held > 0:
+			return ErrTicketReserved
+		}
+
+		d := events.TicketDeletedData{ID: id, UserID: owner}
+		if err := tx.QueryRowContext(ctx, `
+UPDATE tickets SET deleted_at=now(), version=version+1 WHERE id=$1
+RETURNING version`, id).Scan(&d.Version); err != nil {
+			return err
+		}
+		b, err := events.Marshal(ctx, events.SubjectTicketDeleted, d)
+		if err != nil {
+			return err
+		}
+		return r.outbox.Add(ctx, tx, id, string(events.SubjectTicketDeleted), b)
+	})
+}
+
+func (r *repo) Reserve(ctx context.Context, id, orderID, userID string, quantity int) (*Ticket, error) {
+	return r.restock(ctx, id, func(tx *sql.Tx, s *stock, holder *string) (bool, error) {
+		if s.free() < quantity {
+			return false, nil
+		}
+		res, err := tx.ExecContext(ctx, `
+INSERT INTO ticket_holds (order_id, ticket_id, quantity, user_id) VALUES ($1,$2,$3,$4)
+ON CONFLICT (order_id) DO NOTHING`, orderID, id, quantity, sql.NullString{String: userID, Valid: userID != ""})
+		if err != nil {
+			return false, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return false, nil
+		}
+		s.held += quantity
+		if s.total == 1 {
+			*holder = orderID
+		}
+		return true, nil
+	})
+}
+
+func (r *repo) Release(ctx context.Context, id, orderID string) (*Ticket, error) {
+	return r.restock(ctx, id, func(tx *sql.Tx, s *stock, holder *string) (bool, error) {
+		var quantity int
+		err := tx.QueryRowContext(ctx, `
+DELETE FROM ticket_holds WHERE order_id=$1 AND ticket_id=$2 AND status='held'
+RETURNING quantity`, orderID, id).Scan(&quantity)
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		s.held -= quantity
+		if *holder == orderID {
+			*holder = ""
+		}
+		return true, nil
+	})
+}
+
+func (r *repo) MarkSold(ctx context.Context, orderID string) (*Ticket, error) {
+	var id string
+	err := r.db.QueryRowContext(ctx, `SELECT ticket_id FROM ticket_holds WHERE order_id=$1`, orderID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.restock(ctx, id, func(tx *sql.Tx, s *stock, holder *string) (bool, error) {
+		var quantity int
+		err := tx.QueryRowContext(ctx, `
+UPDATE ticket_holds SET status='sold', credential=`+newCredential+` WHERE order_id=$1 AND status='held'
+RETURNING quantity`, orderID).Scan(&quantity)
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		s.held -= quantity
+		s.sold += quantity
+		return true, nil
+	})
+}
+
+// restock locks ticket id and lets change adjust its units and the order
+// holding a single ticket. If change reports a change, the ticket is saved
+// at the next version with the status for its units and ticket:updated is
+// recorded; otherwise nothing is written and restock returns nil.
+func (r *repo) restock(ctx context.Context, id string, change func(tx *sql.Tx, s *stock, holder *string) (bool, error)) (*Ticket, error) {
+	var t Ticket
+	changed := false
+	err := store.WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		var s stock
+		var holder sql.NullString
+		err := tx.QueryRowContext(ctx, `
+SELECT quantity_total, quantity_held, quantity_sold, order_id FROM tickets WHERE id=$1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&s.total, &s.held, &s.sold, &holder)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if changed, err = change(tx, &s, &holder.String); err != nil || !changed {
+			return err
+		}
+		row := tx.QueryRowContext(ctx, `
+UPDATE tickets SET quantity_held=$2, quantity_sold=$3, status=$4, order_id=$5, version=version+1
+WHERE id=$1
+RETURNING `+ticketColumns, id, s.held, s.sold, s.status(), sql.NullString{String: holder.String, Valid: holder.String != ""})
+		if err := scanTicket(row, &t); err != nil {
+			return err
+		}
+		b, err := events.Marshal(ctx, events.SubjectTicketUpdated, updatedData(&t))
+		if err != nil {
+			return err
+		}
+		return r.outbox.Add(ctx, tx, t.ID, string(events.SubjectTicketUpdated), b)
+	})
+	if err != nil || !changed {
+		return nil, err
+	}
+	return &t, nil
 }
@@ -0,0 +1,384 @@
+package tickets
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/catalog"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/outbox"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/store"
+)
+
+// testRepo returns a tickets repository on the scratch Postgres database in
+// TEST_DATABASE_URL, with the database for reading its outbox, and skips the
+// test when it is not set.
+func testRepo(t *testing.T) (Repository, *sql.DB) {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := store.NewPostgres(dsn)
+	if err != nil {
+		t.Fatalf("store.NewPostgres: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	ctx := context.Background()
+	ob := outbox.New(db, OutboxTable)
+	if err := catalog.NewRepository(db, ob).EnsureSchema(ctx); err != nil {
+		t.Fatalf("catalog EnsureSchema: %v", err)
+	}
+	r := NewRepository(db, ob)
+	if err := r.EnsureSchema(ctx); err != nil {
+		t.Fatalf("EnsureSchema: %v", err)
+	}
+	return r, db
+}
+
+// newOrderID returns an order ID no other test run uses.
+func newOrderID() string { return uuid.NewString() }
+
+// outboxed returns the data of the events on subject the repository wrote
+// for aggregate id, oldest first.
+func outboxed[T any](t *testing.T, db *sql.DB, id string, subject events.Subject) []T {
+	t.Helper()
+	rows, err := db.Query(`SELECT payload FROM `+OutboxTable+` WHERE aggregate_id=$1 AND subject=$2 ORDER BY id`, id, string(subject))
+	if err != nil {
+		t.Fatalf("read outbox: %v", err)
+	}
+	defer rows.Close()
+	var out []T
+	for rows.Next() {
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			t.Fatalf("read outbox: %v", err)
+		}
+		env, err := events.Decode[T](subject, b)
+		if err != nil {
+			t.Fatalf("decode %s: %v", subject, err)
+		}
+		out = append(out, env.Data)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("read outbox: %v", err)
+	}
+	return out
+}
+
+func TestTransferHandsUnitsToRecipient(t *testing.T) {
+	repo, db := testRepo(t)
+	ctx := context.Background()
+	tk, err := repo.Create(ctx, "", "Festival", "", 5000, 5, "seller")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	o1 := newOrderID()
+	if _, err := repo.Reserve(ctx, tk.ID, o1, "buyer", 2); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	if _, err := repo.MarkSold(ctx, o1); err != nil {
+		t.Fatalf("MarkSold: %v", err)
+	}
+	before, err := repo.Holding(ctx, o1, "buyer")
+	if err != nil || before.Quantity != 2 || before.Credential == "" {
+		t.Fatalf("buyer's holding = %+v, %v", before, err)
+	}
+
+	// Only the holder can offer the units, once at a time.
+	if _, err := repo.StartTransfer(ctx, o1, "stranger", "friend@example.com"); err != ErrNotHolder {
+		t.Errorf("stranger starts transfer: got %v, want %v", err, ErrNotHolder)
+	}
+	tr, err := repo.StartTransfer(ctx, o1, "buyer", "friend@example.com")
+	if err != nil {
+		t.Fatalf("StartTransfer: %v", err)
+	}
+	if tr.Status != TransferPending || tr.Quantity != 2 || tr.TicketID != tk.ID {
+		t.Errorf("transfer = %+v", tr)
+	}
+	if _, err := repo.StartTransfer(ctx, o1, "buyer", "other@example.com"); err != ErrTransferPending {
+		t.Errorf("second transfer: got %v, want %v", err, ErrTransferPending)
+	}
+
+	// Only the recipient can accept it, and only once.
+	if _, err := repo.AcceptTransfer(ctx, tr.ID, "stranger", "stranger@example.com"); err != ErrNotRecipient {
+		t.Errorf("stranger accepts: got %v, want %v", err, ErrNotRecipient)
+	}
+	if _, err := repo.AcceptTransfer(ctx, tr.ID, "friend", "Friend@Example.com"); err != nil {
+		t.Fatalf("AcceptTransfer: %v", err)
+	}
+	if _, err := repo.AcceptTransfer(ctx, tr.ID, "friend", "friend@example.com"); err != ErrTransferClosed {
+		t.Errorf("accept twice: got %v, want %v", err, ErrTransferClosed)
+	}
+
+	// The friend holds the units with a new credential; the buyer's stops
+	// working.
+	after, err := repo.Holding(ctx, o1, "friend")
+	if err != nil || after.Credential == before.Credential || after.Transfers != 1 {
+		t.Fatalf("friend's holding = %+v, %v", after, err)
+	}
+	if _, err := repo.Holding(ctx, o1, "buyer"); err != ErrNotHolder {
+		t.Errorf("buyer's holding after transfer: got %v, want %v", err, ErrNotHolder)
+	}
+	if h, err := repo.HoldingByCredential(ctx, before.Credential); err != nil || h != nil {
+		t.Errorf("old credential admits %+v, %v", h, err)
+	}
+	if h, err := repo.HoldingByCredential(ctx, after.Credential); err != nil || h == nil || h.UserID != "friend" {
+		t.Errorf("new credential admits %+v, %v", h, err)
+	}
+	want := events.TicketTransferredData{ID: tk.ID, OrderID: o1, TransferID: tr.ID, FromUserID: "buyer", UserID: "friend", Quantity: 2, Version: 1}
+	if got := outboxed[events.TicketTransferredData](t, db, tk.ID, events.SubjectTicketTransferred); len(got) != 1 || got[0] != want {
+		t.Errorf("ticket:transferred events = %+v, want %+v", got, want)
+	}
+
+	// Both owners see the history; others do not.
+	owners, err := repo.Owners(ctx, o1, "buyer")
+	if err != nil || len(owners) != 2 || owners[0].UserID != "buyer" || owners[1].UserID != "friend" || owners[1].TransferID != tr.ID {
+		t.Errorf("owners = %+v, %v", owners, err)
+	}
+	if _, err := repo.Owners(ctx, o1, "stranger"); err != ErrNotHolder {
+		t.Errorf("stranger reads owners: got %v, want %v", err, ErrNotHolder)
+	}
+
+	// The friend can offer the units on and withdraw the offer.
+	back, err := repo.StartTransfer(ctx, o1, "friend", "buyer@example.com")
+	if err != nil {
+		t.Fatalf("StartTransfer by friend: %v", err)
+	}
+	if err := repo.CancelTransfer(ctx, back.ID, "buyer"); err != ErrNotHolder {
+		t.Errorf("recipient cancels: got %v, want %v", err, ErrNotHolder)
+	}
+	if err := repo.CancelTransfer(ctx, back.ID, "friend"); err != nil {
+		t.Fatalf("CancelTransfer: %v", err)
+	}
+	if _, err := repo.AcceptTransfer(ctx, back.ID, "buyer", "buyer@example.com"); err != ErrTransferClosed {
+		t.Errorf("accept cancelled transfer: got %v, want %v", err, ErrTransferClosed)
+	}
+}
+
+func TestDeleteWithdrawsOnlyUnorderedTickets(t *testing.T) {
+	repo, db := testRepo(t)
+	ctx := context.Background()
+	create := func(quantity int, userID string) *Ticket {
+		t.Helper()
+		tk, err := repo.Create(ctx, "", "Concert", "", 2000, quantity, userID)
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		return tk
+	}
+	free, held, partlySold, theirs := create(1, "seller"), create(1, "seller"), create(10, "seller"), create(1, "someone else")
+	if _, err := repo.Reserve(ctx, held.ID, newOrderID(), "buyer", 1); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	sold := newOrderID()
+	if _, err := repo.Reserve(ctx, partlySold.ID, sold, "buyer", 4); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	if _, err := repo.MarkSold(ctx, sold); err != nil {
+		t.Fatalf("MarkSold: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		id   string
+		want error
+	}{
+		{"held", held.ID, ErrTicketReserved},
+		{"partly sold", partlySold.ID, ErrTicketSold},
+		{"someone else's", theirs.ID, ErrNotOwner},
+		{"missing", uuid.NewString(), ErrNotFound},
+		{"free", free.ID, nil},
+		{"withdrawn", free.ID, ErrNotFound},
+	} {
+		if err := repo.Delete(ctx, tc.id, "seller"); err != tc.want {
+			t.Errorf("delete %s ticket: got %v, want %v", tc.name, err, tc.want)
+		}
+	}
+
+	want := events.TicketDeletedData{ID: free.ID, UserID: "seller", Version: free.Version + 1}
+	if got := outboxed[events.TicketDeletedData](t, db, free.ID, events.SubjectTicketDeleted); len(got) != 1 || got[0] != want {
+		t.Errorf("ticket:deleted events = %+v, want %+v", got, want)
+	}
+	if got, err := repo.Get(ctx, free.ID); err != nil || got != nil {
+		t.Errorf("withdrawn ticket still served: %+v, %v", got, err)
+	}
+	if got, err := repo.Reserve(ctx, free.ID, newOrderID(), "buyer", 1); err != nil || got != nil {
+		t.Errorf("withdrawn ticket reserved: %+v, %v", got, err)
+	}
+}
+
+func TestOrdersHoldUnitsOfGeneralAdmissionTickets(t *testing.T) {
+	repo, db := testRepo(t)
+	ctx := context.Background()
+	tk, err := repo.Create(ctx, "", "Festival", "", 5000, 5, "seller")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	check := func(step string, got *Ticket, err error, held, sold int, status string) {
+		t.Helper()
+		if err != nil || got == nil || got.QuantityHeld != held || got.QuantitySold != sold || got.Status != status || got.OrderID != nil {
+			t.Fatalf("%s: ticket = %+v, %v; want %d held, %d sold, %s", step, got, err, held, sold, status)
+		}
+	}
+	o1, o2, o3 := newOrderID(), newOrderID(), newOrderID()
+
+	got, err := repo.Reserve(ctx, tk.ID, o1, "buyer", 3)
+	check("3 of 5 held", got, err, 3, 0, StatusAvailable)
+	if got, err := repo.Reserve(ctx, tk.ID, o2, "buyer", 3); err != nil || got != nil {
+		t.Fatalf("more units than left: %+v, %v", got, err)
+	}
+	got, err = repo.Reserve(ctx, tk.ID, o3, "buyer", 2)
+	check("all units held", got, err, 5, 0, StatusReserved)
+
+	got, err = repo.MarkSold(ctx, o1)
+	check("o1 paid", got, err, 2, 3, StatusReserved)
+	got, err = repo.Release(ctx, tk.ID, o3)
+	check("o3 cancelled", got, err, 0, 3, StatusAvailable)
+
+	updates := outboxed[events.TicketUpdatedData](t, db, tk.ID, events.SubjectTicketUpdated)
+	if len(updates) != 4 {
+		t.Fatalf("ticket:updated events = %+v, want 4", updates)
+	}
+	if last := updates[3]; last.QuantityTotal != 5 || last.QuantityHeld != 0 || last.QuantitySold != 3 || last.Version != tk.Version+4 {
+		t.Errorf("last ticket:updated = %+v", last)
+	}
+}
+
+func TestUpdateRejectsReservedAndSoldTickets(t *testing.T) {
+	repo, _ := testRepo(t)
+	ctx := context.Background()
+	create := func(userID string, quantity int) *Ticket {
+		t.Helper()
+		tk, err := repo.Create(ctx, "", "Concert", "", 2000, quantity, userID)
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		return tk
+	}
+	free, held, sold, theirs := create("seller", 1), create("seller", 1), create("seller", 1), create("someone else", 1)
+	partlyHeld, partlySold := create("seller", 10), create("seller", 10)
+	heldBy, soldTo := newOrderID(), newOrderID()
+	if _, err := repo.Reserve(ctx, held.ID, heldBy, "buyer", 1); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	if _, err := repo.Reserve(ctx, sold.ID, soldTo, "buyer", 1); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	if _, err := repo.MarkSold(ctx, soldTo); err != nil {
+		t.Fatalf("MarkSold: %v", err)
+	}
+	// General-admission tickets with most of their units still free.
+	if _, err := repo.Reserve(ctx, partlyHeld.ID, newOrderID(), "buyer", 2); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	partlySoldTo := newOrderID()
+	if _, err := repo.Reserve(ctx, partlySold.ID, partlySoldTo, "buyer", 2); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	if _, err := repo.MarkSold(ctx, partlySoldTo); err != nil {
+		t.Fatalf("MarkSold: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		id      string
+		version int
+		want    error
+	}{
+		{"held", held.ID, held.Version + 1, ErrTicketReserved},
+		{"sold", sold.ID, sold.Version + 2, ErrTicketSold},
+		{"partly held", partlyHeld.ID, partlyHeld.Version + 1, ErrTicketReserved},
+		{"partly sold", partlySold.ID, partlySold.Version + 2, ErrTicketSold},
+		{"outdated", free.ID, free.Version - 1, ErrVersionConflict},
+		{"someone else's", theirs.ID, theirs.Version, ErrNotOwner},
+		{"missing", uuid.NewString(), 0, ErrNotFound},
+		{"free", free.ID, free.Version, nil},
+	} {
+		got, err := repo.UpdateWithVersion(ctx, tc.id, tc.version, "Concert (late show)", "", 2500, "seller")
+		if err != tc.want {
+			t.Errorf("update %s ticket: got %v, want %v", tc.name, err, tc.want)
+			continue
+		}
+		if err == nil && (got.Title != "Concert (late show)" || got.Price != 2500 || got.Version != tc.version+1) {
+			t.Errorf("updated ticket = %+v", got)
+		}
+	}
+}
+
+func TestPaidTicketStaysSold(t *testing.T) {
+	repo, db := testRepo(t)
+	ctx := context.Background()
+	tk, err := repo.Create(ctx, "", "Concert", "", 2000, 1, "seller")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	o1 := newOrderID()
+	if _, err := repo.Reserve(ctx, tk.ID, o1, "buyer", 1); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+
+	// The payment and its redelivery.
+	got, err := repo.MarkSold(ctx, o1)
+	if err != nil || got == nil || got.Status != StatusSold || got.Version != tk.Version+2 {
+		t.Fatalf("after payment ticket = %+v, %v", got, err)
+	}
+	if got, err := repo.MarkSold(ctx, o1); err != nil || got != nil {
+		t.Errorf("redelivered payment changed ticket: %+v, %v", got, err)
+	}
+
+	// A late cancellation of the paid order does not release the ticket.
+	if got, err := repo.Release(ctx, tk.ID, o1); err != nil || got != nil {
+		t.Errorf("sold ticket released: %+v, %v", got, err)
+	}
+	if got, _ := repo.Get(ctx, tk.ID); got == nil || got.Status != StatusSold || got.Version != tk.Version+2 {
+		t.Errorf("ticket after late cancel = %+v", got)
+	}
+	if updates := outboxed[events.TicketUpdatedData](t, db, tk.ID, events.SubjectTicketUpdated); len(updates) != 2 || updates[1].Status != StatusSold {
+		t.Errorf("ticket:updated events = %+v", updates)
+	}
+}
+
+func TestOrdersReserveAndReleaseTickets(t *testing.T) {
+	repo, db := testRepo(t)
+	ctx := context.Background()
+	tk, err := repo.Create(ctx, "", "Concert", "", 2000, 1, "seller")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	o1, o2 := newOrderID(), newOrderID()
+
+	// The reservation and its redelivery.
+	got, err := repo.Reserve(ctx, tk.ID, o1, "buyer", 1)
+	if err != nil || got == nil || got.OrderID == nil || *got.OrderID != o1 || got.Status != StatusReserved || got.Version != tk.Version+1 {
+		t.Fatalf("after reserve ticket = %+v, %v", got, err)
+	}
+	if got, err := repo.Reserve(ctx, tk.ID, o1, "buyer", 1); err != nil || got != nil {
+		t.Errorf("redelivered reservation changed ticket: %+v, %v", got, err)
+	}
+
+	// Another order for the same ticket does not take it over.
+	if got, err := repo.Reserve(ctx, tk.ID, o2, "someone else", 1); err != nil || got != nil {
+		t.Errorf("competing order reserved ticket: %+v, %v", got, err)
+	}
+	if got, err := repo.Release(ctx, tk.ID, o2); err != nil || got != nil {
+		t.Errorf("competing order released ticket: %+v, %v", got, err)
+	}
+
+	got, err = repo.Release(ctx, tk.ID, o1)
+	if err != nil || got == nil || got.OrderID != nil || got.Status != StatusAvailable || got.Version != tk.Version+2 {
+		t.Fatalf("after release ticket = %+v, %v", got, err)
+	}
+	if got, err := repo.Release(ctx, tk.ID, o1); err != nil || got != nil {
+		t.Errorf("redelivered release changed ticket: %+v, %v", got, err)
+	}
+
+	updates := outboxed[events.TicketUpdatedData](t, db, tk.ID, events.SubjectTicketUpdated)
+	if len(updates) != 2 || updates[0].OrderID == nil || updates[1].OrderID != nil || updates[1].Version != tk.Version+2 {
+		t.Errorf("ticket:updated events = %+v", updates)
+	}
+}
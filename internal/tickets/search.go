@@ -0,0 +1,163 @@
+package tickets
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// SearchConfig is the Postgres text search configuration for tickets.
+const SearchConfig = "english"
+
+// searchDocument is the SQL expression for a ticket's search vector. Title
+// words weigh more than description words in the rank.
+func searchDocument(title, description string) string {
+	return fmt.Sprintf("setweight(to_tsvector('%s', %s), 'A') || setweight(to_tsvector('%s', %s), 'B')",
+		SearchConfig, title, SearchConfig, description)
+}
+
+// SearchQuery is a full-text ticket search.
+type SearchQuery struct {
+	Text  string // user input; every word must match as a prefix
+	Limit int
+	// EventID keeps only the tickets for that event.
+	EventID string
+	// Available keeps only tickets that can be ordered when true.
+	Available *bool
+}
+
+// SearchResult is a ticket matching a search, with its rank and the matched
+// words highlighted. Highlights are HTML: the ticket's text is escaped and
+// matches are wrapped in <mark>.
+type SearchResult struct {
+	*Ticket
+	Rank      float64   `json:"rank"`
+	Highlight Highlight `json:"highlight"`
+}
+
+type Highlight struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// ParseSearchQuery reads a SearchQuery from URL parameters: q, limit, eventId
+// and available. Invalid parameters are reported by name.
+func ParseSearchQuery(v url.Values) (SearchQuery, map[string]string) {
+	q := SearchQuery{Text: v.Get("q"), Limit: DefaultListLimit, EventID: v.Get("eventId")}
+	bad := map[string]string{}
+	if tsQuery(q.Text) == "" {
+		bad["q"] = "must contain a word"
+	}
+	if s := v.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > MaxListLimit {
+			bad["limit"] = fmt.Sprintf("must be between 1 and %d", MaxListLimit)
+		}
+		q.Limit = n
+	}
+	if q.EventID != "" {
+		if _, err := uuid.Parse(q.EventID); err != nil {
+			bad["eventId"] = "must be the ID of an event"
+		}
+	}
+	if s := v.Get("available"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			bad["available"] = "must be true or false"
+		}
+		q.Available = &b
+	}
+	if len(bad) > 0 {
+		return q, bad
+	}
+	return q, nil
+}
+
+// tsQuery turns user input into a to_tsquery expression that matches every
+// word as a prefix, so results show up while the buyer is still typing.
+// Anything but letters and digits separates words, which also keeps tsquery
+// operators in the input from reaching Postgres.
+func tsQuery(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		words[i] = w + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// Markers ts_headline puts around matches. They are control characters
+// sellers do not type, so highlight can escape the text and then turn the
+// markers into tags.
+const (
+	markStart = "\x02"
+	markStop  = "\x03"
+)
+
+var headlineOptions = fmt.Sprintf("StartSel=%s, StopSel=%s", markStart, markStop)
+
+var markTags = strings.NewReplacer(markStart, "<mark>", markStop, "</mark>")
+
+func highlight(s string) string { return markTags.Replace(html.EscapeString(s)) }
+
+func (r *repo) Search(ctx context.Context, q SearchQuery) ([]*SearchResult, error) {
+	limit := q.Limit
+	if limit <= 0 || limit > MaxListLimit {
+		limit = DefaultListLimit
+	}
+	var available, eventID any
+	if q.Available != nil {
+		available = *q.Available
+	}
+	if q.EventID != "" {
+		eventID = q.EventID
+	}
+	rows, err := r.db.QueryContext(ctx, `
+SELECT `+ticketColumns+`,
+       ts_rank_cd(search, query) AS rank,
+       ts_headline($5, title, query, $3),
+       ts_headline($5, description, query, $4)
+FROM tickets, to_tsquery($5, $1) query
+WHERE search @@ query AND deleted_at IS NULL
+  AND ($6::boolean IS NULL OR (status = 'available') = $6)
+  AND ($7::uuid IS NULL OR event_id = $7::uuid)
+ORDER BY rank DESC, created_at DESC, id
+LIMIT $2
+`, tsQuery(q.Text), limit,
+		headlineOptions+", HighlightAll=true",
+		headlineOptions+", MaxFragments=2, MinWords=5, MaxWords=20, FragmentDelimiter=\" … \"",
+		SearchConfig, available, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []*SearchResult{}
+	for rows.Next() {
+		var t Ticket
+		res := SearchResult{Ticket: &t}
+		if err := scanTicket(rows, &t, &res.Rank, &res.Highlight.Title, &res.Highlight.Description); err != nil {
+			return nil, err
+		}
+		res.Highlight.Title = highlight(res.Highlight.Title)
+		res.Highlight.Description = highlight(res.Highlight.Description)
+		out = append(out, &res)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	tickets := make([]*Ticket, len(out))
+	for i, res := range out {
+		tickets[i] = res.Ticket
+	}
+	if err := r.attachImages(ctx, tickets); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,108 @@
+package tickets
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/catalog"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/outbox"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/store"
+)
+
+func TestTSQuery(t *testing.T) {
+	for in, want := range map[string]string{
+		"Rock concert":        "rock:* & concert:*",
+		"  jazz ":             "jazz:*",
+		"a|b & !c:*":          "a:* & b:* & c:*",
+		"Beyoncé 2024":        "beyoncé:* & 2024:*",
+		"'; DROP TABLE x; --": "drop:* & table:* & x:*",
+		"!!!":                 "",
+	} {
+		if got := tsQuery(in); got != want {
+			t.Errorf("tsQuery(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestHighlightEscapesTicketText(t *testing.T) {
+	got := highlight("<b>Rock</b> & " + markStart + "roll" + markStop)
+	if want := "&lt;b&gt;Rock&lt;/b&gt; &amp; <mark>roll</mark>"; got != want {
+		t.Errorf("highlight = %q, want %q", got, want)
+	}
+}
+
+func TestParseSearchQuery(t *testing.T) {
+	if _, bad := ParseSearchQuery(url.Values{"q": {"  ?? "}}); bad["q"] == "" {
+		t.Errorf("query without words accepted")
+	}
+	q, bad := ParseSearchQuery(url.Values{"q": {"concert"}, "limit": {"5"}, "available": {"true"}})
+	if bad != nil || q.Text != "concert" || q.Limit != 5 || !*q.Available {
+		t.Errorf("ParseSearchQuery = %+v, %v", q, bad)
+	}
+}
+
+// TestSearchRanksTitleMatchesFirst needs a scratch Postgres database in
+// TEST_DATABASE_URL and is skipped otherwise.
+func TestSearchRanksTitleMatchesFirst(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := store.NewPostgres(dsn)
+	if err != nil {
+		t.Fatalf("store.NewPostgres: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	ob := outbox.New(db, OutboxTable)
+	if err := catalog.NewRepository(db, ob).EnsureSchema(ctx); err != nil {
+		t.Fatalf("catalog EnsureSchema: %v", err)
+	}
+	r := NewRepository(db, ob)
+	if err := r.EnsureSchema(ctx); err != nil {
+		t.Fatalf("EnsureSchema: %v", err)
+	}
+
+	inDesc, err := r.Create(ctx, "", "Summer festival", "Three days of <live> guitarists", 5000, 1, "seller")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	inTitle, err := r.Create(ctx, "", "Guitar legends night", "An evening of music", 3000, 1, "seller")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	results, err := r.Search(ctx, SearchQuery{Text: "guitar"})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	var ids []string
+	for _, res := range results {
+		if res.ID == inTitle.ID || res.ID == inDesc.ID {
+			ids = append(ids, res.ID)
+		}
+	}
+	if len(ids) != 2 || ids[0] != inTitle.ID {
+		t.Fatalf("search for guitar: got %v, want title match %s before description match %s", ids, inTitle.ID, inDesc.ID)
+	}
+
+	// The index follows updates.
+	if _, err := r.UpdateWithVersion(ctx, inTitle.ID, inTitle.Version, "Piano legends night", "", 3000, "seller"); err != nil {
+		t.Fatalf("UpdateWithVersion: %v", err)
+	}
+	results, err = r.Search(ctx, SearchQuery{Text: "pian"})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	var found *SearchResult
+	for _, res := range results {
+		if res.ID == inTitle.ID {
+			found = res
+		}
+	}
+	if found == nil || found.Highlight.Title != "<mark>Piano</mark> legends night" {
+		t.Fatalf("search for pian after update: %+v", found)
+	}
+}
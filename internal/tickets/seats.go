@@ -0,0 +1,192 @@
+package tickets
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/store"
+)
+
+// SeatTicketsInput asks for one ticket per seat of an event's venue.
+type SeatTicketsInput struct {
+	EventID string `json:"eventId"`
+	// Price is the price of seats in sections without one in SectionPrices.
+	Price         int64            `json:"price"`
+	SectionPrices map[string]int64 `json:"sectionPrices,omitempty"`
+}
+
+// Validate reports invalid fields by JSON name, or returns nil.
+func (in SeatTicketsInput) Validate() map[string]string {
+	bad := map[string]string{}
+	if _, err := uuid.Parse(in.EventID); err != nil {
+		bad["eventId"] = "must be the ID of an event"
+	}
+	if in.Price <= 0 {
+		bad["price"] = "must be positive"
+	}
+	for section, price := range in.SectionPrices {
+		if price <= 0 {
+			bad["sectionPrices."+section] = "must be positive"
+		}
+	}
+	if len(bad) > 0 {
+		return bad
+	}
+	return nil
+}
+
+// Seat states in a SeatAvailability. A held seat is reserved by an order
+// that is not paid yet.
+const (
+	SeatFree = "free"
+	SeatHeld = "held"
+	SeatSold = "sold"
+)
+
+var seatStates = map[string]string{
+	StatusAvailable: SeatFree,
+	StatusReserved:  SeatHeld,
+	StatusSold:      SeatSold,
+}
+
+// SeatAvailability is the seat map of an event with the state of each seat.
+// Only seats with a ticket are included.
+type SeatAvailability struct {
+	EventID  string             `json:"eventId"`
+	Sections []SectionSeatState `json:"sections"`
+}
+
+type SectionSeatState struct {
+	Name string         `json:"name"`
+	Rows []RowSeatState `json:"rows"`
+}
+
+type RowSeatState struct {
+	Name  string      `json:"name"`
+	Seats []SeatState `json:"seats"`
+}
+
+type SeatState struct {
+	Number   int    `json:"number"`
+	TicketID string `json:"ticketId"`
+	Price    int64  `json:"price"`
+	Status   string `json:"status"` // SeatFree, SeatHeld or SeatSold
+}
+
+// newSeatAvailability groups seat tickets, in seat map order, by section and
+// row.
+func newSeatAvailability(eventID string, seats []*Ticket) *SeatAvailability {
+	a := &SeatAvailability{EventID: eventID, Sections: []SectionSeatState{}}
+	for _, t := range seats {
+		if n := len(a.Sections); n == 0 || a.Sections[n-1].Name != t.Seat.Section {
+			a.Sections = append(a.Sections, SectionSeatState{Name: t.Seat.Section})
+		}
+		section := &a.Sections[len(a.Sections)-1]
+		if n := len(section.Rows); n == 0 || section.Rows[n-1].Name != t.Seat.Row {
+			section.Rows = append(section.Rows, RowSeatState{Name: t.Seat.Row})
+		}
+		row := &section.Rows[len(section.Rows)-1]
+		row.Seats = append(row.Seats, SeatState{Number: t.Seat.Number, TicketID: t.ID, Price: t.Price, Status: seatStates[t.Status]})
+	}
+	return a
+}
+
+// seatTitle is the SQL expression for the title of the ticket for seat s of
+// event e.
+const seatTitle = `format('%s: %s, row %s, seat %s', e.name, s.section, s.seat_row, s.seat_number)`
+
+func (r *repo) CreateSeatTickets(ctx context.Context, in SeatTicketsInput, userID string) ([]*Ticket, error) {
+	prices, err := json.Marshal(in.SectionPrices)
+	if err != nil {
+		return nil, err
+	}
+	var out []*Ticket
+	err = store.WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		// Keep the event and its seat map from changing until the tickets
+		// are in.
+		var owner, venueID string
+		err := tx.QueryRowContext(ctx, `
+SELECT e.user_id, e.venue_id FROM events e JOIN venues v ON v.id = e.venue_id
+WHERE e.id=$1
+FOR SHARE`, in.EventID).Scan(&owner, &venueID)
+		switch {
+		case err == sql.ErrNoRows:
+			return ErrUnknownEvent
+		case err != nil:
+			return err
+		case owner != userID:
+			return ErrNotEventOwner
+		}
+		var seated bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM venue_seats WHERE venue_id=$1)`, venueID).Scan(&seated); err != nil {
+			return err
+		}
+		if !seated {
+			return ErrNoSeatMap
+		}
+
+		rows, err := tx.QueryContext(ctx, `
+INSERT INTO tickets (title, description, price, user_id, event_id, seat_id, seat_section, seat_row, seat_number, search)
+SELECT `+seatTitle+`, '', COALESCE(($2::jsonb ->> s.section)::bigint, $3), $4, e.id, s.id, s.section, s.seat_row, s.seat_number,
+       `+searchDocument(seatTitle, "''")+`
+FROM events e JOIN venue_seats s ON s.venue_id = e.venue_id
+WHERE e.id=$1
+ORDER BY s.position
+ON CONFLICT (event_id, seat_id) WHERE seat_id IS NOT NULL DO NOTHING
+RETURNING `+ticketColumns, in.EventID, string(prices), in.Price, userID)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var t Ticket
+			if err := scanTicket(rows, &t); err != nil {
+				rows.Close()
+				return err
+			}
+			out = append(out, &t)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		for _, t := range out {
+			b, err := events.Marshal(ctx, events.SubjectTicketCreated, createdData(t))
+			if err != nil {
+				return err
+			}
+			if err := r.outbox.Add(ctx, tx, t.ID, string(events.SubjectTicketCreated), b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *repo) SeatTickets(ctx context.Context, eventID string) ([]*Ticket, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT `+ticketColumns+` FROM tickets
+WHERE event_id=$1 AND seat_id IS NOT NULL AND deleted_at IS NULL
+ORDER BY (SELECT position FROM venue_seats WHERE id = seat_id)
+`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []*Ticket{}
+	for rows.Next() {
+		var t Ticket
+		if err := scanTicket(rows, &t); err != nil {
+			return nil, err
+		}
+		out = append(out, &t)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,68 @@
+package tickets
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestSeatsServesAvailabilityBySectionAndRow(t *testing.T) {
+	const event = "6f1c2b7e-3f43-4a55-9a4e-0d9c1f3c2a10"
+	o1, o2 := "o1", "o2"
+	seat := func(id, section, row string, number int, status string, orderID *string) *Ticket {
+		return &Ticket{ID: id, Price: 5000, EventID: event, Seat: &Seat{Section: section, Row: row, Number: number}, Status: status, OrderID: orderID}
+	}
+	repo := newFakeRepo(
+		seat("s1", "Stalls", "A", 1, StatusAvailable, nil),
+		seat("s2", "Stalls", "A", 2, StatusReserved, &o1),
+		seat("s3", "Stalls", "B", 1, StatusSold, &o2),
+		seat("s4", "Balcony", "A", 1, StatusAvailable, nil),
+		&Ticket{ID: "s5", EventID: event, Status: StatusAvailable}, // general admission
+		seat("s6", "Stalls", "A", 1, StatusAvailable, nil),
+	)
+	repo.tickets["s6"].EventID = "another event"
+	h := NewHTTPHandler(NewService(repo))
+
+	rec := httptest.NewRecorder()
+	h.Seats(rec, httptest.NewRequest(http.MethodGet, "/api/tickets/seats?eventId="+event, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d (%s)", rec.Code, rec.Body)
+	}
+	var got SeatAvailability
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := SeatAvailability{EventID: event, Sections: []SectionSeatState{
+		{Name: "Stalls", Rows: []RowSeatState{
+			{Name: "A", Seats: []SeatState{{1, "s1", 5000, SeatFree}, {2, "s2", 5000, SeatHeld}}},
+			{Name: "B", Seats: []SeatState{{1, "s3", 5000, SeatSold}}},
+		}},
+		{Name: "Balcony", Rows: []RowSeatState{
+			{Name: "A", Seats: []SeatState{{1, "s4", 5000, SeatFree}}},
+		}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("availability = %+v\nwant %+v", got, want)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Seats(rec, httptest.NewRequest(http.MethodGet, "/api/tickets/seats?eventId=nope", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid eventId: status %d, want 400", rec.Code)
+	}
+}
+
+func TestSeatTicketsInputValidate(t *testing.T) {
+	ok := SeatTicketsInput{EventID: "6f1c2b7e-3f43-4a55-9a4e-0d9c1f3c2a10", Price: 2500, SectionPrices: map[string]int64{"Balcony": 1500}}
+	if bad := ok.Validate(); bad != nil {
+		t.Errorf("Validate(%+v) = %v", ok, bad)
+	}
+	bad := SeatTicketsInput{EventID: "x", SectionPrices: map[string]int64{"Balcony": 0}}.Validate()
+	for _, field := range []string{"eventId", "price", "sectionPrices.Balcony"} {
+		if bad[field] == "" {
+			t.Errorf("no error for %s in %v", field, bad)
+		}
+	}
+}
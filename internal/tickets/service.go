@@ -1,47 +1,95 @@
 package tickets
 
-import (
-	"context"
-	"encoding/json"
-
-	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
-	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/pubsub"
-)
+import "context"
 
+// Service holds ticket business logic. Domain events are written to the
+// outbox by the repository and published by an outbox.Relay.
 type Service struct {
 	repo Repository
-	pub  pubsub.Publisher
 }
 
-func NewService(repo Repository, pub pubsub.Publisher) *Service {
-	return &Service{repo: repo, pub: pub}
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
 }
 
-func (s *Service) Create(ctx context.Context, title string, price int64, userID string) (*Ticket, error) {
-	t, err := s.repo.Create(ctx, title, price, userID)
-	if err != nil {
-		return nil, err
-	}
-	if s.pub != nil {
-		evt := events.TicketCreatedData{ID: t.ID, Title: t.Title, Price: t.Price, UserID: t.UserID, Version: t.Version}
-		b, _ := json.Marshal(evt)
-		_ = s.pub.Publish(ctx, string(events.SubjectTicketCreated), b)
-	}
-	return t, nil
+func (s *Service) Create(ctx context.Context, eventID, title, description string, price int64, quantity int, userID string) (*Ticket, error) {
+	return s.repo.Create(ctx, eventID, title, description, price, quantity, userID)
+}
+
+func (s *Service) Update(ctx context.Context, id string, version int, title, description string, price int64, userID string) (*Ticket, error) {
+	return s.repo.UpdateWithVersion(ctx, id, version, title, description, price, userID)
+}
+
+// Delete withdraws a ticket that no order holds or has bought.
+func (s *Service) Delete(ctx context.Context, id, userID string) error {
+	return s.repo.Delete(ctx, id, userID)
+}
+
+func (s *Service) Get(ctx context.Context, id string) (*Ticket, error) { return s.repo.Get(ctx, id) }
+
+// List returns one page of tickets matching q.
+func (s *Service) List(ctx context.Context, q ListQuery) (*TicketPage, error) {
+	return s.repo.List(ctx, q)
+}
+
+// Search returns the tickets matching q, best match first.
+func (s *Service) Search(ctx context.Context, q SearchQuery) ([]*SearchResult, error) {
+	return s.repo.Search(ctx, q)
+}
+
+// CreateSeatTickets lists a ticket for every seat of the event's venue that
+// has none yet.
+func (s *Service) CreateSeatTickets(ctx context.Context, in SeatTicketsInput, userID string) ([]*Ticket, error) {
+	return s.repo.CreateSeatTickets(ctx, in, userID)
 }
 
-func (s *Service) Update(ctx context.Context, id string, version int, title string, price int64, userID string) (*Ticket, error) {
-	t, err := s.repo.UpdateWithVersion(ctx, id, version, title, price, userID)
+// Seats returns the seat map of an event with the state of each seat.
+func (s *Service) Seats(ctx context.Context, eventID string) (*SeatAvailability, error) {
+	seats, err := s.repo.SeatTickets(ctx, eventID)
 	if err != nil {
 		return nil, err
 	}
-	if s.pub != nil {
-		evt := events.TicketUpdatedData{ID: t.ID, Title: t.Title, Price: t.Price, UserID: t.UserID, OrderID: t.OrderID, Version: t.Version}
-		b, _ := json.Marshal(evt)
-		_ = s.pub.Publish(ctx, string(events.SubjectTicketUpdated), b)
-	}
-	return t, nil
+	return newSeatAvailability(eventID, seats), nil
 }
 
-func (s *Service) Get(ctx context.Context, id string) (*Ticket, error) { return s.repo.Get(ctx, id) }
-func (s *Service) List(ctx context.Context) ([]*Ticket, error)         { return s.repo.List(ctx) }
+// Snapshot returns one page of all tickets with their versions, for replicas
+// in other services to bootstrap from.
+func (s *Service) Snapshot(ctx context.Context, after string, limit int) ([]*Ticket, error) {
+	return s.repo.Snapshot(ctx, after, limit)
+}
+
+// StartTransfer offers the units of a paid order held by userID to email.
+func (s *Service) StartTransfer(ctx context.Context, in TransferInput, userID string) (*Transfer, error) {
+	return s.repo.StartTransfer(ctx, in.OrderID, userID, in.Email)
+}
+
+// AcceptTransfer hands the units of a pending transfer to userID, who must
+// be signed in with the email it was sent to.
+func (s *Service) AcceptTransfer(ctx context.Context, transferID, userID, email string) (*Transfer, error) {
+	return s.repo.AcceptTransfer(ctx, transferID, userID, email)
+}
+
+func (s *Service) CancelTransfer(ctx context.Context, transferID, userID string) error {
+	return s.repo.CancelTransfer(ctx, transferID, userID)
+}
+
+// Transfers returns the pending transfers sent by or addressed to a user.
+func (s *Service) Transfers(ctx context.Context, userID, email string) ([]*Transfer, error) {
+	return s.repo.Transfers(ctx, userID, email)
+}
+
+// Holding returns the units of a paid order held by userID, with the
+// credential to show at the door.
+func (s *Service) Holding(ctx context.Context, orderID, userID string) (*Holding, error) {
+	return s.repo.Holding(ctx, orderID, userID)
+}
+
+// CheckCredential returns the units a credential admits, or nil.
+func (s *Service) CheckCredential(ctx context.Context, credential string) (*Holding, error) {
+	return s.repo.HoldingByCredential(ctx, credential)
+}
+
+// Owners returns the ownership history of a paid order's units.
+func (s *Service) Owners(ctx context.Context, orderID, userID string) ([]Owner, error) {
+	return s.repo.Owners(ctx, orderID, userID)
+}
@@ -0,0 +1,307 @@
+package tickets
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/events"
+	"github.com/DucAnhLe1992/ticket-booking-go-app/internal/store"
+)
+
+// Holding is what a buyer owns once their order is paid: its units of a
+// ticket. The buyer holds them until they transfer them to another user.
+type Holding struct {
+	OrderID  string `json:"orderId"`
+	TicketID string `json:"ticketId"`
+	Quantity int    `json:"quantity"`
+	UserID   string `json:"userId"`
+	// Credential is the code the holder shows at the door. Every transfer
+	// replaces it, so earlier holders' codes stop admitting anyone.
+	Credential string `json:"credential"`
+	// Transfers counts the accepted transfers of the units.
+	Transfers int `json:"transfers"`
+}
+
+// Owner is one holder in the ownership history of an order's units.
+// TransferID is empty for the buyer.
+type Owner struct {
+	UserID     string    `json:"userId"`
+	Since      time.Time `json:"since"`
+	TransferID string    `json:"transferId,omitempty"`
+}
+
+// Transfer states. A pending transfer is accepted by its recipient or
+// cancelled by its sender.
+const (
+	TransferPending   = "pending"
+	TransferAccepted  = "accepted"
+	TransferCancelled = "cancelled"
+)
+
+// Transfer offers the units of a paid order to whoever signs in with ToEmail.
+type Transfer struct {
+	ID         string     `json:"id"`
+	OrderID    string     `json:"orderId"`
+	TicketID   string     `json:"ticketId"`
+	Quantity   int        `json:"quantity"`
+	FromUserID string     `json:"fromUserId"`
+	ToEmail    string     `json:"toEmail"`
+	ToUserID   string     `json:"toUserId,omitempty"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	AcceptedAt *time.Time `json:"acceptedAt,omitempty"`
+}
+
+// Errors returned for holdings and transfers.
+var (
+	ErrHoldingNotFound  = errors.New("order has no paid ticket")
+	ErrNotHolder        = errors.New("ticket is held by another user")
+	ErrTransferPending  = errors.New("ticket already has a pending transfer")
+	ErrTransferNotFound = errors.New("transfer not found")
+	ErrTransferClosed   = errors.New("transfer is no longer pending")
+	ErrNotRecipient     = errors.New("transfer is addressed to another email")
+)
+
+// TransferInput offers the units of OrderID to Email.
+type TransferInput struct {
+	OrderID string `json:"orderId"`
+	Email   string `json:"email"`
+}
+
+// Validate reports invalid fields by JSON name, or returns nil. senderEmail
+// is the email of the user starting the transfer.
+func (in TransferInput) Validate(senderEmail string) map[string]string {
+	bad := map[string]string{}
+	if in.OrderID == "" {
+		bad["orderId"] = "must be the ID of a paid order"
+	}
+	if a, err := mail.ParseAddress(in.Email); err != nil || a.Address != strings.TrimSpace(in.Email) {
+		bad["email"] = "must be an email address"
+	} else if strings.EqualFold(a.Address, senderEmail) {
+		bad["email"] = "must be someone else's email address"
+	}
+	if len(bad) > 0 {
+		return bad
+	}
+	return nil
+}
+
+// newCredential is the SQL expression for a fresh holding credential.
+const newCredential = `encode(gen_random_bytes(16), 'hex')`
+
+const transferColumns = `id, order_id, ticket_id, quantity, from_user_id, to_email, COALESCE(to_user_id, ''), status, created_at, accepted_at`
+
+func scanTransfer(row interface{ Scan(...any) error }, t *Transfer) error {
+	return row.Scan(&t.ID, &t.OrderID, &t.TicketID, &t.Quantity, &t.FromUserID, &t.ToEmail, &t.ToUserID, &t.Status, &t.CreatedAt, &t.AcceptedAt)
+}
+
+// lockHolding locks the sold units of orderID and checks userID holds them.
+func lockHolding(ctx context.Context, tx *sql.Tx, orderID, userID string) (*Holding, error) {
+	h := Holding{OrderID: orderID}
+	err := tx.QueryRowContext(ctx, `
+SELECT ticket_id, quantity, COALESCE(user_id, ''), COALESCE(credential, ''), transfers FROM ticket_holds
+WHERE order_id=$1 AND status='sold'
+FOR UPDATE`, orderID).Scan(&h.TicketID, &h.Quantity, &h.UserID, &h.Credential, &h.Transfers)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, ErrHoldingNotFound
+	case err != nil:
+		return nil, err
+	case h.UserID != userID:
+		// Holds from before buyers were recorded have no holder and
+		// cannot be transferred.
+		return nil, ErrNotHolder
+	}
+	return &h, nil
+}
+
+func (r *repo) StartTransfer(ctx context.Context, orderID, userID, email string) (*Transfer, error) {
+	var t Transfer
+	err := store.WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		h, err := lockHolding(ctx, tx, orderID, userID)
+		if err != nil {
+			return err
+		}
+		var pending bool
+		if err := tx.QueryRowContext(ctx, `
+SELECT EXISTS (SELECT 1 FROM ticket_transfers WHERE order_id=$1 AND status='pending')`, orderID).Scan(&pending); err != nil {
+			return err
+		}
+		if pending {
+			return ErrTransferPending
+		}
+		return scanTransfer(tx.QueryRowContext(ctx, `
+INSERT INTO ticket_transfers (order_id, ticket_id, quantity, from_user_id, to_email)
+VALUES ($1,$2,$3,$4,$5)
+RETURNING `+transferColumns, orderID, h.TicketID, h.Quantity, userID, strings.TrimSpace(email)), &t)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *repo) AcceptTransfer(ctx context.Context, transferID, userID, email string) (*Transfer, error) {
+	var t Transfer
+	err := store.WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		err := scanTransfer(tx.QueryRowContext(ctx, `SELECT `+transferColumns+` FROM ticket_transfers WHERE id=$1 FOR UPDATE`, transferID), &t)
+		switch {
+		case err == sql.ErrNoRows:
+			return ErrTransferNotFound
+		case err != nil:
+			return err
+		case !strings.EqualFold(t.ToEmail, email):
+			return ErrNotRecipient
+		case t.Status != TransferPending:
+			return ErrTransferClosed
+		}
+
+		// The sender still holds the units: transfers are only started by
+		// the holder, and a pending one blocks any other.
+		d := events.TicketTransferredData{ID: t.TicketID, OrderID: t.OrderID, TransferID: t.ID, FromUserID: t.FromUserID, UserID: userID, Quantity: t.Quantity}
+		if err := tx.QueryRowContext(ctx, `
+UPDATE ticket_holds SET user_id=$2, credential=`+newCredential+`, transfers=transfers+1
+WHERE order_id=$1 AND status='sold' AND user_id=$3
+RETURNING transfers`, t.OrderID, userID, t.FromUserID).Scan(&d.Version); err != nil {
+			if err == sql.ErrNoRows {
+				return ErrTransferClosed
+			}
+			return err
+		}
+		if err := scanTransfer(tx.QueryRowContext(ctx, `
+UPDATE ticket_transfers SET status='accepted', to_user_id=$2, accepted_at=now() WHERE id=$1
+RETURNING `+transferColumns, t.ID, userID), &t); err != nil {
+			return err
+		}
+		b, err := events.Marshal(ctx, events.SubjectTicketTransferred, d)
+		if err != nil {
+			return err
+		}
+		return r.outbox.Add(ctx, tx, t.TicketID, string(events.SubjectTicketTransferred), b)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *repo) CancelTransfer(ctx context.Context, transferID, userID string) error {
+	return store.WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		var from, status string
+		err := tx.QueryRowContext(ctx, `SELECT from_user_id, status FROM ticket_transfers WHERE id=$1 FOR UPDATE`, transferID).Scan(&from, &status)
+		switch {
+		case err == sql.ErrNoRows:
+			return ErrTransferNotFound
+		case err != nil:
+			return err
+		case from != userID:
+			return ErrNotHolder
+		case status != TransferPending:
+			return ErrTransferClosed
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE ticket_transfers SET status='cancelled' WHERE id=$1`, transferID)
+		return err
+	})
+}
+
+func (r *repo) Transfers(ctx context.Context, userID, email string) ([]*Transfer, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT `+transferColumns+` FROM ticket_transfers
+WHERE status='pending' AND (from_user_id=$1 OR lower(to_email)=lower($2))
+ORDER BY created_at DESC, id`, userID, email)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []*Transfer{}
+	for rows.Next() {
+		var t Transfer
+		if err := scanTransfer(rows, &t); err != nil {
+			return nil, err
+		}
+		out = append(out, &t)
+	}
+	return out, rows.Err()
+}
+
+func (r *repo) Holding(ctx context.Context, orderID, userID string) (*Holding, error) {
+	h := Holding{OrderID: orderID}
+	err := r.db.QueryRowContext(ctx, `
+SELECT ticket_id, quantity, COALESCE(user_id, ''), COALESCE(credential, ''), transfers FROM ticket_holds
+WHERE order_id=$1 AND status='sold'`, orderID).Scan(&h.TicketID, &h.Quantity, &h.UserID, &h.Credential, &h.Transfers)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, ErrHoldingNotFound
+	case err != nil:
+		return nil, err
+	case h.UserID != userID:
+		return nil, ErrNotHolder
+	}
+	return &h, nil
+}
+
+func (r *repo) HoldingByCredential(ctx context.Context, credential string) (*Holding, error) {
+	h := Holding{Credential: credential}
+	err := r.db.QueryRowContext(ctx, `
+SELECT order_id, ticket_id, quantity, COALESCE(user_id, ''), transfers FROM ticket_holds
+WHERE credential=$1 AND status='sold'`, credential).Scan(&h.OrderID, &h.TicketID, &h.Quantity, &h.UserID, &h.Transfers)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+func (r *repo) Owners(ctx context.Context, orderID, userID string) ([]Owner, error) {
+	var buyer sql.NullString
+	var since time.Time
+	err := r.db.QueryRowContext(ctx, `
+SELECT COALESCE((SELECT from_user_id FROM ticket_transfers WHERE order_id=$1 AND status='accepted' ORDER BY accepted_at LIMIT 1), user_id),
+       created_at
+FROM ticket_holds WHERE order_id=$1 AND status='sold'`, orderID).Scan(&buyer, &since)
+	if err == sql.ErrNoRows {
+		return nil, ErrHoldingNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	owners := []Owner{{UserID: buyer.String, Since: since}}
+	rows, err := r.db.QueryContext(ctx, `
+SELECT to_user_id, accepted_at, id FROM ticket_transfers
+WHERE order_id=$1 AND status='accepted'
+ORDER BY accepted_at`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var o Owner
+		if err := rows.Scan(&o.UserID, &o.Since, &o.TransferID); err != nil {
+			return nil, err
+		}
+		owners = append(owners, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if !ownedBy(owners, userID) {
+		return nil, ErrNotHolder
+	}
+	return owners, nil
+}
+
+// ownedBy reports whether userID is or was one of owners.
+func ownedBy(owners []Owner, userID string) bool {
+	for _, o := range owners {
+		if o.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package tickets
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	apperr "github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/errors"
+	cmw "github.com/DucAnhLe1992/ticket-booking-go-app/internal/common/middleware"
+)
+
+func TestTransferInputValidate(t *testing.T) {
+	for _, tc := range []struct {
+		in  TransferInput
+		bad []string
+	}{
+		{TransferInput{OrderID: "o1", Email: "friend@example.com"}, nil},
+		{TransferInput{Email: "friend@example.com"}, []string{"orderId"}},
+		{TransferInput{OrderID: "o1", Email: "friend"}, []string{"email"}},
+		{TransferInput{OrderID: "o1", Email: "Friend <friend@example.com>"}, []string{"email"}},
+		{TransferInput{OrderID: "o1", Email: "Buyer@Example.com"}, []string{"email"}},
+	} {
+		bad := tc.in.Validate("buyer@example.com")
+		if len(bad) != len(tc.bad) {
+			t.Errorf("%+v: got %v, want errors for %v", tc.in, bad, tc.bad)
+			continue
+		}
+		for _, field := range tc.bad {
+			if bad[field] == "" {
+				t.Errorf("%+v: got %v, want an error for %s", tc.in, bad, field)
+			}
+		}
+	}
+}
+
+func TestTransferErrorStatus(t *testing.T) {
+	for _, tc := range []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{ErrHoldingNotFound, http.StatusNotFound, ""},
+		{ErrTransferNotFound, http.StatusNotFound, ""},
+		{ErrNotHolder, http.StatusForbidden, ""},
+		{ErrNotRecipient, http.StatusForbidden, ""},
+		{ErrTransferPending, http.StatusConflict, CodeTransferPending},
+		{ErrTransferClosed, http.StatusConflict, CodeTransferClosed},
+	} {
+		rec := httptest.NewRecorder()
+		cmw.JSONError(rec, transferError(tc.err))
+		var resp apperr.ErrorResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("%v: decode error response: %v", tc.err, err)
+		}
+		if rec.Code != tc.status || resp.Code != tc.code || resp.Message == "" {
+			t.Errorf("%v: status %d, response %+v; want %d with code %q", tc.err, rec.Code, resp, tc.status, tc.code)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+-- Tickets, Orders and Payments: transactional outbox tables.
+-- Events are inserted in the same transaction as the state change and
+-- published by each service's outbox relay.
+
+CREATE TABLE IF NOT EXISTS tickets_outbox (
+  id BIGSERIAL PRIMARY KEY,
+  aggregate_id TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  payload BYTEA NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  sent_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS tickets_outbox_pending_idx ON tickets_outbox(id) WHERE sent_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS orders_outbox (
+  id BIGSERIAL PRIMARY KEY,
+  aggregate_id TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  payload BYTEA NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  sent_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS orders_outbox_pending_idx ON orders_outbox(id) WHERE sent_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS payments_outbox (
+  id BIGSERIAL PRIMARY KEY,
+  aggregate_id TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  payload BYTEA NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  sent_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS payments_outbox_pending_idx ON payments_outbox(id) WHERE sent_at IS NULL;
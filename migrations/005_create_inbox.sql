@@ -0,0 +1,15 @@
+-- Orders and Payments: processed-event inbox tables.
+-- A row is inserted in the same transaction as the listener's writes, so a
+-- redelivered event with the same ID is skipped.
+
+CREATE TABLE IF NOT EXISTS orders_inbox (
+  event_id TEXT PRIMARY KEY,
+  subject TEXT NOT NULL,
+  processed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS payments_inbox (
+  event_id TEXT PRIMARY KEY,
+  subject TEXT NOT NULL,
+  processed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,16 @@
+-- Orders, Payments and Expiration: events a listener gave up on.
+-- Inspect and replay them with `go run ./cmd/eventctl`.
+
+CREATE TABLE IF NOT EXISTS dead_letters (
+  id BIGSERIAL PRIMARY KEY,
+  service TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  event_id TEXT NOT NULL DEFAULT '',
+  payload BYTEA NOT NULL,
+  reason TEXT NOT NULL,
+  attempts INT NOT NULL,
+  failed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  replayed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS dead_letters_pending_idx ON dead_letters(service, failed_at) WHERE replayed_at IS NULL;
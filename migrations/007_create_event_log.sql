@@ -0,0 +1,23 @@
+-- Eventlog: every event seen on the bus, indexed by the order, ticket and
+-- user it concerns. Read it back with GET /internal/events/{kind}/{id}.
+
+CREATE TABLE IF NOT EXISTS event_log (
+  id BIGSERIAL PRIMARY KEY,
+  event_id TEXT NOT NULL UNIQUE,
+  subject TEXT NOT NULL,
+  schema_version INT NOT NULL,
+  producer TEXT NOT NULL,
+  correlation_id TEXT NOT NULL DEFAULT '',
+  occurred_at TIMESTAMPTZ NOT NULL,
+  received_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  aggregate_id TEXT NOT NULL DEFAULT '',
+  order_id TEXT NULL,
+  ticket_id TEXT NULL,
+  user_id TEXT NULL,
+  data JSONB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS event_log_order_idx ON event_log(order_id, occurred_at) WHERE order_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS event_log_ticket_idx ON event_log(ticket_id, occurred_at) WHERE ticket_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS event_log_user_idx ON event_log(user_id, occurred_at) WHERE user_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS event_log_correlation_idx ON event_log(correlation_id, occurred_at);
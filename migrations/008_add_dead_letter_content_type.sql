@@ -0,0 +1,4 @@
+-- Dead letters keep the Content-Type header of the failed message so a
+-- replay republishes protobuf events as protobuf.
+
+ALTER TABLE dead_letters ADD COLUMN IF NOT EXISTS content_type TEXT NOT NULL DEFAULT 'application/json';
@@ -0,0 +1,8 @@
+-- Tickets: processed-event inbox for the order listeners that reserve and
+-- release tickets.
+
+CREATE TABLE IF NOT EXISTS tickets_inbox (
+  event_id TEXT PRIMARY KEY,
+  subject TEXT NOT NULL,
+  processed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,7 @@
+-- Tickets: lifecycle state. A ticket is reserved while an order holds it and
+-- sold once that order is paid; only available tickets can be edited.
+
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'available'
+  CHECK (status IN ('available', 'reserved', 'sold'));
+
+UPDATE tickets SET status = 'reserved' WHERE order_id IS NOT NULL AND status = 'available';
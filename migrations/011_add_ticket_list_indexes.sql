@@ -0,0 +1,5 @@
+-- Tickets: keyset pagination indexes for GET /api/tickets, one per sort
+-- column with the id tie-breaker.
+
+CREATE INDEX IF NOT EXISTS idx_tickets_created_at_id ON tickets(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_tickets_price_id ON tickets(price, id);
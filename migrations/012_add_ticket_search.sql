@@ -0,0 +1,12 @@
+-- Tickets: description and full-text search. The search vector is written by
+-- the statements that create and update a ticket; title words rank above
+-- description words.
+
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS search TSVECTOR;
+
+UPDATE tickets
+SET search = setweight(to_tsvector('english', title), 'A') || setweight(to_tsvector('english', description), 'B')
+WHERE search IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_tickets_search ON tickets USING GIN (search);
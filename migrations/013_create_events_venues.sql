@@ -0,0 +1,31 @@
+-- Tickets Service: events and the venues they take place at. Tickets belong
+-- to an event; tickets listed before events existed keep a NULL event_id.
+CREATE EXTENSION IF NOT EXISTS "pgcrypto";
+
+CREATE TABLE IF NOT EXISTS venues (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  name TEXT NOT NULL,
+  address TEXT NOT NULL DEFAULT '',
+  city TEXT NOT NULL,
+  capacity INT NOT NULL DEFAULT 0,
+  user_id TEXT NOT NULL,
+  version INT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS events (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  name TEXT NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  starts_at TIMESTAMPTZ NOT NULL,
+  venue_id UUID NOT NULL REFERENCES venues(id),
+  user_id TEXT NOT NULL,
+  version INT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_events_venue_id ON events(venue_id);
+CREATE INDEX IF NOT EXISTS idx_events_starts_at ON events(starts_at);
+
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS event_id UUID NULL REFERENCES events(id);
+CREATE INDEX IF NOT EXISTS idx_tickets_event_id ON tickets(event_id) WHERE event_id IS NOT NULL;
@@ -0,0 +1,15 @@
+-- Orders Service: replicated events, kept from event:created and
+-- event:updated so orders can show the event their ticket is for.
+
+CREATE TABLE IF NOT EXISTS orders_events (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  starts_at TIMESTAMPTZ NOT NULL,
+  venue_id TEXT NOT NULL,
+  venue_name TEXT NOT NULL,
+  venue_city TEXT NOT NULL,
+  version INT NOT NULL DEFAULT 0,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE orders_tickets ADD COLUMN IF NOT EXISTS event_id TEXT NULL;
@@ -0,0 +1,19 @@
+-- Tickets Service: venue seat maps. Tickets generated for an event have one
+-- seat each; the seat is copied onto the ticket because seats cannot change
+-- once they have tickets.
+
+CREATE TABLE IF NOT EXISTS venue_seats (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  venue_id UUID NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+  section TEXT NOT NULL,
+  seat_row TEXT NOT NULL,
+  seat_number INT NOT NULL,
+  position INT NOT NULL,
+  UNIQUE (venue_id, section, seat_row, seat_number)
+);
+
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS seat_id UUID NULL REFERENCES venue_seats(id);
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS seat_section TEXT NULL;
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS seat_row TEXT NULL;
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS seat_number INT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tickets_event_id_seat_id ON tickets(event_id, seat_id) WHERE seat_id IS NOT NULL;
@@ -0,0 +1,8 @@
+-- Orders Service: the seat of each replicated ticket, so orders can name a
+-- seat. A seat has one ticket, so one row in the replica.
+
+ALTER TABLE orders_tickets ADD COLUMN IF NOT EXISTS seat_section TEXT NULL;
+ALTER TABLE orders_tickets ADD COLUMN IF NOT EXISTS seat_row TEXT NULL;
+ALTER TABLE orders_tickets ADD COLUMN IF NOT EXISTS seat_number INT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_tickets_seat ON orders_tickets(event_id, seat_section, seat_row, seat_number)
+  WHERE seat_number IS NOT NULL;
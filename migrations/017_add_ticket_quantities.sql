@@ -0,0 +1,24 @@
+-- Tickets Service: general-admission inventory. A ticket has quantity_total
+-- units; ticket_holds records the units each order holds or bought, and
+-- quantity_held / quantity_sold sum them. Tickets listed before quantities
+-- are single tickets of one unit.
+
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS quantity_total INT NOT NULL DEFAULT 1;
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS quantity_held INT NOT NULL DEFAULT 0;
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS quantity_sold INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS ticket_holds (
+    order_id TEXT PRIMARY KEY,
+    ticket_id UUID NOT NULL REFERENCES tickets(id),
+    quantity INT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'held',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_ticket_holds_ticket_id ON ticket_holds(ticket_id);
+
+INSERT INTO ticket_holds (order_id, ticket_id, quantity, status)
+SELECT order_id, id, 1, CASE status WHEN 'sold' THEN 'sold' ELSE 'held' END FROM tickets
+WHERE order_id IS NOT NULL AND quantity_held + quantity_sold = 0
+ON CONFLICT (order_id) DO NOTHING;
+UPDATE tickets SET quantity_held = 1 WHERE status = 'reserved' AND quantity_held + quantity_sold = 0;
+UPDATE tickets SET quantity_sold = 1 WHERE status = 'sold' AND quantity_held + quantity_sold = 0;
@@ -0,0 +1,10 @@
+-- Orders Service: orders for several units of a general-admission ticket.
+-- Orders keep their quantity and the unit price they were placed at; the
+-- ticket replica counts the units held by orders that are not cancelled.
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS quantity INT NOT NULL DEFAULT 1;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS price BIGINT NULL;
+
+ALTER TABLE orders_tickets ADD COLUMN IF NOT EXISTS quantity_total INT NOT NULL DEFAULT 1;
+ALTER TABLE orders_tickets ADD COLUMN IF NOT EXISTS quantity_held INT NOT NULL DEFAULT 0;
+UPDATE orders_tickets SET quantity_held = 1 WHERE order_id IS NOT NULL AND quantity_held = 0;
@@ -0,0 +1,5 @@
+-- Tickets Service: sellers can withdraw tickets no order holds or has
+-- bought. Withdrawn tickets keep their row, so replicas catching up from the
+-- snapshot learn of them, but are left out of listings and search.
+
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL;
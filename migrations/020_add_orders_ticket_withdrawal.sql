@@ -0,0 +1,4 @@
+-- Orders Service: withdrawn tickets in the replica, set by ticket:deleted,
+-- so they can no longer be ordered.
+
+ALTER TABLE orders_tickets ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL;
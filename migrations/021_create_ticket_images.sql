@@ -0,0 +1,15 @@
+-- Tickets Service: sellers' pictures of their tickets. The files live in
+-- blob storage under key and thumbnail_key; rows only record them.
+
+CREATE TABLE IF NOT EXISTS ticket_images (
+    id UUID PRIMARY KEY,
+    ticket_id UUID NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    thumbnail_key TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ticket_images_ticket_id ON ticket_images(ticket_id, created_at);
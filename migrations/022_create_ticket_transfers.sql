@@ -0,0 +1,28 @@
+-- Tickets Service: buyers can transfer the units of a paid order to another
+-- user. Holds record who holds them and the credential shown at the door,
+-- which every transfer replaces. Holds from before this migration have no
+-- holder and cannot be transferred.
+
+ALTER TABLE ticket_holds ADD COLUMN IF NOT EXISTS user_id TEXT NULL;
+ALTER TABLE ticket_holds ADD COLUMN IF NOT EXISTS credential TEXT NULL;
+ALTER TABLE ticket_holds ADD COLUMN IF NOT EXISTS transfers INT NOT NULL DEFAULT 0;
+
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+UPDATE ticket_holds SET credential = encode(gen_random_bytes(16), 'hex') WHERE status = 'sold' AND credential IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ticket_holds_credential ON ticket_holds(credential) WHERE credential IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS ticket_transfers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id TEXT NOT NULL REFERENCES ticket_holds(order_id),
+    ticket_id UUID NOT NULL REFERENCES tickets(id),
+    quantity INT NOT NULL,
+    from_user_id TEXT NOT NULL,
+    to_email TEXT NOT NULL,
+    to_user_id TEXT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    accepted_at TIMESTAMPTZ NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ticket_transfers_pending ON ticket_transfers(order_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_ticket_transfers_to_email ON ticket_transfers(lower(to_email)) WHERE status = 'pending';
@@ -0,0 +1,7 @@
+-- Orders Service: the user holding a paid order's units, set by
+-- ticket:transferred. NULL means the buyer still holds them; holder_version
+-- is the number of the last transfer applied.
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS holder_id TEXT NULL;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS holder_version INT NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_orders_holder_id ON orders(holder_id) WHERE holder_id IS NOT NULL;